@@ -0,0 +1,77 @@
+package address
+
+import (
+	"fmt"
+	"strings"
+
+	"service_template/models"
+)
+
+// Reason tells why an address is invalid
+type Reason string
+
+const (
+	ReasonEmpty            Reason = "empty"
+	ReasonFormat           Reason = "format"
+	ReasonChecksum         Reason = "checksum"
+	ReasonVersion          Reason = "version"
+	ReasonLength           Reason = "length"
+	ReasonUnsupportedChain Reason = "unsupported_chain"
+)
+
+// Error describes an invalid address
+type Error struct {
+	Reason  Reason
+	Chain   string
+	Address string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid %v address %q: %v", e.Chain, e.Address, e.Reason)
+}
+
+func invalid(chain, addr string, r Reason) *Error {
+	return &Error{Reason: r, Chain: chain, Address: addr}
+}
+
+// Normalize validates an address of a chain and returns its canonical form:
+// bech32 and ethereum-style addresses are lower-cased, base58 addresses are kept as is
+func Normalize(chain *models.Chain, addr string) (string, error) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return "", invalid(chain.Name, addr, ReasonEmpty)
+	}
+
+	switch chain.Kind {
+	case models.ChainKindBTC:
+		params, ok := btcParams[chain.Name]
+		if !ok {
+			params = btcParams[models.ChainBTC]
+		}
+		return normalizeBTC(chain.Name, params, addr)
+	case models.ChainKindETH:
+		return normalizeETH(chain.Name, addr)
+	case models.ChainKindTRX:
+		return normalizeTRX(chain.Name, addr)
+	}
+
+	return "", invalid(chain.Name, addr, ReasonUnsupportedChain)
+}
+
+// NormalizeContract validates a token contract address of a chain
+func NormalizeContract(chain *models.Chain, addr string) (string, error) {
+	if chain.Kind == models.ChainKindBTC {
+		return "", invalid(chain.Name, addr, ReasonUnsupportedChain)
+	}
+
+	return Normalize(chain, addr)
+}
+
+// ReasonOf returns the reason of an address validation error or an empty string
+func ReasonOf(err error) Reason {
+	if e, ok := err.(*Error); ok {
+		return e.Reason
+	}
+
+	return ""
+}
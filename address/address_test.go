@@ -0,0 +1,102 @@
+package address
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"service_template/models"
+)
+
+var (
+	btc = &models.Chain{Name: models.ChainBTC, Kind: models.ChainKindBTC}
+	eth = &models.Chain{Name: "ETH", Kind: models.ChainKindETH}
+	trx = &models.Chain{Name: "TRX", Kind: models.ChainKindTRX}
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		chain  *models.Chain
+		addr   string
+		want   string
+		reason Reason
+	}{
+		// base58check
+		{btc, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", ""},
+		{btc, "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", ""},
+		{btc, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb", "", ReasonChecksum},
+		{btc, "1A1zP1eP5QGefi2DMPTfTL5SLmv7Divf0a", "", ReasonFormat},
+		{btc, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", "", ReasonVersion},
+		{btc, "  ", "", ReasonEmpty},
+		// segwit is lower-cased
+		{btc, "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", ""},
+		{btc, "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", "", ReasonFormat},
+		// EIP-55
+		{eth, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", ""},
+		{eth, "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359", ""},
+		{eth, "0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB", "0xdbf03b407c01e7cd3cbea99509d93f8dddc8c6fb", ""},
+		{eth, "0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb", "0xd1220a0cf47c7b9be7a2e6ba89f429762e7b9adb", ""},
+		{eth, "0x52908400098527886E0F7030069857D2E4169EE7", "0x52908400098527886e0f7030069857d2e4169ee7", ""},
+		{eth, "0xde709f2102306220921060314715629080e2fb77", "0xde709f2102306220921060314715629080e2fb77", ""},
+		{eth, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", "", ReasonChecksum},
+		{eth, "5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", "", ReasonFormat},
+		{eth, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea", "", ReasonLength},
+		{eth, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beazz", "", ReasonFormat},
+		// TRON
+		{trx, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", ""},
+		{trx, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6u", "", ReasonChecksum},
+		{trx, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", "", ReasonFormat},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.chain, tt.addr)
+		if ReasonOf(err) != tt.reason {
+			t.Errorf("%v %q: error %v, want reason %q", tt.chain.Name, tt.addr, err, tt.reason)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%v %q: got %q, want %q", tt.chain.Name, tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestChecksumETH(t *testing.T) {
+	for _, addr := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		if got := ChecksumETH(addr); got != addr {
+			t.Errorf("ChecksumETH(%v) = %v", addr, got)
+		}
+	}
+}
+
+func TestFromScript(t *testing.T) {
+	tests := []struct {
+		script, want string
+	}{
+		{"76a91462e907b15cbf27d5425399ebf6f0fb50ebb88f1888ac", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"},
+		{"0014751e76e8199196d454941c45d1b3a323f1433bd6", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+		{"512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0"},
+		{"6a0401020304", ""},
+	}
+	for _, tt := range tests {
+		script, _ := hex.DecodeString(tt.script)
+		got, ok := FromScript(models.ChainBTC, script)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("FromScript(%v) = %q, %v, want %q", tt.script, got, ok, tt.want)
+		}
+	}
+}
+
+func TestTRXFromHex(t *testing.T) {
+	for _, s := range []string{
+		"41a614f803b6fd780986a42c78ec9c7f77e6ded13c",
+		"0xa614f803b6fd780986a42c78ec9c7f77e6ded13c",
+		"000000000000000000000000a614f803b6fd780986a42c78ec9c7f77e6ded13c",
+	} {
+		if got, ok := TRXFromHex(s); !ok || got != "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t" {
+			t.Errorf("TRXFromHex(%v) = %v, %v", s, got, ok)
+		}
+	}
+}
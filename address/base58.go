@@ -0,0 +1,96 @@
+package address
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Index = func() (ret [256]int) {
+	for i := range ret {
+		ret[i] = -1
+	}
+	for i, c := range base58Alphabet {
+		ret[c] = i
+	}
+
+	return
+}()
+
+// base58Decode returns false if s has characters outside of the alphabet
+func base58Decode(s string) ([]byte, bool) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		d := base58Index[s[i]]
+		if d < 0 {
+			return nil, false
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(d)))
+	}
+
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+
+	return append(make([]byte, zeros), n.Bytes()...), true
+}
+
+func doubleSHA256(b []byte) []byte {
+	h := sha256.Sum256(b)
+	h = sha256.Sum256(h[:])
+
+	return h[:]
+}
+
+// base58CheckDecode returns the version byte and payload of a base58check string
+func base58CheckDecode(chain, s string) (byte, []byte, *Error) {
+	b, ok := base58Decode(s)
+	if !ok {
+		return 0, nil, invalid(chain, s, ReasonFormat)
+	}
+	if len(b) < 5 {
+		return 0, nil, invalid(chain, s, ReasonLength)
+	}
+
+	data, sum := b[:len(b)-4], b[len(b)-4:]
+	if !bytes.Equal(doubleSHA256(data)[:4], sum) {
+		return 0, nil, invalid(chain, s, ReasonChecksum)
+	}
+
+	return data[0], data[1:], nil
+}
+
+func base58Encode(b []byte) string {
+	n := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var ret []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		ret = append(ret, base58Alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		ret = append(ret, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+
+	return string(ret)
+}
+
+func base58CheckEncode(version byte, payload []byte) string {
+	data := append([]byte{version}, payload...)
+
+	return base58Encode(append(data, doubleSHA256(data)[:4]...))
+}
@@ -0,0 +1,154 @@
+package address
+
+import "strings"
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	ret := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		ret = append(ret, hrp[i]>>5)
+	}
+	ret = append(ret, 0)
+	for i := 0; i < len(hrp); i++ {
+		ret = append(ret, hrp[i]&31)
+	}
+
+	return ret
+}
+
+// bech32Decode returns the lower-cased hrp, 5-bit data without checksum and checksum constant
+func bech32Decode(chain, s string) (string, []byte, uint32, *Error) {
+	if len(s) > 90 {
+		return "", nil, 0, invalid(chain, s, ReasonLength)
+	}
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, 0, invalid(chain, s, ReasonFormat)
+	}
+	lower := strings.ToLower(s)
+
+	pos := strings.LastIndexByte(lower, '1')
+	if pos < 1 || pos+7 > len(lower) {
+		return "", nil, 0, invalid(chain, s, ReasonFormat)
+	}
+
+	hrp := lower[:pos]
+	data := make([]byte, 0, len(lower)-pos-1)
+	for i := pos + 1; i < len(lower); i++ {
+		d := strings.IndexByte(bech32Charset, lower[i])
+		if d < 0 {
+			return "", nil, 0, invalid(chain, s, ReasonFormat)
+		}
+		data = append(data, byte(d))
+	}
+
+	c := bech32Polymod(append(bech32HRPExpand(hrp), data...))
+	if c != bech32Const && c != bech32mConst {
+		return "", nil, 0, invalid(chain, s, ReasonChecksum)
+	}
+
+	return hrp, data[:len(data)-6], c, nil
+}
+
+// convertBits regroups words of from bits into words of to bits. Without pad leftover bits
+// must be zero padding and false is returned otherwise
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, bool) {
+	acc, bits := uint32(0), uint(0)
+	maxv := uint32(1)<<to - 1
+	var ret []byte
+	for _, v := range data {
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			ret = append(ret, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			ret = append(ret, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || (acc<<(to-bits))&maxv != 0 {
+		return nil, false
+	}
+
+	return ret, true
+}
+
+// decodeSegwit validates a segwit address: v0 programs use bech32, v1+ use bech32m
+func decodeSegwit(chain, hrp, s string) *Error {
+	gotHRP, data, c, err := bech32Decode(chain, s)
+	if err != nil {
+		return err
+	}
+	if gotHRP != hrp || len(data) < 1 {
+		return invalid(chain, s, ReasonVersion)
+	}
+
+	version := data[0]
+	if version > 16 {
+		return invalid(chain, s, ReasonVersion)
+	}
+	if (version == 0 && c != bech32Const) || (version > 0 && c != bech32mConst) {
+		return invalid(chain, s, ReasonChecksum)
+	}
+
+	program, ok := convertBits(data[1:], 5, 8, false)
+	if !ok {
+		return invalid(chain, s, ReasonFormat)
+	}
+	if len(program) < 2 || len(program) > 40 || (version == 0 && len(program) != 20 && len(program) != 32) {
+		return invalid(chain, s, ReasonLength)
+	}
+
+	return nil
+}
+
+func bech32Encode(hrp string, data []byte, c uint32) string {
+	values := append(bech32HRPExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ c
+
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, d := range data {
+		b.WriteByte(bech32Charset[d])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+
+	return b.String()
+}
+
+// encodeSegwit encodes a witness program, v0 with bech32 and v1+ with bech32m
+func encodeSegwit(hrp string, version byte, program []byte) string {
+	data, _ := convertBits(program, 8, 5, true)
+	c := uint32(bech32Const)
+	if version > 0 {
+		c = bech32mConst
+	}
+
+	return bech32Encode(hrp, append([]byte{version}, data...), c)
+}
@@ -0,0 +1,123 @@
+package address
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// BIP-173 and BIP-350 checksum vectors
+func TestBech32Checksum(t *testing.T) {
+	valid := []struct {
+		s string
+		c uint32
+	}{
+		{"A12UEL5L", bech32Const},
+		{"a12uel5l", bech32Const},
+		{"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs", bech32Const},
+		{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", bech32Const},
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", bech32Const},
+		{"?1ezyfcl", bech32Const},
+		{"A1LQFN3A", bech32mConst},
+		{"a1lqfn3a", bech32mConst},
+		{"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx", bech32mConst},
+		{"split1checkupstagehandshakeupstreamerranterredcaperredlc445v", bech32mConst},
+		{"?1v759aa", bech32mConst},
+	}
+	for _, v := range valid {
+		_, _, c, err := bech32Decode("BTC", v.s)
+		if err != nil {
+			t.Errorf("%v: %v", v.s, err)
+			continue
+		}
+		if c != v.c {
+			t.Errorf("%v: checksum constant %x, want %x", v.s, c, v.c)
+		}
+	}
+
+	invalid := []string{
+		"x1b4n0q5v",  // invalid data character
+		"li1dgmt3",   // too short checksum
+		"A1G7SGD8",   // checksum calculated with uppercase hrp
+		"10a06t8",    // empty hrp
+		"1qzzfhee",   // empty hrp
+		"1p2gdwpf",   // empty hrp
+		"16plkw9",    // empty hrp
+		"M1VUXWEZ",   // bech32m checksum calculated with uppercase hrp
+		"in1muywd",   // too short checksum
+		"lt1igcx5c0", // invalid data character
+		"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx", // too long
+	}
+	for _, s := range invalid {
+		if _, _, _, err := bech32Decode("BTC", s); err == nil {
+			t.Errorf("%v: decoded", s)
+		}
+	}
+}
+
+// BIP-350 segwit address vectors
+func TestSegwit(t *testing.T) {
+	valid := []struct {
+		addr, script string
+	}{
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", "0014751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", "5128751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"BC1SW50QGDZ25J", "6002751e"},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", "5210751e76e8199196d454941c45d1b3a323"},
+		{"tb1qqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesrxh6hy", "0020000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+		{"tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c", "5120000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"},
+	}
+	for _, v := range valid {
+		hrp := strings.ToLower(v.addr[:2])
+		if err := decodeSegwit("BTC", hrp, v.addr); err != nil {
+			t.Errorf("%v: %v", v.addr, err)
+			continue
+		}
+
+		script, _ := hex.DecodeString(v.script)
+		version := script[0]
+		if version > 0 {
+			version -= 0x50
+		}
+		if got := encodeSegwit(hrp, version, script[2:]); got != strings.ToLower(v.addr) {
+			t.Errorf("%v: encoded as %v", v.addr, got)
+		}
+	}
+
+	invalid := []struct {
+		addr   string
+		reason Reason
+	}{
+		{"tc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq5zuyut", ReasonVersion},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", ReasonChecksum},
+		{"tb1z0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqglt7rf", ReasonChecksum},
+		{"BC1S0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ54WELL", ReasonChecksum},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh", ReasonChecksum},
+		{"tb1q0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq24jc47", ReasonChecksum},
+		{"bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4", ReasonFormat},
+		{"BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R", ReasonVersion},
+		{"bc1pw5dgrnzv", ReasonLength},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v8n0nx0muaewav253zgeav", ReasonLength},
+		{"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P", ReasonLength},
+		{"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47Zagq", ReasonFormat},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v07qwwzcrf", ReasonFormat},
+		{"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vpggkg4j", ReasonFormat},
+		{"bc1gmk9yu", ReasonVersion},
+	}
+	for _, v := range invalid {
+		hrp := "bc"
+		if strings.HasPrefix(strings.ToLower(v.addr), "tb") {
+			hrp = "tb"
+		}
+		err := decodeSegwit("BTC", hrp, v.addr)
+		if err == nil {
+			t.Errorf("%v: decoded", v.addr)
+			continue
+		}
+		if err.Reason != v.reason {
+			t.Errorf("%v: reason %v, want %v", v.addr, err.Reason, v.reason)
+		}
+	}
+}
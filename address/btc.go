@@ -0,0 +1,43 @@
+package address
+
+import (
+	"strings"
+
+	"service_template/models"
+)
+
+// bitcoin-like network parameters, hrp is empty for chains without segwit
+type btcNetParams struct {
+	pubKeyHash byte
+	scriptHash byte
+	hrp        string
+}
+
+var btcParams = map[string]btcNetParams{
+	models.ChainBTC: {pubKeyHash: 0x00, scriptHash: 0x05, hrp: "bc"},
+	"LTC":           {pubKeyHash: 0x30, scriptHash: 0x32, hrp: "ltc"},
+	"DOGE":          {pubKeyHash: 0x1e, scriptHash: 0x16},
+}
+
+func normalizeBTC(chain string, params btcNetParams, addr string) (string, error) {
+	if params.hrp != "" && strings.HasPrefix(strings.ToLower(addr), params.hrp+"1") {
+		if err := decodeSegwit(chain, params.hrp, addr); err != nil {
+			return "", err
+		}
+
+		return strings.ToLower(addr), nil
+	}
+
+	version, payload, err := base58CheckDecode(chain, addr)
+	if err != nil {
+		return "", err
+	}
+	if version != params.pubKeyHash && version != params.scriptHash {
+		return "", invalid(chain, addr, ReasonVersion)
+	}
+	if len(payload) != 20 {
+		return "", invalid(chain, addr, ReasonLength)
+	}
+
+	return addr, nil
+}
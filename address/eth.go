@@ -0,0 +1,55 @@
+package address
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+func normalizeETH(chain, addr string) (string, error) {
+	if !strings.HasPrefix(addr, "0x") && !strings.HasPrefix(addr, "0X") {
+		return "", invalid(chain, addr, ReasonFormat)
+	}
+
+	body := addr[2:]
+	if len(body) != 40 {
+		return "", invalid(chain, addr, ReasonLength)
+	}
+	if _, err := hex.DecodeString(body); err != nil {
+		return "", invalid(chain, addr, ReasonFormat)
+	}
+
+	// all-lower and all-upper addresses carry no checksum
+	lower := strings.ToLower(body)
+	if body != lower && body != strings.ToUpper(body) && ChecksumETH(lower)[2:] != body {
+		return "", invalid(chain, addr, ReasonChecksum)
+	}
+
+	return "0x" + lower, nil
+}
+
+// ChecksumETH returns the EIP-55 mixed-case form of a 40 hex digit address with or without 0x prefix
+func ChecksumETH(addr string) string {
+	lower := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(addr, "0x"), "0X"))
+
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(lower))
+	hash := h.Sum(nil)
+
+	ret := []byte(lower)
+	for i, c := range ret {
+		if c < 'a' {
+			continue
+		}
+		nibble := hash[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		if nibble&0x0f >= 8 {
+			ret[i] = c - 'a' + 'A'
+		}
+	}
+
+	return "0x" + string(ret)
+}
@@ -0,0 +1,27 @@
+package address
+
+// FromScript returns the address paying to a standard output script of a bitcoin-like chain:
+// P2PKH, P2SH and segwit v0/v1 programs
+func FromScript(chain string, script []byte) (string, bool) {
+	params, ok := btcParams[chain]
+	if !ok {
+		return "", false
+	}
+
+	switch {
+	case len(script) == 25 && script[0] == 0x76 && script[1] == 0xa9 && script[2] == 0x14 &&
+		script[23] == 0x88 && script[24] == 0xac:
+		return base58CheckEncode(params.pubKeyHash, script[3:23]), true
+	case len(script) == 23 && script[0] == 0xa9 && script[1] == 0x14 && script[22] == 0x87:
+		return base58CheckEncode(params.scriptHash, script[2:22]), true
+	case params.hrp == "":
+		return "", false
+	case len(script) == 22 && script[0] == 0x00 && script[1] == 0x14,
+		len(script) == 34 && script[0] == 0x00 && script[1] == 0x20:
+		return encodeSegwit(params.hrp, 0, script[2:]), true
+	case len(script) == 34 && script[0] == 0x51 && script[1] == 0x20:
+		return encodeSegwit(params.hrp, 1, script[2:]), true
+	}
+
+	return "", false
+}
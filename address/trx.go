@@ -0,0 +1,48 @@
+package address
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+const trxAddressVersion = 0x41
+
+func normalizeTRX(chain, addr string) (string, error) {
+	if !strings.HasPrefix(addr, "T") {
+		return "", invalid(chain, addr, ReasonFormat)
+	}
+
+	version, payload, err := base58CheckDecode(chain, addr)
+	if err != nil {
+		return "", err
+	}
+	if version != trxAddressVersion {
+		return "", invalid(chain, addr, ReasonVersion)
+	}
+	if len(payload) != 20 {
+		return "", invalid(chain, addr, ReasonLength)
+	}
+
+	return addr, nil
+}
+
+// TRXFromHex converts a hex address of the TRON HTTP API (41 prefixed, or the bare 20 bytes
+// used in event logs) to its base58 form
+func TRXFromHex(s string) (string, bool) {
+	s = strings.TrimPrefix(strings.ToLower(s), "0x")
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return "", false
+	}
+	switch {
+	case len(b) == 21 && b[0] == trxAddressVersion:
+		b = b[1:]
+	case len(b) == 32:
+		// an indexed address topic is left padded to a word
+		b = b[12:]
+	case len(b) != 20:
+		return "", false
+	}
+
+	return base58CheckEncode(trxAddressVersion, b), true
+}
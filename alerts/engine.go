@@ -0,0 +1,326 @@
+// Package alerts evaluates alert rules on stored outgoing transfers and delivers raised alerts
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"service_template/logger"
+	"service_template/metrics"
+	"service_template/models"
+	"service_template/storage"
+)
+
+const (
+	defaultMaxAge           = 24 * time.Hour
+	defaultMaxAttempts      = 10
+	defaultDeliveryInterval = 30 * time.Second
+
+	// alerts sent per delivery run
+	deliveryBatch = 100
+	// transfers waiting for evaluation, more are dropped
+	maxQueued = 10000
+)
+
+// Notifier delivers an alert to people or systems watching them
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, a *models.Alert) error
+}
+
+// Engine evaluates enabled rules of the transferred asset as transfers are stored.
+// Transfers are queued by the storage hook and evaluated by Run, alerts are stored
+// first and then delivered, so a failing notifier does not lose them
+type Engine struct {
+	DB        *storage.Storage
+	Notifiers []Notifier
+	// transfers older than MaxAge are not evaluated, e.g. while the indexer catches up
+	MaxAge time.Duration
+	// delivery of an alert is retried through the notifiers that have not sent it yet
+	// until every one succeeds or MaxAttempts attempts were made
+	MaxAttempts      int
+	DeliveryInterval time.Duration
+
+	notify chan struct{}
+
+	mu     sync.Mutex
+	queued []storage.OutgoingTransfer
+}
+
+func New(db *storage.Storage, notifiers ...Notifier) *Engine {
+	return &Engine{
+		DB:               db,
+		Notifiers:        notifiers,
+		MaxAge:           defaultMaxAge,
+		MaxAttempts:      defaultMaxAttempts,
+		DeliveryInterval: defaultDeliveryInterval,
+		notify:           make(chan struct{}, 1),
+	}
+}
+
+// Evaluate queues stored transfers for evaluation by Run, it is meant to be an OnOutgoingStored
+// hook and returns at once, storing transfers must not wait for or fail because of alerting
+func (e *Engine) Evaluate(ctx context.Context, transfers []storage.OutgoingTransfer) {
+	log := logger.FromContext(ctx).WithField("m", "Evaluate")
+	log.Debugf("Evaluate:: transfers: %v", len(transfers))
+
+	dropped := 0
+	e.mu.Lock()
+	for _, t := range transfers {
+		if time.Since(t.Timestamp) > e.MaxAge {
+			continue
+		}
+		if len(e.queued) >= maxQueued {
+			dropped++
+			continue
+		}
+		e.queued = append(e.queued, t)
+	}
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		log.Errorf("alert queue is full, %d transfers not evaluated", dropped)
+	}
+
+	select {
+	case e.notify <- struct{}{}:
+	default:
+	}
+}
+
+// evaluateQueued evaluates the transfers queued so far
+func (e *Engine) evaluateQueued(ctx context.Context) {
+	log := logger.FromContext(ctx).WithField("m", "evaluateQueued")
+
+	e.mu.Lock()
+	transfers := e.queued
+	e.queued = nil
+	e.mu.Unlock()
+
+	if len(transfers) == 0 {
+		return
+	}
+	log.Debugf("evaluateQueued:: transfers: %v", len(transfers))
+
+	if _, err := e.evaluate(ctx, transfers); err != nil && ctx.Err() == nil {
+		log.Errorf("alert evaluation failed: %v", err)
+	}
+}
+
+func (e *Engine) evaluate(ctx context.Context, transfers []storage.OutgoingTransfer) (raised int, err error) {
+	log := logger.FromContext(ctx).WithField("m", "evaluate")
+
+	rules := map[string][]models.AlertRule{}
+	// windows already checked in this batch by rule
+	checked := map[string]bool{}
+
+	for _, t := range transfers {
+		if time.Since(t.Timestamp) > e.MaxAge {
+			continue
+		}
+
+		rs, ok := rules[t.AssetID]
+		if !ok {
+			if rs, err = e.DB.GetAlertRules(ctx, t.AssetID); err != nil {
+				return raised, err
+			}
+			rules[t.AssetID] = rs
+		}
+
+		for i := range rs {
+			r := &rs[i]
+			if r.UserID != "" && r.UserID != t.UserID {
+				continue
+			}
+
+			alert, err := e.check(ctx, r, t, checked)
+			if err != nil {
+				return raised, fmt.Errorf("rule %v: %v", r.ID, err)
+			}
+			if alert == nil {
+				continue
+			}
+
+			created, err := e.DB.CreateAlert(ctx, alert)
+			if err != nil {
+				return raised, err
+			}
+			if created {
+				raised++
+				metrics.AlertsRaised.WithLabelValues(r.Kind, r.Severity).Inc()
+				log.Warnf("alert %v raised by rule %v (%v) for user %v: %v %v",
+					alert.ID, r.ID, r.Name, alert.UserID, alert.Amount, alert.AssetID)
+			}
+		}
+	}
+
+	return raised, nil
+}
+
+// check returns an alert if the transfer triggers the rule
+func (e *Engine) check(ctx context.Context, r *models.AlertRule, t storage.OutgoingTransfer, checked map[string]bool) (*models.Alert, error) {
+	switch r.Kind {
+	case models.AlertRuleTxAmount:
+		if t.Amount.Cmp(r.Threshold) < 0 {
+			return nil, nil
+		}
+		if !r.IncludeInternal {
+			internal, err := e.DB.IsInternalTransaction(ctx, t.Chain, t.TxID)
+			if err != nil || internal {
+				return nil, err
+			}
+		}
+
+		return newAlert(r, t, t.Chain+":"+t.TxID, t.TxID, t.Amount, map[string]interface{}{
+			"threshold":  r.Threshold.String(),
+			"to_address": t.ToAddress,
+			"timestamp":  t.Timestamp,
+		})
+
+	case models.AlertRulePeriodAmount, models.AlertRuleNewAddressBurst:
+		window := r.Window()
+		from := t.Timestamp.UTC().Truncate(window)
+		key := t.UserID + ":" + strconv.FormatInt(from.Unix(), 10)
+		seen := strconv.FormatUint(uint64(r.ID), 10) + ":" + key
+		if checked[seen] {
+			return nil, nil
+		}
+		checked[seen] = true
+
+		asset, err := e.DB.LookupAsset(ctx, r.AssetID)
+		if err != nil || asset == nil {
+			return nil, err
+		}
+		chain, err := e.DB.LookupChain(ctx, asset.Chain)
+		if err != nil || chain == nil {
+			return nil, err
+		}
+		w := storage.TransferWindow{
+			Asset:           asset,
+			Chain:           chain,
+			UserID:          t.UserID,
+			Range:           storage.TimeRange{From: from, To: from.Add(window)},
+			IncludeInternal: r.IncludeInternal,
+		}
+
+		var count int64
+		var sum models.Amount
+		if r.Kind == models.AlertRulePeriodAmount {
+			if count, sum, err = e.DB.SumTransfers(ctx, w); err != nil || sum.Cmp(r.Threshold) < 0 {
+				return nil, err
+			}
+		} else {
+			if count, sum, err = e.DB.SumNewAddressTransfers(ctx, w, r.Threshold); err != nil || count < int64(r.Count) {
+				return nil, err
+			}
+		}
+
+		return newAlert(r, t, key, "", sum, map[string]interface{}{
+			"threshold": r.Threshold.String(),
+			"count":     count,
+			"from":      w.Range.From,
+			"to":        w.Range.To,
+		})
+	}
+
+	return nil, nil
+}
+
+func newAlert(r *models.AlertRule, t storage.OutgoingTransfer, key, txID string, amount models.Amount,
+	details map[string]interface{}) (*models.Alert, error) {
+	b, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Alert{
+		RuleID:   r.ID,
+		RuleName: r.Name,
+		Kind:     r.Kind,
+		Severity: r.Severity,
+		Key:      key,
+		Chain:    t.Chain,
+		AssetID:  t.AssetID,
+		UserID:   t.UserID,
+		TxID:     txID,
+		Amount:   amount,
+		Decimals: amount.Decimals(),
+		Details:  string(b),
+		Status:   models.AlertOpen,
+	}, nil
+}
+
+// Run evaluates queued transfers as they are stored and delivers alerts every DeliveryInterval
+// and after each evaluation, until ctx is done
+func (e *Engine) Run(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithField("m", "Run")
+	log.Debugf("Run:: notifiers: %v", len(e.Notifiers))
+
+	ticker := time.NewTicker(e.DeliveryInterval)
+	defer ticker.Stop()
+
+	for {
+		e.evaluateQueued(ctx)
+		if err := e.Deliver(ctx); err != nil && ctx.Err() == nil {
+			log.Errorf("alert delivery failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-e.notify:
+		}
+	}
+}
+
+// Deliver sends the oldest undelivered alerts through every notifier that has not sent them yet
+func (e *Engine) Deliver(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithField("m", "Deliver")
+	log.Debugf("Deliver:: ")
+
+	if len(e.Notifiers) == 0 {
+		return nil
+	}
+
+	alerts, err := e.DB.GetUndeliveredAlerts(ctx, e.MaxAttempts, deliveryBatch)
+	if err != nil {
+		return err
+	}
+
+	for i := range alerts {
+		notified := map[string]bool{}
+		for _, name := range alerts[i].NotifiedBy {
+			notified[name] = true
+		}
+
+		var sent, failed []string
+		for _, n := range e.Notifiers {
+			if notified[n.Name()] {
+				continue
+			}
+			if err := n.Notify(ctx, &alerts[i]); err != nil {
+				metrics.AlertNotifyErrors.WithLabelValues(n.Name()).Inc()
+				failed = append(failed, n.Name()+": "+err.Error())
+				continue
+			}
+			sent = append(sent, n.Name())
+		}
+
+		var deliveryErr error
+		if len(failed) > 0 {
+			deliveryErr = fmt.Errorf("%v", strings.Join(failed, "; "))
+			log.Warnf("alert %v delivery attempt %d failed: %v", alerts[i].ID, alerts[i].NotifyAttempts+1, deliveryErr)
+		}
+		if err := e.DB.SetAlertDelivery(ctx, alerts[i].ID, sent, deliveryErr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package alerts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"service_template/storage"
+)
+
+func TestEvaluateQueues(t *testing.T) {
+	e := New(nil)
+	ctx := context.Background()
+
+	now := time.Now()
+	e.Evaluate(ctx, []storage.OutgoingTransfer{
+		{TxID: "recent", Timestamp: now},
+		{TxID: "old", Timestamp: now.Add(-2 * e.MaxAge)},
+	})
+	if len(e.queued) != 1 || e.queued[0].TxID != "recent" {
+		t.Fatalf("queued %+v", e.queued)
+	}
+	select {
+	case <-e.notify:
+	default:
+		t.Error("Run not woken up")
+	}
+
+	many := make([]storage.OutgoingTransfer, maxQueued)
+	for i := range many {
+		many[i].Timestamp = now
+	}
+	e.Evaluate(ctx, many)
+	if len(e.queued) != maxQueued {
+		t.Errorf("%v queued, want %v", len(e.queued), maxQueued)
+	}
+}
@@ -0,0 +1,88 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"service_template/logger"
+	"service_template/models"
+	"service_template/tracer"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+// LogNotifier writes alerts to the service log
+type LogNotifier struct{}
+
+func (LogNotifier) Name() string { return "log" }
+
+func (LogNotifier) Notify(ctx context.Context, a *models.Alert) error {
+	logger.FromContext(ctx).WithField("m", "Notify").
+		WithField("alert_id", a.ID).
+		WithField("rule_id", a.RuleID).
+		WithField("severity", a.Severity).
+		WithField("user_id", a.UserID).
+		Warnf("ALERT %v: %v %v of %v, details: %v", a.RuleName, a.Amount, a.AssetID, a.UserID, a.Details)
+
+	return nil
+}
+
+// WebhookNotifier posts alerts as JSON, any status other than 2xx is a failure
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string
+	Timeout time.Duration
+	Client  tracer.HTTPClient
+}
+
+func NewWebhookNotifier(url string, headers map[string]string, timeout time.Duration) *WebhookNotifier {
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	return &WebhookNotifier{
+		URL:     url,
+		Headers: headers,
+		Timeout: timeout,
+		Client:  tracer.NewTraceHTTPClient(&http.Client{Timeout: timeout}, nil),
+	}
+}
+
+func (n *WebhookNotifier) Name() string { return "webhook" }
+
+func (n *WebhookNotifier) Notify(ctx context.Context, a *models.Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %v", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/spf13/viper"
+
+	"service_template/alerts"
+	"service_template/auth"
+	"service_template/storage"
+	"service_template/tracer"
+)
+
+// newAlertEngine configures alert evaluation and delivery from alerts.*, the log notifier
+// is on unless alerts.log is false and the webhook notifier is on when alerts.webhook.url is set.
+// With alerts.webhook.sign webhook calls are signed with the outbound signing key
+func newAlertEngine(db *storage.Storage) *alerts.Engine {
+	var notifiers []alerts.Notifier
+	if !viper.IsSet("alerts.log") || viper.GetBool("alerts.log") {
+		notifiers = append(notifiers, alerts.LogNotifier{})
+	}
+	if url := viper.GetString("alerts.webhook.url"); url != "" {
+		webhook := alerts.NewWebhookNotifier(url,
+			viper.GetStringMapString("alerts.webhook.headers"),
+			viper.GetDuration("alerts.webhook.timeout"))
+		if viper.GetBool("alerts.webhook.sign") {
+			if signer := outboundSigner(); signer != nil {
+				webhook.Client = auth.NewSigningHTTPClient(
+					tracer.NewTraceHTTPClient(&http.Client{Timeout: webhook.Timeout}, nil), signer)
+			}
+		}
+		notifiers = append(notifiers, webhook)
+	}
+
+	engine := alerts.New(db, notifiers...)
+	if d := viper.GetDuration("alerts.max_age"); d > 0 {
+		engine.MaxAge = d
+	}
+	if n := viper.GetInt("alerts.max_attempts"); n > 0 {
+		engine.MaxAttempts = n
+	}
+	if d := viper.GetDuration("alerts.delivery_interval"); d > 0 {
+		engine.DeliveryInterval = d
+	}
+
+	return engine
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"service_template/auth"
+	"service_template/logger"
+	"service_template/models"
+)
+
+const defaultUsageFlushInterval = 30 * time.Second
+
+// CreateAPIClient stores a client with a new token and returns the token, it cannot be shown again.
+// A zero ttl creates a token that does not expire
+func (a *App) CreateAPIClient(ctx context.Context, name, kind string, roles, permissions []string, ttl time.Duration) (string, error) {
+	log := logger.FromContext(ctx).WithField("m", "CreateAPIClient")
+	log.Debugf("CreateAPIClient:: name: %v, kind: %v", name, kind)
+
+	if a.DB == nil {
+		return "", errNotInitialized
+	}
+	if name == "" {
+		return "", fmt.Errorf("name is not set")
+	}
+	if kind != models.APIClientAdmin && kind != models.APIClientService {
+		return "", fmt.Errorf("invalid kind %v", kind)
+	}
+
+	token, prefix, hash, err := auth.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	client := &models.APIClient{
+		Name:        name,
+		Kind:        kind,
+		TokenPrefix: prefix,
+		TokenHash:   hash,
+		Roles:       roles,
+		Permissions: permissions,
+		CreatedBy:   "cli",
+	}
+	if ttl > 0 {
+		expires := time.Now().Add(ttl)
+		client.ExpiresAt = &expires
+	}
+	if err := a.DB.CreateAPIClient(ctx, client); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// RevokeAPIClient revokes the token of a client
+func (a *App) RevokeAPIClient(ctx context.Context, name string) error {
+	log := logger.FromContext(ctx).WithField("m", "RevokeAPIClient")
+	log.Debugf("RevokeAPIClient:: name: %v", name)
+
+	if a.DB == nil {
+		return errNotInitialized
+	}
+
+	found, err := a.DB.RevokeAPIClient(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("no active client %v", name)
+	}
+
+	return nil
+}
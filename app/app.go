@@ -2,25 +2,40 @@ package app
 
 import (
 	"context"
-	"github.com/gorilla/handlers"
+	"errors"
+	muxhandlers "github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"net/http"
+	"time"
 
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 
 	"github.com/spf13/viper"
 
+	"service_template/alerts"
+	"service_template/auth"
+	"service_template/handlers"
 	"service_template/infra"
+	"service_template/ingest"
+	"service_template/jobs"
 	"service_template/logger"
+	"service_template/metrics"
 	"service_template/middlewares"
+	"service_template/routes"
 	"service_template/storage"
+	"service_template/tracer"
 )
 
+var errNotInitialized = errors.New("app is not initialized")
+
 type App struct {
 	Router               *mux.Router
 	DB                   *storage.Storage
 	Infra                infra.Config
 	keywalletRemoveAllow bool
+	indexers             []*ingest.Indexer
+	alerts               *alerts.Engine
+	routes               *routes.Registry
 }
 
 func (a *App) Initialize(ctx context.Context) {
@@ -40,11 +55,21 @@ func (a *App) Initialize(ctx context.Context) {
 		return
 	}
 
-	a.DB = db
-	a.Router = mux.NewRouter()
-	a.setRouters()
+	if ttl := viper.GetDuration("catalog.cache_ttl"); ttl > 0 {
+		db.SetCatalogTTL(ttl)
+	}
 
+	a.DB = db
 	a.keywalletRemoveAllow = viper.GetBool("keywallet_remove_allow")
+
+	a.bootstrapRoles(ctx)
+
+	a.alerts = newAlertEngine(db)
+	db.OnOutgoingStored(a.alerts.Evaluate)
+
+	a.Router = mux.NewRouter()
+	a.routes = routes.NewRegistry(routeDefaults())
+	a.setRouters(ctx)
 }
 
 func (a *App) Run(infraCtx context.Context, host string) {
@@ -52,42 +77,169 @@ func (a *App) Run(infraCtx context.Context, host string) {
 	log = log.WithField("m", "Run")
 	log.Debugf("Run:: ")
 
-	a.Router.Use(middlewares.LoggingMiddleware)
-	a.Router.Use(middlewares.AuthMiddlewareGenerator(infraCtx, a.DB.DB))
+	a.startJobs(infraCtx)
+
+	ipLimiter, principalLimiter := a.rateLimiters(infraCtx)
+
+	metricsMiddleware := middlewares.MetricsMiddlewareGenerator(infraCtx)
+	a.Router.Use(metricsMiddleware)
+	// the router runs middlewares only for matched routes, 404 and 405 are counted as unmatched here
+	a.Router.NotFoundHandler = metricsMiddleware(http.NotFoundHandler())
+	a.Router.MethodNotAllowedHandler = metricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	a.Router.Use(middlewares.ClientIPMiddlewareGenerator(infraCtx, clientIPResolver(infraCtx)))
+	a.Router.Use(middlewares.AccessLogMiddlewareGenerator(infraCtx, accessLogConfig(), a.routes))
+	a.Router.Use(middlewares.RouteLimitsMiddlewareGenerator(infraCtx, a.routes))
+	// per address bucket ahead of authentication, so bad credentials are throttled as well
+	if ipLimiter != nil {
+		a.Router.Use(ipLimiter)
+	}
+	a.Router.Use(middlewares.SignatureMiddlewareGenerator(infraCtx, a.signatureVerifier(infraCtx)))
+	a.Router.Use(middlewares.AuthMiddlewareGenerator(infraCtx, a.DB, a.jwtVerifier(infraCtx), a.routes))
+	if principalLimiter != nil {
+		a.Router.Use(principalLimiter)
+	}
+	a.Router.Use(middlewares.PermissionMiddlewareGenerator(infraCtx, a.DB, a.routes))
 
-	cors := handlers.CORS(
-		handlers.AllowedHeaders([]string{"Origin", "Content-Type", "Authorization"}),
-		handlers.AllowedOrigins([]string{"localhost:3000"}),
-		handlers.AllowedMethods([]string{"POST", "GET", "PUT", "DELETE", "OPTIONS"}),
-		handlers.AllowCredentials(),
+	cors := muxhandlers.CORS(
+		muxhandlers.AllowedHeaders([]string{"Origin", "Content-Type", "Authorization"}),
+		muxhandlers.AllowedOrigins([]string{"localhost:3000"}),
+		muxhandlers.AllowedMethods([]string{"POST", "GET", "PUT", "DELETE", "OPTIONS"}),
+		muxhandlers.AllowCredentials(),
 	)(a.Router)
 
-	handler := func(h http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			tc := r.Context()
-			r = r.WithContext(infra.GenerateNewTrace(tc))
-			h.ServeHTTP(w, r)
-		})
-	}(cors)
+	handler := tracer.Middleware(cors)
+
+	if err := infra.ServeHTTP(infraCtx, host, handler); err != nil {
+		log.Errorf("ServeHTTP error: %v", err)
+	}
+	if err := infra.Wait(infraCtx); err != nil {
+		log.Errorf("shutdown error: %v", err)
+	}
+}
 
-	log.Errorf(infra.ServeHTTP(infraCtx, host, handler))
+func (a *App) Get(path string, f func(w http.ResponseWriter, r *http.Request), opts ...routes.Option) {
+	a.routes.Set(a.Router.HandleFunc(path, f).Methods("GET"), opts...)
 }
 
-func (a *App) Get(path string, f func(w http.ResponseWriter, r *http.Request)) {
-	a.Router.HandleFunc(path, f).Methods("GET")
+func (a *App) Post(path string, f func(w http.ResponseWriter, r *http.Request), opts ...routes.Option) {
+	a.routes.Set(a.Router.HandleFunc(path, f).Methods("POST"), opts...)
 }
 
-func (a *App) Post(path string, f func(w http.ResponseWriter, r *http.Request)) {
-	a.Router.HandleFunc(path, f).Methods("POST")
+func (a *App) Put(path string, f func(w http.ResponseWriter, r *http.Request), opts ...routes.Option) {
+	a.routes.Set(a.Router.HandleFunc(path, f).Methods("PUT"), opts...)
 }
 
-func (a *App) Put(path string, f func(w http.ResponseWriter, r *http.Request)) {
-	a.Router.HandleFunc(path, f).Methods("PUT")
+func (a *App) Delete(path string, f func(w http.ResponseWriter, r *http.Request), opts ...routes.Option) {
+	a.routes.Set(a.Router.HandleFunc(path, f).Methods("DELETE"), opts...)
 }
 
-func (a *App) Delete(path string, f func(w http.ResponseWriter, r *http.Request)) {
-	a.Router.HandleFunc(path, f).Methods("DELETE")
+func (a *App) setRouters(ctx context.Context) {
+	a.routes.Set(a.Router.Handle("/metrics", metrics.Handler()).Methods("GET"), routes.Public())
+	a.routes.Set(a.Router.Handle("/health", infra.HealthHandler(ctx)).Methods("GET"), routes.Public())
+
+	a.Get("/api/v1/wallets", a.handleRequest(handlers.GetWallets), routes.Permissions(auth.PermissionWalletsRead))
+	a.Delete("/api/v1/wallets/{id:[0-9]+}", a.handleRequest(handlers.DeleteWalletGenerator(a.keywalletRemoveAllow)), routes.Permissions(auth.PermissionWalletsRemove))
+	a.Post("/api/v1/internal_transactions", a.handleRequest(handlers.PostInternalTransactions), routes.Permissions(auth.PermissionTransactionsWrite), routes.LogBody())
+	a.Get("/api/v1/internal_transactions/{chain}/{tx_id}", a.handleRequest(handlers.GetInternalTransaction), routes.Permissions(auth.PermissionTransactionsRead))
+	a.Get("/api/v1/transactions/outgoing", a.handleRequest(handlers.GetOutgoingTransactions), routes.Permissions(auth.PermissionTransactionsRead))
+
+	a.Get("/api/v1/turnover", a.handleRequest(handlers.GetTurnover), routes.Permissions(auth.PermissionReportsRead))
+
+	a.Get("/api/v1/assets", a.handleRequest(handlers.GetSupportedAssets), routes.Permissions(auth.PermissionCatalogRead))
+
+	a.Get("/api/v1/admin/chains", a.handleRequest(handlers.GetChains), routes.Permissions(auth.PermissionCatalogRead))
+	a.Post("/api/v1/admin/chains", a.handleRequest(handlers.PostChain), routes.Permissions(auth.PermissionCatalogWrite), routes.LogBody())
+	a.Put("/api/v1/admin/chains/{chain}", a.handleRequest(handlers.PutChain), routes.Permissions(auth.PermissionCatalogWrite), routes.LogBody())
+	a.Delete("/api/v1/admin/chains/{chain}", a.handleRequest(handlers.DeleteChain), routes.Permissions(auth.PermissionCatalogWrite))
+	a.Get("/api/v1/admin/assets", a.handleRequest(handlers.GetAssets), routes.Permissions(auth.PermissionCatalogRead))
+	a.Post("/api/v1/admin/assets", a.handleRequest(handlers.PostAsset), routes.Permissions(auth.PermissionCatalogWrite), routes.LogBody())
+	a.Get("/api/v1/admin/assets/{asset_id}", a.handleRequest(handlers.GetAsset), routes.Permissions(auth.PermissionCatalogRead))
+	a.Put("/api/v1/admin/assets/{asset_id}", a.handleRequest(handlers.PutAsset), routes.Permissions(auth.PermissionCatalogWrite), routes.LogBody())
+	a.Delete("/api/v1/admin/assets/{asset_id}", a.handleRequest(handlers.DeleteAsset), routes.Permissions(auth.PermissionCatalogWrite))
+	a.Get("/api/v1/admin/prices", a.handleRequest(handlers.GetPrices), routes.Permissions(auth.PermissionPricesRead))
+	a.Post("/api/v1/admin/prices", a.handleRequest(handlers.PostPrices), routes.Permissions(auth.PermissionPricesWrite),
+		routes.BodyLimit(handlers.MaxPricesBody), routes.Timeout(exportTimeout))
+
+	a.Get("/api/v1/reconciliation/findings", a.handleRequest(handlers.GetFindings), routes.Permissions(auth.PermissionReconciliationRead))
+	a.Put("/api/v1/reconciliation/findings/{id:[0-9]+}", a.handleRequest(handlers.PutFinding), routes.Permissions(auth.PermissionReconciliationWrite), routes.LogBody())
+	a.Get("/api/v1/reconciliation/summary", a.handleRequest(handlers.GetFindingsSummary), routes.Permissions(auth.PermissionReconciliationRead))
+
+	a.Get("/api/v1/alerts", a.handleRequest(handlers.GetAlerts), routes.Permissions(auth.PermissionAlertsRead))
+	a.Put("/api/v1/alerts/{id:[0-9]+}", a.handleRequest(handlers.PutAlert), routes.Permissions(auth.PermissionAlertsWrite), routes.LogBody())
+	a.Get("/api/v1/admin/alert_rules", a.handleRequest(handlers.GetAlertRules), routes.Permissions(auth.PermissionAlertsRead))
+	a.Post("/api/v1/admin/alert_rules", a.handleRequest(handlers.PostAlertRule), routes.Permissions(auth.PermissionAlertRulesWrite), routes.LogBody())
+	a.Put("/api/v1/admin/alert_rules/{id:[0-9]+}", a.handleRequest(handlers.PutAlertRule), routes.Permissions(auth.PermissionAlertRulesWrite), routes.LogBody())
+	a.Delete("/api/v1/admin/alert_rules/{id:[0-9]+}", a.handleRequest(handlers.DeleteAlertRule), routes.Permissions(auth.PermissionAlertRulesWrite))
+
+	a.Get("/api/v1/indexer/status", a.handleRequest(handlers.GetIndexerStatusGenerator(a.indexerStatus)), routes.Permissions(auth.PermissionIndexerRead))
+
+	a.Get("/api/v1/auth/permissions", a.handleRequest(handlers.GetPermissions))
+	a.Post("/api/v1/auth/login", a.handleRequest(handlers.PostLoginGenerator(a.sessionConfig())), routes.Public(), routes.RateClass(routes.RateAuth))
+	a.Post("/api/v1/auth/refresh", a.handleRequest(handlers.PostRefreshGenerator(a.sessionConfig())), routes.Public(), routes.RateClass(routes.RateAuth))
+	a.Post("/api/v1/auth/logout", a.handleRequest(handlers.PostLogout))
+	a.Post("/api/v1/auth/logout_all", a.handleRequest(handlers.PostLogoutAll))
+	a.Get("/api/v1/auth/sessions", a.handleRequest(handlers.GetSessions))
+	a.Put("/api/v1/auth/password", a.handleRequest(handlers.PutPasswordGenerator(a.sessionConfig())))
+	a.Get("/api/v1/admin/roles", a.handleRequest(handlers.GetRoles), routes.Permissions(auth.PermissionRolesRead))
+	a.Put("/api/v1/admin/roles/{role}", a.handleRequest(handlers.PutRole), routes.Permissions(auth.PermissionRolesWrite), routes.LogBody())
+
+	a.Get("/api/v1/admin/api_clients", a.handleRequest(handlers.GetAPIClients), routes.Permissions(auth.PermissionAPIClientsRead))
+	a.Post("/api/v1/admin/api_clients", a.handleRequest(handlers.PostAPIClient), routes.Permissions(auth.PermissionAPIClientsWrite))
+	a.Put("/api/v1/admin/api_clients/{id:[0-9]+}", a.handleRequest(handlers.PutAPIClient), routes.Permissions(auth.PermissionAPIClientsWrite))
+	a.Post("/api/v1/admin/api_clients/{id:[0-9]+}/rotate", a.handleRequest(handlers.RotateAPIClient), routes.Permissions(auth.PermissionAPIClientsWrite))
+	a.Delete("/api/v1/admin/api_clients/{id:[0-9]+}", a.handleRequest(handlers.DeleteAPIClient), routes.Permissions(auth.PermissionAPIClientsWrite))
+
+	a.Get("/api/v1/admin/users", a.handleRequest(handlers.GetAdminUsers), routes.Permissions(auth.PermissionUsersRead))
+	a.Post("/api/v1/admin/users", a.handleRequest(handlers.PostAdminUser), routes.Permissions(auth.PermissionUsersWrite))
+	a.Put("/api/v1/admin/users/{id:[0-9]+}", a.handleRequest(handlers.PutAdminUser), routes.Permissions(auth.PermissionUsersWrite))
+	a.Post("/api/v1/admin/users/{id:[0-9]+}/unlock", a.handleRequest(handlers.PostAdminUserUnlock), routes.Permissions(auth.PermissionUsersWrite))
+	a.Put("/api/v1/admin/users/{id:[0-9]+}/password", a.handleRequest(handlers.PutAdminUserPassword), routes.Permissions(auth.PermissionUsersWrite))
+	a.Delete("/api/v1/admin/users/{id:[0-9]+}/sessions", a.handleRequest(handlers.DeleteAdminUserSessions), routes.Permissions(auth.PermissionUsersWrite))
+
+	a.Get("/api/v1/export/wallets", a.handleRequest(handlers.ExportWallets), routes.Permissions(auth.PermissionExportRead),
+		routes.RateClass(routes.RateExport), routes.Timeout(exportTimeout), routes.Streaming())
+	a.Get("/api/v1/export/transactions/outgoing", a.handleRequest(handlers.ExportOutgoingTransactions), routes.Permissions(auth.PermissionExportRead),
+		routes.RateClass(routes.RateExport), routes.Timeout(exportTimeout), routes.Streaming())
 }
 
-func (a *App) setRouters() {
+func (a *App) startJobs(ctx context.Context) {
+	rollupInterval := viper.GetDuration("rollup.interval")
+	if rollupInterval == 0 {
+		rollupInterval = defaultRollupInterval
+	}
+	a.goJob(ctx, "turnover_rollup", rollupInterval, a.refreshTurnover)
+
+	reconciliationInterval := viper.GetDuration("reconciliation.interval")
+	if reconciliationInterval == 0 {
+		reconciliationInterval = defaultReconciliationInterval
+	}
+	a.goJob(ctx, "reconciliation", reconciliationInterval, a.reconcile)
+
+	infra.Go(ctx, "alerts", a.alerts.Run)
+
+	usageInterval := viper.GetDuration("api_clients.usage_flush_interval")
+	if usageInterval == 0 {
+		usageInterval = defaultUsageFlushInterval
+	}
+	a.goJob(ctx, "api_client_usage", usageInterval, a.DB.FlushAPIClientUsage)
+
+	a.startIngestion(ctx)
+}
+
+func (a *App) goJob(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	infra.Go(ctx, name, func(ctx context.Context) error {
+		jobs.Run(ctx, name, interval, fn)
+
+		return nil
+	})
+}
+
+type RequestHandlerFunction func(db *storage.Storage, w http.ResponseWriter, r *http.Request)
+
+func (a *App) handleRequest(handler RequestHandlerFunction) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(a.DB, w, r)
+	}
 }
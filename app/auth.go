@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"service_template/auth"
+	"service_template/handlers"
+	"service_template/infra"
+	"service_template/logger"
+	"service_template/models"
+)
+
+const defaultJWKSReloadInterval = 30 * time.Second
+
+// request signing defaults
+const (
+	defaultSignatureSkew  = 5 * time.Minute
+	defaultSignatureNonce = 100000
+	minSigningSecretLen   = 32
+)
+
+// admin user session defaults
+const (
+	defaultAccessTTL        = 15 * time.Minute
+	defaultRefreshTTL       = 24 * time.Hour
+	defaultSessionLifetime  = 7 * 24 * time.Hour
+	defaultMaxLoginFailures = 5
+	defaultLockout          = 15 * time.Minute
+)
+
+// jwtVerifier configures JWT authentication from auth.jwt.*, it returns nil if auth.jwt.jwks is not
+// set or the key set cannot be loaded. The key set is reloaded while ctx is alive
+func (a *App) jwtVerifier(ctx context.Context) *auth.JWTVerifier {
+	log := logger.FromContext(ctx).WithField("m", "jwtVerifier")
+	log.Debugf("jwtVerifier:: ")
+
+	path := viper.GetString("auth.jwt.jwks")
+	if path == "" {
+		return nil
+	}
+
+	keys, err := auth.LoadKeySet(path)
+	if err != nil {
+		log.Errorf("JWT authentication disabled, cannot load key set %v: %v", path, err)
+
+		return nil
+	}
+
+	interval := viper.GetDuration("auth.jwt.reload_interval")
+	if interval == 0 {
+		interval = defaultJWKSReloadInterval
+	}
+	infra.Go(ctx, "jwks_reload", func(ctx context.Context) error {
+		return keys.Run(ctx, interval)
+	})
+
+	return auth.NewJWTVerifier(auth.JWTConfig{
+		Issuer:           viper.GetString("auth.jwt.issuer"),
+		Audience:         viper.GetStringSlice("auth.jwt.audience"),
+		Leeway:           viper.GetDuration("auth.jwt.leeway"),
+		Algorithms:       viper.GetStringSlice("auth.jwt.algorithms"),
+		UserClaim:        viper.GetString("auth.jwt.user_claim"),
+		RolesClaim:       viper.GetString("auth.jwt.roles_claim"),
+		PermissionsClaim: viper.GetString("auth.jwt.permissions_claim"),
+		Kind:             viper.GetString("auth.jwt.kind"),
+	}, keys)
+}
+
+// bootstrapRoles seeds an empty role table from rbac.roles: {<role>: [<permission>, ...]}
+func (a *App) bootstrapRoles(ctx context.Context) {
+	log := logger.FromContext(ctx).WithField("m", "bootstrapRoles")
+	log.Debugf("bootstrapRoles:: ")
+
+	if ttl := viper.GetDuration("rbac.cache_ttl"); ttl > 0 {
+		a.DB.SetRolesTTL(ttl)
+	}
+
+	roles := map[string][]string{}
+	for role := range viper.GetStringMap("rbac.roles") {
+		roles[role] = viper.GetStringSlice("rbac.roles." + role)
+	}
+	if len(roles) == 0 {
+		return
+	}
+
+	added, err := a.DB.BootstrapRoles(ctx, roles)
+	if err != nil {
+		log.Errorf("roles not bootstrapped: %v", err)
+
+		return
+	}
+	if added > 0 {
+		log.Infof("bootstrapped %d role permissions from config", added)
+	}
+}
+
+// sessionConfig reads admin user session settings from auth.sessions.*
+func (a *App) sessionConfig() handlers.SessionConfig {
+	cfg := handlers.SessionConfig{
+		AccessTTL:   viper.GetDuration("auth.sessions.access_ttl"),
+		RefreshTTL:  viper.GetDuration("auth.sessions.refresh_ttl"),
+		MaxLifetime: viper.GetDuration("auth.sessions.max_lifetime"),
+		MaxFailures: viper.GetInt("auth.sessions.max_failures"),
+		Lockout:     viper.GetDuration("auth.sessions.lockout"),
+	}
+	if cfg.AccessTTL <= 0 {
+		cfg.AccessTTL = defaultAccessTTL
+	}
+	if cfg.RefreshTTL <= 0 {
+		cfg.RefreshTTL = defaultRefreshTTL
+	}
+	if cfg.MaxLifetime <= 0 {
+		cfg.MaxLifetime = defaultSessionLifetime
+	}
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = defaultMaxLoginFailures
+	}
+	if cfg.Lockout <= 0 {
+		cfg.Lockout = defaultLockout
+	}
+
+	return cfg
+}
+
+// CreateAdminUser stores an admin user with a password
+func (a *App) CreateAdminUser(ctx context.Context, username, password string, roles, permissions []string) error {
+	log := logger.FromContext(ctx).WithField("m", "CreateAdminUser")
+	log.Debugf("CreateAdminUser:: username: %v", username)
+
+	if a.DB == nil {
+		return errNotInitialized
+	}
+	if username == "" {
+		return fmt.Errorf("username is not set")
+	}
+	if err := auth.CheckPasswordPolicy(password); err != nil {
+		return err
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return a.DB.CreateAdminUser(ctx, &models.AdminUser{
+		Username:     username,
+		PasswordHash: hash,
+		Roles:        roles,
+		Permissions:  permissions,
+	})
+}
+
+// signatureVerifier configures request signing from auth.hmac.*, it returns nil if no keys are set.
+// Keys are auth.hmac.keys.<key id>: {secret, service, roles, permissions}
+func (a *App) signatureVerifier(ctx context.Context) *auth.SignatureVerifier {
+	log := logger.FromContext(ctx).WithField("m", "signatureVerifier")
+	log.Debugf("signatureVerifier:: ")
+
+	var keys []auth.SigningKey
+	for id := range viper.GetStringMap("auth.hmac.keys") {
+		prefix := "auth.hmac.keys." + id + "."
+		key := auth.SigningKey{
+			ID:          id,
+			Secret:      []byte(viper.GetString(prefix + "secret")),
+			Service:     viper.GetString(prefix + "service"),
+			Roles:       viper.GetStringSlice(prefix + "roles"),
+			Permissions: viper.GetStringSlice(prefix + "permissions"),
+		}
+		if len(key.Secret) < minSigningSecretLen {
+			log.Errorf("signing key %v ignored, the secret must be at least %d bytes", id, minSigningSecretLen)
+
+			continue
+		}
+		if key.Service == "" {
+			key.Service = id
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	skew := viper.GetDuration("auth.hmac.max_skew")
+	if skew <= 0 {
+		skew = defaultSignatureSkew
+	}
+	nonces := viper.GetInt("auth.hmac.max_nonces")
+	if nonces <= 0 {
+		nonces = defaultSignatureNonce
+	}
+	log.Infof("request signing enabled, keys: %d", len(keys))
+
+	return auth.NewSignatureVerifier(keys, skew, nonces)
+}
+
+// outboundSigner returns the signer of calls to other services from auth.hmac.signer.{key_id, secret},
+// nil if it is not configured
+func outboundSigner() *auth.Signer {
+	id, secret := viper.GetString("auth.hmac.signer.key_id"), viper.GetString("auth.hmac.signer.secret")
+	if id == "" || secret == "" {
+		return nil
+	}
+
+	return &auth.Signer{KeyID: id, Secret: []byte(secret), Headers: []string{"content-type"}}
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"service_template/infra"
+	"service_template/ingest"
+	"service_template/logger"
+	"service_template/models"
+	"service_template/nodes/bitcoind"
+	"service_template/nodes/ethereum"
+	"service_template/nodes/rpc"
+	"service_template/nodes/tron"
+)
+
+const defaultNodeTimeout = 30 * time.Second
+
+// startIngestion starts block indexers configured as indexer.nodes.<chain> and subscribes
+// to node notifications configured as zmq.<chain>: <endpoint>
+func (a *App) startIngestion(ctx context.Context) {
+	log := logger.FromContext(ctx).WithField("m", "startIngestion")
+	log.Debugf("startIngestion:: ")
+
+	indexers := map[string]*ingest.Indexer{}
+	for key := range viper.GetStringMap("indexer.nodes") {
+		indexer, err := a.newIndexer(ctx, key)
+		if err != nil {
+			log.Errorf("indexer %v not started: %v", key, err)
+			continue
+		}
+		indexers[indexer.Chain.Name] = indexer
+		a.indexers = append(a.indexers, indexer)
+		infra.Go(ctx, "indexer_"+key, indexer.Run)
+	}
+
+	for chain, endpoint := range viper.GetStringMapString("zmq") {
+		ingestor := &ingest.BTCIngestor{
+			Chain:    strings.ToUpper(chain),
+			Endpoint: endpoint,
+			DB:       a.DB,
+		}
+		// a block announcement wakes the indexer of the chain up before its poll interval
+		if indexer, ok := indexers[ingestor.Chain]; ok {
+			ingestor.OnBlock = func(string) { indexer.Notify() }
+		}
+		infra.Go(ctx, "zmq_"+chain, ingestor.Run)
+	}
+}
+
+func (a *App) newIndexer(ctx context.Context, key string) (*ingest.Indexer, error) {
+	prefix := "indexer.nodes." + key + "."
+	name := strings.ToUpper(key)
+
+	chain, err := a.DB.LookupChain(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if chain == nil {
+		return nil, fmt.Errorf("chain %v is not in the catalog", name)
+	}
+
+	url := viper.GetString(prefix + "url")
+	if url == "" {
+		return nil, fmt.Errorf("%vurl is not set", prefix)
+	}
+	timeout := viper.GetDuration(prefix + "timeout")
+	if timeout == 0 {
+		timeout = defaultNodeTimeout
+	}
+
+	var source ingest.Source
+	switch chain.Kind {
+	case models.ChainKindBTC:
+		client := bitcoind.New(url,
+			rpc.WithBasicAuth(viper.GetString(prefix+"user"), viper.GetString(prefix+"password")),
+			rpc.WithTimeout(timeout))
+		source = ingest.NewBTCSource(chain.Name, client, a.DB)
+	case models.ChainKindETH:
+		opts := []rpc.Option{rpc.WithTimeout(timeout)}
+		if token := viper.GetString(prefix + "token"); token != "" {
+			opts = append(opts, rpc.WithHeader("Authorization", "Bearer "+token))
+		}
+		source = ingest.NewETHSource(chain.Name, ethereum.New(url, opts...), a.DB)
+	case models.ChainKindTRX:
+		client := tron.New(url, tron.WithAPIKey(viper.GetString(prefix+"api_key")), tron.WithTimeout(timeout))
+		source = ingest.NewTRXSource(chain.Name, client, a.DB)
+	default:
+		return nil, fmt.Errorf("chain %v has unsupported kind %v", chain.Name, chain.Kind)
+	}
+
+	indexer := ingest.NewIndexer(chain, source, a.DB)
+	if viper.IsSet(prefix + "start_height") {
+		indexer.StartHeight = viper.GetInt64(prefix + "start_height")
+	}
+	if n := viper.GetInt("indexer.batch_size"); n > 0 {
+		indexer.BatchSize = n
+	}
+	if d := viper.GetDuration("indexer.poll_interval"); d > 0 {
+		indexer.PollInterval = d
+	}
+	if n := viper.GetInt64("indexer.reorg_window"); n > 0 {
+		indexer.ReorgWindow = n
+	}
+
+	return indexer, nil
+}
+
+// indexerStatus returns the progress of indexers running in this process
+func (a *App) indexerStatus() []models.IndexerStatus {
+	ret := make([]models.IndexerStatus, 0, len(a.indexers))
+	for _, x := range a.indexers {
+		ret = append(ret, x.Status())
+	}
+
+	return ret
+}
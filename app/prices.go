@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"service_template/logger"
+	"service_template/valuation"
+)
+
+// ImportPrices stores prices read from CSV, all assets must be in the catalog
+func (a *App) ImportPrices(ctx context.Context, r io.Reader) (int, error) {
+	log := logger.FromContext(ctx).WithField("m", "ImportPrices")
+	log.Debugf("ImportPrices:: ")
+
+	if a.DB == nil {
+		return 0, errNotInitialized
+	}
+
+	prices, err := valuation.ParseCSV(r)
+	if err != nil {
+		return 0, err
+	}
+
+	checked := map[string]bool{}
+	for _, p := range prices {
+		if checked[p.AssetID] {
+			continue
+		}
+		asset, err := a.DB.LookupAsset(ctx, p.AssetID)
+		if err != nil {
+			return 0, err
+		}
+		if asset == nil {
+			return 0, fmt.Errorf("asset %v is not in the catalog", p.AssetID)
+		}
+		checked[p.AssetID] = true
+	}
+
+	return a.DB.ImportPrices(ctx, prices)
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"service_template/clientip"
+	"service_template/infra"
+	"service_template/logger"
+	"service_template/middlewares"
+	"service_template/ratelimit"
+)
+
+const ratelimitPruneInterval = time.Minute
+
+// clientIPResolver trusts X-Forwarded-For from the proxies in http.trusted_proxies
+func clientIPResolver(ctx context.Context) *clientip.Resolver {
+	log := logger.FromContext(ctx).WithField("m", "clientIPResolver")
+	log.Debugf("clientIPResolver:: ")
+
+	res, err := clientip.NewResolver(viper.GetStringSlice("http.trusted_proxies"))
+	if err != nil {
+		log.Errorf("X-Forwarded-For is ignored: %v", err)
+		res, _ = clientip.NewResolver(nil)
+	}
+
+	return res
+}
+
+// rateLimiters returns the middleware of the per address bucket taken before authentication and
+// the middleware of the route class buckets taken after it, nil for disabled ones
+func (a *App) rateLimiters(ctx context.Context) (ip, principal func(http.Handler) http.Handler) {
+	if !viper.GetBool("ratelimit.enabled") {
+		return nil, nil
+	}
+
+	limits := rateLimits(ctx)
+	all := make([]ratelimit.Limit, 0, len(limits)+1)
+	for _, l := range limits {
+		all = append(all, l)
+	}
+	ipLimit, ipLimited := ipRateLimit(ctx)
+	if ipLimited {
+		all = append(all, ipLimit)
+	}
+	store := a.rateLimitStore(ctx, all)
+
+	if ipLimited {
+		ip = middlewares.IPRateLimitMiddlewareGenerator(ctx, store, ipLimit)
+	}
+	principal = middlewares.RateLimitMiddlewareGenerator(ctx, store, limits, a.routes)
+
+	return ip, principal
+}
+
+// rateLimits reads ratelimit.classes.<class>: {requests, per, burst}, burst defaults to requests
+func rateLimits(ctx context.Context) map[string]ratelimit.Limit {
+	limits := map[string]ratelimit.Limit{}
+	for class := range viper.GetStringMap("ratelimit.classes") {
+		if l, ok := rateLimit(ctx, "ratelimit.classes."+class); ok {
+			limits[class] = l
+		}
+	}
+
+	return limits
+}
+
+// ipRateLimit reads ratelimit.ip: {requests, per, burst}, the bucket of a client address taken
+// before authentication. It returns false if the limit is not set
+func ipRateLimit(ctx context.Context) (ratelimit.Limit, bool) {
+	if !viper.IsSet("ratelimit.ip") {
+		return ratelimit.Limit{}, false
+	}
+
+	return rateLimit(ctx, "ratelimit.ip")
+}
+
+func rateLimit(ctx context.Context, key string) (ratelimit.Limit, bool) {
+	log := logger.FromContext(ctx).WithField("m", "rateLimit")
+
+	l := ratelimit.Limit{
+		Requests: viper.GetInt(key + ".requests"),
+		Per:      viper.GetDuration(key + ".per"),
+		Burst:    viper.GetInt(key + ".burst"),
+	}
+	if l.Burst == 0 {
+		l.Burst = l.Requests
+	}
+	if !l.Valid() {
+		log.Errorf("rate limit %v ignored, requests, per and burst must be positive", key)
+
+		return l, false
+	}
+
+	return l, true
+}
+
+// rateLimitStore keeps buckets in the database with ratelimit.shared, in memory otherwise,
+// and prunes buckets that stayed idle long enough to be full
+func (a *App) rateLimitStore(ctx context.Context, limits []ratelimit.Limit) ratelimit.Store {
+	var idle time.Duration
+	for _, l := range limits {
+		if fill := time.Duration(float64(l.Burst) / l.Rate() * float64(time.Second)); fill > idle {
+			idle = fill
+		}
+	}
+
+	if viper.GetBool("ratelimit.shared") {
+		store := ratelimit.NewSharedStore(a.DB)
+		a.goJob(ctx, "ratelimit_prune", ratelimitPruneInterval, func(ctx context.Context) error {
+			store.Fallback.Prune(time.Now().Add(-idle))
+			_, err := a.DB.DeleteIdleRateLimitBuckets(ctx, time.Now().Add(-idle))
+
+			return err
+		})
+
+		return store
+	}
+
+	store := ratelimit.NewMemoryStore()
+	infra.Go(ctx, "ratelimit_prune", func(ctx context.Context) error {
+		return store.Run(ctx, ratelimitPruneInterval, idle)
+	})
+
+	return store
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"service_template/logger"
+	"service_template/metrics"
+)
+
+const (
+	defaultReconciliationInterval = 10 * time.Minute
+	defaultReconciliationGrace    = 6 * time.Hour
+	defaultReconciliationLookback = 30 * 24 * time.Hour
+)
+
+// reconcile matches the internal transaction registry against chain transactions.
+// Entries younger than the grace period are skipped to let the indexer and the wallet catch up
+func (a *App) reconcile(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithField("m", "reconcile")
+	log.Debugf("reconcile:: ")
+
+	grace := viper.GetDuration("reconciliation.grace")
+	if grace == 0 {
+		grace = defaultReconciliationGrace
+	}
+	lookback := viper.GetDuration("reconciliation.lookback")
+	if lookback == 0 {
+		lookback = defaultReconciliationLookback
+	}
+
+	until := time.Now().Add(-grace)
+	found, err := a.DB.Reconcile(ctx, until.Add(-lookback), until)
+	if err != nil {
+		return err
+	}
+	metrics.ReconciliationNewFindings.Add(float64(found))
+	if found > 0 {
+		log.Warnf("reconciliation recorded %d new findings", found)
+	}
+
+	summary, err := a.DB.GetFindingsSummary(ctx)
+	if err != nil {
+		return err
+	}
+	metrics.ReconciliationFindings.Reset()
+	for _, s := range summary {
+		metrics.ReconciliationFindings.WithLabelValues(s.Kind, s.Status).Set(float64(s.Count))
+	}
+
+	return nil
+}
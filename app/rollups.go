@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"service_template/logger"
+	"service_template/storage"
+)
+
+const (
+	defaultRollupInterval = time.Minute
+	defaultRollupLookback = 24 * time.Hour
+	backfillChunk         = 24 * time.Hour
+)
+
+// refreshTurnover recomputes turnover buckets from the lookback window, or from the last
+// refreshed point if the job was down for longer, up to the end of the current hour
+func (a *App) refreshTurnover(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithField("m", "refreshTurnover")
+	log.Debugf("refreshTurnover:: ")
+
+	lookback := viper.GetDuration("rollup.lookback")
+	if lookback == 0 {
+		lookback = defaultRollupLookback
+	}
+
+	until := time.Now().UTC().Truncate(time.Hour).Add(time.Hour)
+	from := until.Add(-lookback)
+
+	last, err := a.DB.GetRollupState(ctx, storage.TurnoverRollup)
+	if err != nil {
+		return err
+	}
+	if !last.IsZero() && last.Before(from) {
+		from = last
+	}
+
+	if err := a.backfillTurnover(ctx, from, until); err != nil {
+		return err
+	}
+
+	return a.DB.SetRollupState(ctx, storage.TurnoverRollup, until)
+}
+
+// backfillTurnover recomputes turnover buckets in [from, to) day by day
+func (a *App) backfillTurnover(ctx context.Context, from, to time.Time) error {
+	log := logger.FromContext(ctx).WithField("m", "backfillTurnover")
+	log.Debugf("backfillTurnover:: from: %v, to: %v", from, to)
+
+	for start := from; start.Before(to); start = start.Add(backfillChunk) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := start.Add(backfillChunk)
+		if end.After(to) {
+			end = to
+		}
+		if err := a.DB.RefreshTurnover(ctx, start, end); err != nil {
+			return err
+		}
+		log.Infof("turnover refreshed from %v to %v", start, end)
+	}
+
+	return nil
+}
+
+// Backfill recomputes turnover rollups for a historical range
+func (a *App) Backfill(ctx context.Context, from, to time.Time) error {
+	log := logger.FromContext(ctx).WithField("m", "Backfill")
+	log.Debugf("Backfill:: from: %v, to: %v", from, to)
+
+	if a.DB == nil {
+		return errNotInitialized
+	}
+
+	return a.backfillTurnover(ctx, from.UTC().Truncate(time.Hour), to)
+}
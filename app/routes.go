@@ -0,0 +1,53 @@
+package app
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+
+	"service_template/middlewares"
+	"service_template/routes"
+)
+
+const (
+	defaultBodyLimit    = 1 << 20
+	defaultRouteTimeout = time.Minute
+	defaultLogBodyLimit = 4096
+	// exports and imports stream large result sets
+	exportTimeout = 10 * time.Minute
+)
+
+// routeDefaults reads the options of routes that declare none from http.*
+func routeDefaults() routes.Options {
+	opts := routes.Options{
+		BodyLimit: viper.GetInt64("http.body_limit"),
+		Timeout:   viper.GetDuration("http.timeout"),
+	}
+	if opts.BodyLimit == 0 {
+		opts.BodyLimit = defaultBodyLimit
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultRouteTimeout
+	}
+
+	return opts
+}
+
+// defaultRedactKeys hide credentials in logged request bodies
+var defaultRedactKeys = []string{"api_key", "authorization", "mnemonic", "password", "private_key", "secret", "seed", "signature", "token"}
+
+// accessLogConfig reads http.access_log.{body_limit, redact_keys}
+func accessLogConfig() middlewares.AccessLogConfig {
+	cfg := middlewares.AccessLogConfig{
+		BodyLimit:  viper.GetInt("http.access_log.body_limit"),
+		RedactKeys: viper.GetStringSlice("http.access_log.redact_keys"),
+	}
+	if cfg.BodyLimit == 0 {
+		cfg.BodyLimit = defaultLogBodyLimit
+	}
+	if len(cfg.RedactKeys) == 0 {
+		cfg.RedactKeys = defaultRedactKeys
+	}
+
+	return cfg
+}
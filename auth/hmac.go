@@ -0,0 +1,330 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"service_template/tracer"
+)
+
+// SignatureScheme is the Authorization scheme of signed requests:
+//
+//	Authorization: HMAC-SHA256 keyId="svc",timestamp="1700000000",nonce="…",headers="host;content-type",signature="…"
+//
+// The signature is the hex HMAC-SHA256 of the canonical request, see CanonicalRequest
+const SignatureScheme = "HMAC-SHA256"
+
+// headers every signature must cover
+var requiredSignedHeaders = []string{"host"}
+
+var (
+	// ErrSignatureMalformed is returned for Authorization values that cannot be parsed
+	ErrSignatureMalformed = errors.New("malformed signature")
+	// ErrSignatureInvalid wraps key, clock, replay and signature check failures
+	ErrSignatureInvalid = errors.New("invalid signature")
+	// ErrNonceCacheFull is returned for valid signatures while the nonce cache holds only
+	// unexpired nonces, the request cannot be checked for a replay and must be retried later
+	ErrNonceCacheFull = errors.New("nonce cache full")
+)
+
+// SigningKey is a shared secret of a calling service
+type SigningKey struct {
+	ID     string
+	Secret []byte
+	// principal of requests signed with the key
+	Service     string
+	Roles       []string
+	Permissions []string
+}
+
+// Signature is a parsed Authorization value of a signed request
+type Signature struct {
+	KeyID     string
+	Timestamp time.Time
+	Nonce     string
+	Headers   []string
+	Signature []byte
+}
+
+// ParseSignature reads an Authorization header value of the signature scheme
+func ParseSignature(header string) (*Signature, error) {
+	scheme, params, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || scheme != SignatureScheme {
+		return nil, ErrSignatureMalformed
+	}
+
+	values := map[string]string{}
+	for _, param := range strings.Split(params, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			return nil, ErrSignatureMalformed
+		}
+		values[k] = strings.Trim(v, `"`)
+	}
+
+	sec, err := strconv.ParseInt(values["timestamp"], 10, 64)
+	if err != nil {
+		return nil, ErrSignatureMalformed
+	}
+	sig, err := hex.DecodeString(values["signature"])
+	if err != nil || len(sig) != sha256.Size {
+		return nil, ErrSignatureMalformed
+	}
+	s := &Signature{
+		KeyID:     values["keyId"],
+		Timestamp: time.Unix(sec, 0),
+		Nonce:     values["nonce"],
+		Signature: sig,
+	}
+	if values["headers"] != "" {
+		s.Headers = strings.Split(strings.ToLower(values["headers"]), ";")
+	}
+	if s.KeyID == "" || len(s.Nonce) < 16 || len(s.Nonce) > 64 {
+		return nil, ErrSignatureMalformed
+	}
+
+	return s, nil
+}
+
+// CanonicalRequest returns the string a signature is computed over: method, escaped path,
+// sorted query, unix timestamp, nonce, hex SHA-256 of the body, the signed header names
+// and one name:value line per signed header
+func CanonicalRequest(r *http.Request, body []byte, s *Signature) string {
+	digest := sha256.Sum256(body)
+
+	var b strings.Builder
+	b.WriteString(r.Method + "\n")
+	b.WriteString(r.URL.EscapedPath() + "\n")
+	b.WriteString(r.URL.Query().Encode() + "\n")
+	b.WriteString(strconv.FormatInt(s.Timestamp.Unix(), 10) + "\n")
+	b.WriteString(s.Nonce + "\n")
+	b.WriteString(hex.EncodeToString(digest[:]) + "\n")
+	b.WriteString(strings.Join(s.Headers, ";") + "\n")
+	for _, h := range s.Headers {
+		b.WriteString(h + ":" + headerValue(r, h) + "\n")
+	}
+
+	return b.String()
+}
+
+func headerValue(r *http.Request, name string) string {
+	if name == "host" {
+		if r.Host != "" {
+			return strings.ToLower(r.Host)
+		}
+
+		return strings.ToLower(r.URL.Host)
+	}
+
+	return strings.TrimSpace(strings.Join(r.Header.Values(name), ","))
+}
+
+func computeSignature(secret []byte, canonical string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(canonical))
+
+	return mac.Sum(nil)
+}
+
+// readBody returns the body and replaces it with an unread copy
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// SignatureVerifier checks signed requests against shared keys and rejects replays.
+// Nonces are remembered per process, behind a load balancer a replay can reach another instance
+// within the skew window
+type SignatureVerifier struct {
+	// accepted difference between the signature timestamp and the local clock
+	MaxSkew time.Duration
+
+	keys   map[string]SigningKey
+	nonces *nonceCache
+}
+
+func NewSignatureVerifier(keys []SigningKey, maxSkew time.Duration, maxNonces int) *SignatureVerifier {
+	v := &SignatureVerifier{
+		MaxSkew: maxSkew,
+		keys:    map[string]SigningKey{},
+		nonces:  newNonceCache(maxNonces),
+	}
+	for _, k := range keys {
+		v.keys[k.ID] = k
+	}
+
+	return v
+}
+
+// Verify checks the signature of a request and returns the principal of its key.
+// The body is read and replaced, so handlers can read it again
+func (v *SignatureVerifier) Verify(r *http.Request, now time.Time) (*Principal, error) {
+	s, err := ParseSignature(r.Header.Get("Authorization"))
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := v.keys[s.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %v", ErrSignatureInvalid, s.KeyID)
+	}
+	if d := now.Sub(s.Timestamp); d > v.MaxSkew || d < -v.MaxSkew {
+		return nil, fmt.Errorf("%w: timestamp is %v off", ErrSignatureInvalid, d.Round(time.Second))
+	}
+	for _, h := range requiredSignedHeaders {
+		if !contains(s.Headers, h) {
+			return nil, fmt.Errorf("%w: %v is not signed", ErrSignatureInvalid, h)
+		}
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	}
+	if !hmac.Equal(computeSignature(key.Secret, CanonicalRequest(r, body, s)), s.Signature) {
+		return nil, fmt.Errorf("%w: bad signature", ErrSignatureInvalid)
+	}
+
+	// only nonces of valid signatures are remembered, so forged requests cannot fill the cache.
+	// A nonce is kept as long as its timestamp is acceptable
+	if err := v.nonces.add(key.ID+":"+s.Nonce, s.Timestamp.Add(v.MaxSkew), now); err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		ID:          key.Service,
+		Kind:        "service",
+		Roles:       key.Roles,
+		Permissions: key.Permissions,
+	}, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nonceCache remembers nonces until they expire. Unexpired nonces are never dropped,
+// that would let their requests be replayed, so a full cache refuses new nonces instead
+type nonceCache struct {
+	mu      sync.Mutex
+	max     int
+	expires map[string]time.Time
+}
+
+func newNonceCache(max int) *nonceCache {
+	return &nonceCache{max: max, expires: map[string]time.Time{}}
+}
+
+// add fails if the nonce was already seen or the cache is full of unexpired nonces
+func (c *nonceCache) add(nonce string, expires, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if exp, ok := c.expires[nonce]; ok && now.Before(exp) {
+		return fmt.Errorf("%w: replayed nonce", ErrSignatureInvalid)
+	}
+
+	if len(c.expires) >= c.max {
+		for n, exp := range c.expires {
+			if !now.Before(exp) {
+				delete(c.expires, n)
+			}
+		}
+	}
+	if len(c.expires) >= c.max {
+		return ErrNonceCacheFull
+	}
+	c.expires[nonce] = expires
+
+	return nil
+}
+
+// Signer signs outgoing requests with a shared key
+type Signer struct {
+	KeyID  string
+	Secret []byte
+	// headers signed besides host, e.g. content-type
+	Headers []string
+}
+
+// Sign sets the Authorization header of a request, the body is read and replaced
+func (s *Signer) Sign(r *http.Request, now time.Time) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+
+	sig := &Signature{
+		KeyID:     s.KeyID,
+		Timestamp: now,
+		Nonce:     hex.EncodeToString(nonce),
+		Headers:   append([]string{}, requiredSignedHeaders...),
+	}
+	for _, h := range s.Headers {
+		if h = strings.ToLower(h); !contains(sig.Headers, h) {
+			sig.Headers = append(sig.Headers, h)
+		}
+	}
+	sig.Signature = computeSignature(s.Secret, CanonicalRequest(r, body, sig))
+
+	r.Header.Set("Authorization", fmt.Sprintf(`%s keyId="%s",timestamp="%d",nonce="%s",headers="%s",signature="%s"`,
+		SignatureScheme, sig.KeyID, now.Unix(), sig.Nonce, strings.Join(sig.Headers, ";"), hex.EncodeToString(sig.Signature)))
+
+	return nil
+}
+
+// SigningHTTPClient signs requests before passing them to a traced client
+type SigningHTTPClient struct {
+	client tracer.HTTPClient
+	signer *Signer
+}
+
+var _ tracer.HTTPClient = (*SigningHTTPClient)(nil)
+
+// NewSigningHTTPClient signs requests with signer and sends them with client,
+// a nil client selects a TraceHTTPClient of http.DefaultClient
+func NewSigningHTTPClient(client *tracer.TraceHTTPClient, signer *Signer) *SigningHTTPClient {
+	if client == nil {
+		client = tracer.NewTraceHTTPClient(nil, nil)
+	}
+
+	return &SigningHTTPClient{client: client, signer: signer}
+}
+
+func (c *SigningHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if err := c.signer.Sign(req, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return c.client.Do(req)
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignatureVerify(t *testing.T) {
+	key := SigningKey{ID: "svc", Secret: []byte("0123456789abcdef0123456789abcdef"), Service: "billing"}
+	v := NewSignatureVerifier([]SigningKey{key}, time.Minute, 2)
+	signer := &Signer{KeyID: key.ID, Secret: key.Secret, Headers: []string{"Content-Type"}}
+
+	signed := func(at time.Time) string {
+		r := httptest.NewRequest("POST", "http://api.example/v1/wallets?b=2&a=1", strings.NewReader(`{"x":1}`))
+		r.Header.Set("Content-Type", "application/json")
+		if err := signer.Sign(r, at); err != nil {
+			t.Fatal(err)
+		}
+		return r.Header.Get("Authorization")
+	}
+	verify := func(auth string, now time.Time) (*Principal, error) {
+		r := httptest.NewRequest("POST", "http://api.example/v1/wallets?b=2&a=1", strings.NewReader(`{"x":1}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Authorization", auth)
+		return v.Verify(r, now)
+	}
+
+	first := signed(testNow)
+	p, err := verify(first, testNow)
+	if err != nil || p.ID != "billing" || p.Kind != "service" {
+		t.Fatalf("principal %+v, err: %v", p, err)
+	}
+	if _, err := verify(first, testNow.Add(time.Second)); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("replay: %v", err)
+	}
+	if _, err := verify(signed(testNow.Add(-2*time.Minute)), testNow); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("stale timestamp: %v", err)
+	}
+	if _, err := verify(strings.Replace(first, `keyId="svc"`, `keyId="other"`, 1), testNow); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("unknown key: %v", err)
+	}
+	if _, err := verify("HMAC-SHA256 keyId=svc", testNow); !errors.Is(err, ErrSignatureMalformed) {
+		t.Errorf("malformed: %v", err)
+	}
+
+	// the cache holds max 2 unexpired nonces, the third valid request cannot be checked
+	if _, err := verify(signed(testNow), testNow); err != nil {
+		t.Fatal(err)
+	}
+	third := signed(testNow)
+	if _, err := verify(third, testNow); !errors.Is(err, ErrNonceCacheFull) {
+		t.Errorf("full cache: %v", err)
+	}
+	// the cached nonces are still rejected as replays
+	if _, err := verify(first, testNow); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("replay with a full cache: %v", err)
+	}
+	// once they expire there is room again
+	later := testNow.Add(time.Minute)
+	if _, err := verify(signed(later), later); err != nil {
+		t.Errorf("after expiry: %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"service_template/logger"
+)
+
+// JWK is a verification key of a key set
+type JWK struct {
+	KeyID     string
+	Algorithm string
+	// *rsa.PublicKey, *ecdsa.PublicKey or []byte of an HMAC secret
+	Key interface{}
+}
+
+type rawJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// KeySet holds keys read from a JWKS file or from all *.json JWKS files of a directory
+type KeySet struct {
+	Path string
+
+	mu      sync.RWMutex
+	keys    []JWK
+	version string
+}
+
+// LoadKeySet reads a key set, the path must exist and hold at least one usable key
+func LoadKeySet(path string) (*KeySet, error) {
+	s := &KeySet{Path: path}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Keys returns keys with the key id, or all keys if kid is empty
+func (s *KeySet) Keys(kid string) []JWK {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if kid == "" {
+		return s.keys
+	}
+
+	var ret []JWK
+	for _, k := range s.keys {
+		if k.KeyID == kid {
+			ret = append(ret, k)
+		}
+	}
+
+	return ret
+}
+
+// Reload reads the key set again if any file changed, it reports whether keys were replaced.
+// On error the loaded keys are kept
+func (s *KeySet) Reload() (bool, error) {
+	files, version, err := s.files()
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	unchanged := version == s.version
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	var keys []JWK
+	for _, f := range files {
+		fileKeys, err := readJWKS(f)
+		if err != nil {
+			return false, fmt.Errorf("%v: %v", f, err)
+		}
+		keys = append(keys, fileKeys...)
+	}
+	if len(keys) == 0 {
+		return false, fmt.Errorf("no usable keys in %v", s.Path)
+	}
+
+	s.mu.Lock()
+	s.keys, s.version = keys, version
+	s.mu.Unlock()
+
+	return true, nil
+}
+
+// Run reloads the key set every interval until ctx is done
+func (s *KeySet) Run(ctx context.Context, interval time.Duration) error {
+	log := logger.FromContext(ctx).WithField("m", "Run")
+	log.Debugf("Run:: path: %v, interval: %v", s.Path, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		reloaded, err := s.Reload()
+		if err != nil {
+			log.Errorf("key set %v not reloaded, keeping loaded keys: %v", s.Path, err)
+		} else if reloaded {
+			log.Infof("key set %v reloaded, keys: %d", s.Path, len(s.Keys("")))
+		}
+	}
+}
+
+// files lists the key set files and a version string that changes with any of them
+func (s *KeySet) files() ([]string, string, error) {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	files := []string{s.Path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(s.Path, "*.json")); err != nil {
+			return nil, "", err
+		}
+		sort.Strings(files)
+	}
+
+	var version strings.Builder
+	for _, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return nil, "", err
+		}
+		fmt.Fprintf(&version, "%v:%d:%d;", f, fi.Size(), fi.ModTime().UnixNano())
+	}
+
+	return files, version.String(), nil
+}
+
+func readJWKS(path string) ([]JWK, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []rawJWK `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+
+	var keys []JWK
+	for i, raw := range set.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+		k, err := parseJWK(raw)
+		if err != nil {
+			return nil, fmt.Errorf("key %d (%v): %v", i, raw.Kid, err)
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, nil
+}
+
+func parseJWK(raw rawJWK) (JWK, error) {
+	k := JWK{KeyID: raw.Kid, Algorithm: raw.Alg}
+
+	switch raw.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(raw.K, "="))
+		if err != nil || len(secret) == 0 {
+			return k, fmt.Errorf("invalid k")
+		}
+		k.Key = secret
+
+	case "RSA":
+		n, err := decodeBigInt(raw.N)
+		if err != nil {
+			return k, fmt.Errorf("invalid n")
+		}
+		e, err := decodeBigInt(raw.E)
+		if err != nil || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return k, fmt.Errorf("invalid e")
+		}
+		if n.BitLen() < 2048 {
+			return k, fmt.Errorf("RSA keys shorter than 2048 bits are not accepted")
+		}
+		k.Key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+
+	case "EC":
+		if raw.Crv != "P-256" {
+			return k, fmt.Errorf("unsupported curve %v", raw.Crv)
+		}
+		x, err := decodeBigInt(raw.X)
+		if err != nil {
+			return k, fmt.Errorf("invalid x")
+		}
+		y, err := decodeBigInt(raw.Y)
+		if err != nil {
+			return k, fmt.Errorf("invalid y")
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return k, fmt.Errorf("point is not on the curve")
+		}
+		k.Key = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+
+	default:
+		return k, fmt.Errorf("unsupported key type %v", raw.Kty)
+	}
+
+	return k, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid base64url integer")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
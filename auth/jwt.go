@@ -0,0 +1,286 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// JWT signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+var (
+	// ErrTokenMalformed is returned for tokens that cannot be decoded
+	ErrTokenMalformed = errors.New("malformed token")
+	// ErrTokenInvalid wraps signature and claim check failures
+	ErrTokenInvalid = errors.New("invalid token")
+)
+
+// JWTConfig describes accepted tokens and how their claims map to a principal.
+// Claim names may be dotted paths into nested objects, e.g. realm_access.roles
+type JWTConfig struct {
+	Issuer string
+	// a token must name one of the audiences, any audience is accepted if empty
+	Audience []string
+	Leeway   time.Duration
+	// allowed signing algorithms, all supported algorithms if empty
+	Algorithms []string
+
+	UserClaim        string
+	RolesClaim       string
+	PermissionsClaim string
+	// principal kind of token holders
+	Kind string
+}
+
+// JWTVerifier checks signed tokens against a key set
+type JWTVerifier struct {
+	Config JWTConfig
+	Keys   *KeySet
+}
+
+func NewJWTVerifier(cfg JWTConfig, keys *KeySet) *JWTVerifier {
+	if cfg.UserClaim == "" {
+		cfg.UserClaim = "sub"
+	}
+	if cfg.RolesClaim == "" {
+		cfg.RolesClaim = "roles"
+	}
+	if cfg.PermissionsClaim == "" {
+		cfg.PermissionsClaim = "permissions"
+	}
+	if cfg.Kind == "" {
+		cfg.Kind = "user"
+	}
+	if len(cfg.Algorithms) == 0 {
+		cfg.Algorithms = []string{AlgHS256, AlgRS256, AlgES256}
+	}
+
+	return &JWTVerifier{Config: cfg, Keys: keys}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// Verify checks the signature and the registered claims of a compact JWS token
+// and returns the principal it maps to
+func (v *JWTVerifier) Verify(token string, now time.Time) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if !v.allowed(header.Alg) {
+		return nil, fmt.Errorf("%w: algorithm %q is not allowed", ErrTokenInvalid, header.Alg)
+	}
+	if !v.verifySignature(header, []byte(parts[0]+"."+parts[1]), sig) {
+		return nil, fmt.Errorf("%w: bad signature", ErrTokenInvalid)
+	}
+	if err := v.checkClaims(claims, now); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+	}
+
+	user, _ := claim(claims, v.Config.UserClaim).(string)
+	if user == "" {
+		return nil, fmt.Errorf("%w: no %v claim", ErrTokenInvalid, v.Config.UserClaim)
+	}
+
+	return &Principal{
+		ID:          user,
+		Kind:        v.Config.Kind,
+		Roles:       stringList(claim(claims, v.Config.RolesClaim)),
+		Permissions: stringList(claim(claims, v.Config.PermissionsClaim)),
+	}, nil
+}
+
+func (v *JWTVerifier) allowed(alg string) bool {
+	for _, a := range v.Config.Algorithms {
+		if a == alg {
+			return true
+		}
+	}
+
+	return false
+}
+
+// verifySignature tries keys with the token key id, a key is only used with the algorithm
+// of its type so a public key can never be taken for an HMAC secret
+func (v *JWTVerifier) verifySignature(h jwtHeader, signed, sig []byte) bool {
+	digest := sha256.Sum256(signed)
+
+	for _, k := range v.Keys.Keys(h.Kid) {
+		if k.Algorithm != "" && k.Algorithm != h.Alg {
+			continue
+		}
+
+		switch key := k.Key.(type) {
+		case []byte:
+			if h.Alg != AlgHS256 {
+				continue
+			}
+			mac := hmac.New(sha256.New, key)
+			mac.Write(signed)
+			if hmac.Equal(mac.Sum(nil), sig) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if h.Alg != AlgRS256 {
+				continue
+			}
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil {
+				return true
+			}
+		case *ecdsa.PublicKey:
+			if h.Alg != AlgES256 || len(sig) != 64 {
+				continue
+			}
+			r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+			if ecdsa.Verify(key, digest[:], r, s) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (v *JWTVerifier) checkClaims(claims map[string]interface{}, now time.Time) error {
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return fmt.Errorf("no exp claim")
+	}
+	if !now.Before(exp.Add(v.Config.Leeway)) {
+		return fmt.Errorf("token expired")
+	}
+	for _, name := range []string{"nbf", "iat"} {
+		c, present := claims[name]
+		if !present {
+			continue
+		}
+		t, ok := numericDate(c)
+		if !ok {
+			return fmt.Errorf("invalid %v claim", name)
+		}
+		if now.Add(v.Config.Leeway).Before(t) {
+			if name == "nbf" {
+				return fmt.Errorf("token not valid yet")
+			}
+			return fmt.Errorf("token issued in the future")
+		}
+	}
+
+	if v.Config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.Config.Issuer {
+			return fmt.Errorf("unexpected issuer %q", iss)
+		}
+	}
+
+	if len(v.Config.Audience) > 0 {
+		found := false
+		for _, aud := range stringList(claims["aud"]) {
+			for _, want := range v.Config.Audience {
+				if aud == want {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return fmt.Errorf("unexpected audience")
+		}
+	}
+
+	return nil
+}
+
+func decodeSegment(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.UseNumber()
+
+	return dec.Decode(v)
+}
+
+// maxNumericDate is 9999-12-31T23:59:59Z, later dates are rejected rather than overflowing
+const maxNumericDate = 253402300799
+
+// numericDate returns the time of seconds since the epoch, possibly fractional
+func numericDate(v interface{}) (time.Time, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil || math.IsNaN(f) || math.Abs(f) > maxNumericDate {
+		return time.Time{}, false
+	}
+
+	sec, frac := math.Modf(f)
+
+	return time.Unix(int64(sec), int64(frac*float64(time.Second))), true
+}
+
+// claim returns the value at a dotted path
+func claim(claims map[string]interface{}, path string) interface{} {
+	var v interface{} = claims
+	for _, name := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+
+	return v
+}
+
+// stringList reads an array of strings or a space or comma separated string, such as a scope claim
+func stringList(v interface{}) []string {
+	var ret []string
+	switch x := v.(type) {
+	case string:
+		for _, s := range strings.FieldsFunc(x, func(r rune) bool { return r == ' ' || r == ',' }) {
+			ret = append(ret, s)
+		}
+	case []interface{}:
+		for _, item := range x {
+			if s, ok := item.(string); ok && s != "" {
+				ret = append(ret, s)
+			}
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testNow    = time.Unix(1700000000, 0)
+	testSecret = []byte("0123456789abcdef0123456789abcdef")
+)
+
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testKeys{rsa: rk, ec: ek}
+}
+
+func (k testKeys) keySet() *KeySet {
+	return &KeySet{keys: []JWK{
+		{KeyID: "hs", Algorithm: AlgHS256, Key: testSecret},
+		{KeyID: "rs", Algorithm: AlgRS256, Key: &k.rsa.PublicKey},
+		{KeyID: "es", Key: &k.ec.PublicKey},
+	}}
+}
+
+func segment(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// sign builds a compact token, key is an HMAC secret, *rsa.PrivateKey or *ecdsa.PrivateKey
+func sign(t *testing.T, header map[string]string, claims map[string]interface{}, key interface{}) string {
+	t.Helper()
+
+	signed := segment(t, header) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub": "alice",
+		"iss": "https://issuer",
+		"aud": "service",
+		"exp": testNow.Add(time.Hour).Unix(),
+		"iat": testNow.Unix(),
+	}
+}
+
+func TestJWTAlgorithms(t *testing.T) {
+	keys := newTestKeys(t)
+	v := NewJWTVerifier(JWTConfig{Issuer: "https://issuer", Audience: []string{"service"}}, keys.keySet())
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"HS256", sign(t, map[string]string{"alg": AlgHS256, "kid": "hs"}, validClaims(), testSecret), true},
+		{"RS256", sign(t, map[string]string{"alg": AlgRS256, "kid": "rs"}, validClaims(), keys.rsa), true},
+		{"ES256", sign(t, map[string]string{"alg": AlgES256, "kid": "es"}, validClaims(), keys.ec), true},
+		{"ES256 without kid", sign(t, map[string]string{"alg": AlgES256}, validClaims(), keys.ec), true},
+		{"unknown kid", sign(t, map[string]string{"alg": AlgHS256, "kid": "other"}, validClaims(), testSecret), false},
+		{"wrong secret", sign(t, map[string]string{"alg": AlgHS256, "kid": "hs"}, validClaims(), []byte("other secret")), false},
+		{"none", segment(t, map[string]string{"alg": "none"}) + "." + segment(t, validClaims()) + ".", false},
+		{"HS512", sign(t, map[string]string{"alg": "HS512", "kid": "hs"}, validClaims(), testSecret), false},
+		// the public key used as an HMAC secret must not verify
+		{"HS256 keyed with RSA public key", sign(t, map[string]string{"alg": AlgHS256, "kid": "rs"}, validClaims(), x509.MarshalPKCS1PublicKey(&keys.rsa.PublicKey)), false},
+		{"HS256 keyed with EC public key", sign(t, map[string]string{"alg": AlgHS256, "kid": "es"}, validClaims(), elliptic.Marshal(elliptic.P256(), keys.ec.X, keys.ec.Y)), false},
+		// the key of the kid is pinned to RS256
+		{"ES256 with RS256 kid", sign(t, map[string]string{"alg": AlgES256, "kid": "rs"}, validClaims(), keys.ec), false},
+		{"RS256 with HS256 kid", sign(t, map[string]string{"alg": AlgRS256, "kid": "hs"}, validClaims(), keys.rsa), false},
+	}
+	for _, tt := range tests {
+		p, err := v.Verify(tt.token, testNow)
+		if tt.ok && (err != nil || p.ID != "alice") {
+			t.Errorf("%v: %v", tt.name, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%v: accepted", tt.name)
+		}
+	}
+
+	restricted := NewJWTVerifier(JWTConfig{Algorithms: []string{AlgRS256}}, keys.keySet())
+	token := sign(t, map[string]string{"alg": AlgHS256, "kid": "hs"}, validClaims(), testSecret)
+	if _, err := restricted.Verify(token, testNow); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("algorithm outside the allowed list: %v", err)
+	}
+}
+
+func TestJWTMalformed(t *testing.T) {
+	v := NewJWTVerifier(JWTConfig{}, newTestKeys(t).keySet())
+	valid := sign(t, map[string]string{"alg": AlgHS256}, validClaims(), testSecret)
+	parts := strings.Split(valid, ".")
+
+	for _, token := range []string{
+		"",
+		"a.b",
+		valid + ".x",
+		"!!." + parts[1] + "." + parts[2],
+		parts[0] + ".!!." + parts[2],
+		parts[0] + "." + parts[1] + ".!!",
+		base64.RawURLEncoding.EncodeToString([]byte("[]")) + "." + parts[1] + "." + parts[2],
+	} {
+		if _, err := v.Verify(token, testNow); !errors.Is(err, ErrTokenMalformed) {
+			t.Errorf("%q: %v", token, err)
+		}
+	}
+
+	tampered := parts[0] + "." + segment(t, map[string]interface{}{"sub": "mallory", "exp": testNow.Add(time.Hour).Unix()}) + "." + parts[2]
+	if _, err := v.Verify(tampered, testNow); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("tampered claims: %v", err)
+	}
+}
+
+func TestJWTClaims(t *testing.T) {
+	leeway := 30 * time.Second
+	v := NewJWTVerifier(JWTConfig{
+		Issuer:     "https://issuer",
+		Audience:   []string{"service", "other"},
+		Leeway:     leeway,
+		RolesClaim: "realm_access.roles",
+		Kind:       "staff",
+	}, newTestKeys(t).keySet())
+
+	with := func(set map[string]interface{}, unset ...string) map[string]interface{} {
+		c := validClaims()
+		for k, val := range set {
+			c[k] = val
+		}
+		for _, k := range unset {
+			delete(c, k)
+		}
+		return c
+	}
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		ok     bool
+	}{
+		{"valid", validClaims(), true},
+		{"expired within leeway", with(map[string]interface{}{"exp": testNow.Add(-leeway / 2).Unix()}), true},
+		{"expired beyond leeway", with(map[string]interface{}{"exp": testNow.Add(-leeway).Unix()}), false},
+		{"fractional exp", with(map[string]interface{}{"exp": float64(testNow.Unix()) + 0.5}), true},
+		{"no exp", with(nil, "exp"), false},
+		{"string exp", with(map[string]interface{}{"exp": "2099-01-01"}), false},
+		{"far future exp", with(map[string]interface{}{"exp": 1e10}), true},
+		{"overflowing exp", with(map[string]interface{}{"exp": 1e300}), false},
+		{"overflowing negative exp", with(map[string]interface{}{"exp": -1e300}), false},
+		{"overflowing nbf", with(map[string]interface{}{"nbf": 1e300}), false},
+		{"string iat", with(map[string]interface{}{"iat": "yesterday"}), false},
+		{"nbf within leeway", with(map[string]interface{}{"nbf": testNow.Add(leeway / 2).Unix()}), true},
+		{"nbf beyond leeway", with(map[string]interface{}{"nbf": testNow.Add(leeway + time.Second).Unix()}), false},
+		{"iat in the future", with(map[string]interface{}{"iat": testNow.Add(time.Hour).Unix()}), false},
+		{"other issuer", with(map[string]interface{}{"iss": "https://evil"}), false},
+		{"no issuer", with(nil, "iss"), false},
+		{"audience list", with(map[string]interface{}{"aud": []string{"x", "other"}}), true},
+		{"other audience", with(map[string]interface{}{"aud": "x"}), false},
+		{"audience prefix", with(map[string]interface{}{"aud": "servic"}), false},
+		{"no audience", with(nil, "aud"), false},
+		{"no subject", with(nil, "sub"), false},
+	}
+	for _, tt := range tests {
+		token := sign(t, map[string]string{"alg": AlgHS256, "kid": "hs"}, tt.claims, testSecret)
+		_, err := v.Verify(token, testNow)
+		if tt.ok && err != nil {
+			t.Errorf("%v: %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrTokenInvalid) {
+			t.Errorf("%v: %v", tt.name, err)
+		}
+	}
+
+	claims := with(map[string]interface{}{
+		"realm_access": map[string]interface{}{"roles": []string{"operator", "viewer"}},
+		"permissions":  "wallets.read catalog.read,alerts.read",
+	})
+	p, err := v.Verify(sign(t, map[string]string{"alg": AlgHS256}, claims, testSecret), testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Principal{
+		ID:          "alice",
+		Kind:        "staff",
+		Roles:       []string{"operator", "viewer"},
+		Permissions: []string{"wallets.read", "catalog.read", "alerts.read"},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("principal %+v, want %+v", p, want)
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	keys := newTestKeys(t)
+	dir := t.TempDir()
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwks := map[string]interface{}{"keys": []map[string]string{
+		{"kty": "oct", "kid": "hs", "alg": AlgHS256, "k": b64(testSecret)},
+		{"kty": "RSA", "kid": "rs", "n": b64(keys.rsa.N.Bytes()), "e": "AQAB"},
+		{"kty": "EC", "kid": "es", "crv": "P-256", "x": b64(keys.ec.X.Bytes()), "y": b64(keys.ec.Y.Bytes())},
+	}}
+	b, _ := json.Marshal(jwks)
+	if err := os.WriteFile(filepath.Join(dir, "keys.json"), b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := LoadKeySet(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(set.Keys("")); n != 3 {
+		t.Fatalf("%v keys loaded", n)
+	}
+
+	v := NewJWTVerifier(JWTConfig{}, set)
+	for _, token := range []string{
+		sign(t, map[string]string{"alg": AlgHS256, "kid": "hs"}, validClaims(), testSecret),
+		sign(t, map[string]string{"alg": AlgRS256, "kid": "rs"}, validClaims(), keys.rsa),
+		sign(t, map[string]string{"alg": AlgES256, "kid": "es"}, validClaims(), keys.ec),
+	} {
+		if _, err := v.Verify(token, testNow); err != nil {
+			t.Errorf("loaded key: %v", err)
+		}
+	}
+
+	if _, err := LoadKeySet(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("loaded a missing key set")
+	}
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// argon2id parameters of new hashes, hashes with other parameters are still verified
+// and reported as needing a rehash
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 2
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+// password length limits, long enough for passphrases but bounded to keep hashing cheap to reject
+const (
+	MinPasswordLen = 12
+	MaxPasswordLen = 256
+)
+
+var errUnknownHash = errors.New("unknown password hash format")
+
+// dummyHash is verified for unknown users so that a login takes the same time either way
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// CheckPasswordPolicy returns an error describing why a password cannot be set
+func CheckPasswordPolicy(password string) error {
+	if len(password) < MinPasswordLen {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLen)
+	}
+	if len(password) > MaxPasswordLen {
+		return fmt.Errorf("password must be at most %d characters", MaxPasswordLen)
+	}
+
+	return nil
+}
+
+// HashPassword returns an argon2id hash in the PHC string format
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPassword checks a password against an argon2id or bcrypt hash. rehash is set when the
+// password matched a hash that should be replaced by one from HashPassword
+func VerifyPassword(password, hash string) (ok, rehash bool, err error) {
+	if strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$") {
+		err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+
+		return err == nil, err == nil, err
+	}
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		return false, false, errUnknownHash
+	}
+
+	var version, memory, iterations int
+	var threads uint8
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, false, errUnknownHash
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, errUnknownHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil ||
+		memory <= 0 || iterations <= 0 || threads == 0 {
+		return false, false, errUnknownHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, errUnknownHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false, errUnknownHash
+	}
+
+	got := argon2.IDKey([]byte(password), salt, uint32(iterations), uint32(memory), threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(got, key) != 1 {
+		return false, false, nil
+	}
+	rehash = memory != argonMemory || iterations != argonTime || threads != argonThreads || len(key) != argonKeyLen
+
+	return true, rehash, nil
+}
+
+// VerifyDummyPassword spends the time of a password check, for logins of unknown users
+func VerifyDummyPassword(password string) {
+	dummyHashOnce.Do(func() { dummyHash, _ = HashPassword("dummy password for unknown users") })
+	VerifyPassword(password, dummyHash)
+}
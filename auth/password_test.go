@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordArgon2id(t *testing.T) {
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$", argon2.Version, argonMemory, argonTime, argonThreads)) {
+		t.Fatalf("hash %v", hash)
+	}
+	if other, _ := HashPassword("correct horse battery staple"); other == hash {
+		t.Error("hashes share a salt")
+	}
+
+	ok, rehash, err := VerifyPassword("correct horse battery staple", hash)
+	if !ok || rehash || err != nil {
+		t.Errorf("ok: %v, rehash: %v, err: %v", ok, rehash, err)
+	}
+	ok, rehash, err = VerifyPassword("correct horse battery stapler", hash)
+	if ok || rehash || err != nil {
+		t.Errorf("wrong password: ok: %v, rehash: %v, err: %v", ok, rehash, err)
+	}
+}
+
+func TestPasswordRehash(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("old password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a hash with weaker parameters than the current ones
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte("old password"), salt, 1, 8*1024, 1, argonKeyLen)
+	weakArgon := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, 8*1024, 1, 1,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+	for _, hash := range []string{string(bcryptHash), weakArgon} {
+		ok, rehash, err := VerifyPassword("old password", hash)
+		if !ok || !rehash || err != nil {
+			t.Errorf("%v: ok: %v, rehash: %v, err: %v", hash, ok, rehash, err)
+		}
+		// a mismatch never asks for a rehash
+		ok, rehash, err = VerifyPassword("new password", hash)
+		if ok || rehash || err != nil {
+			t.Errorf("%v wrong password: ok: %v, rehash: %v, err: %v", hash, ok, rehash, err)
+		}
+	}
+}
+
+func TestPasswordUnknownHash(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"plaintext",
+		"$argon2i$v=19$m=65536,t=3,p=2$c2FsdA$a2V5",
+		"$argon2id$v=16$m=65536,t=3,p=2$c2FsdA$a2V5",
+		"$argon2id$v=19$m=0,t=3,p=2$c2FsdA$a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=2$!!$a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$",
+		"$argon2id$v=19$m=65536,t=3,p=2$c2FsdA",
+	} {
+		if ok, _, err := VerifyPassword("password", hash); ok || err == nil {
+			t.Errorf("%q: ok: %v, err: %v", hash, ok, err)
+		}
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	tests := []struct {
+		password string
+		ok       bool
+	}{
+		{strings.Repeat("a", MinPasswordLen-1), false},
+		{strings.Repeat("a", MinPasswordLen), true},
+		{strings.Repeat("a", MaxPasswordLen), true},
+		{strings.Repeat("a", MaxPasswordLen+1), false},
+	}
+	for _, tt := range tests {
+		if err := CheckPasswordPolicy(tt.password); (err == nil) != tt.ok {
+			t.Errorf("length %v: %v", len(tt.password), err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+// Permissions required by routes and checked by handlers
+const (
+	PermissionWalletsRead         = "wallets.read"
+	PermissionWalletsRemove       = "wallets.remove"
+	PermissionTransactionsRead    = "transactions.read"
+	PermissionTransactionsWrite   = "transactions.write"
+	PermissionReportsRead         = "reports.read"
+	PermissionExportRead          = "export.read"
+	PermissionCatalogRead         = "catalog.read"
+	PermissionCatalogWrite        = "catalog.write"
+	PermissionPricesRead          = "prices.read"
+	PermissionPricesWrite         = "prices.write"
+	PermissionReconciliationRead  = "reconciliation.read"
+	PermissionReconciliationWrite = "reconciliation.write"
+	PermissionAlertsRead          = "alerts.read"
+	PermissionAlertsWrite         = "alerts.write"
+	PermissionAlertRulesWrite     = "alert_rules.write"
+	PermissionIndexerRead         = "indexer.read"
+	PermissionRolesRead           = "roles.read"
+	PermissionRolesWrite          = "roles.write"
+	PermissionAPIClientsRead      = "api_clients.read"
+	PermissionAPIClientsWrite     = "api_clients.write"
+	PermissionUsersRead           = "users.read"
+	PermissionUsersWrite          = "users.write"
+)
+
+// PermissionAll grants every permission, a permission ending in .* grants the permissions it prefixes
+const PermissionAll = "*"
+
+// Grants reports whether a granted permission covers the required one
+func Grants(granted, required string) bool {
+	if granted == PermissionAll || granted == required {
+		return true
+	}
+
+	return strings.HasSuffix(granted, ".*") && strings.HasPrefix(required, strings.TrimSuffix(granted, "*"))
+}
+
+// Principal is an authenticated caller
+type Principal struct {
+	ID          string   `json:"id"`
+	Kind        string   `json:"kind"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	// login session of an admin user, zero for other principals
+	SessionID uint `json:"-"`
+}
+
+// Can reports whether the principal has a permission
+func (p *Principal) Can(permission string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, perm := range p.Permissions {
+		if Grants(perm, permission) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String identifies the principal in logs and audit entries
+func (p *Principal) String() string {
+	if p == nil {
+		return "anonymous"
+	}
+
+	return p.Kind + ":" + p.ID
+}
+
+type contextKey struct{}
+
+// ToContext instruments context with the authenticated principal
+func ToContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns nil if the request is not authenticated
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(contextKey{}).(*Principal)
+
+	return p
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// tokens look like <scheme>_<prefix>_<secret>, the prefix identifies the holder and is not secret
+const (
+	tokenScheme    = "st"
+	tokenPrefixLen = 8
+	tokenSecretLen = 32
+)
+
+// Token schemes of admin user sessions
+const (
+	SessionTokenScheme = "ss"
+	RefreshTokenScheme = "sr"
+)
+
+// NewToken returns a random API client token, its prefix and the hash to store
+func NewToken() (token, prefix, hash string, err error) {
+	return NewSchemeToken(tokenScheme)
+}
+
+// NewSchemeToken returns a random token of a scheme, its prefix and the hash to store
+func NewSchemeToken(scheme string) (token, prefix, hash string, err error) {
+	p := make([]byte, tokenPrefixLen/2)
+	if _, err = rand.Read(p); err != nil {
+		return "", "", "", err
+	}
+	secret := make([]byte, tokenSecretLen)
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = hex.EncodeToString(p)
+	token = scheme + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	return token, prefix, HashToken(token), nil
+}
+
+// TokenPrefix returns the prefix of a well-formed API client token
+func TokenPrefix(token string) (string, bool) {
+	return SchemeTokenPrefix(tokenScheme, token)
+}
+
+// SchemeTokenPrefix returns the prefix of a well-formed token of a scheme
+func SchemeTokenPrefix(scheme, token string) (string, bool) {
+	parts := strings.SplitN(token, "_", 3)
+	if len(parts) != 3 || parts[0] != scheme || len(parts[1]) != tokenPrefixLen || parts[2] == "" {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+// HashToken returns the hex SHA-256 of a token. Tokens are random, so a fast hash is enough
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyToken compares the hash of token with a stored hash in constant time
+func VerifyToken(token, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashToken(token)), []byte(hash)) == 1
+}
+
+// BearerToken returns the token of an `Authorization: Bearer <token>` header value
+func BearerToken(header string) string {
+	header = strings.TrimSpace(header)
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+
+	return ""
+}
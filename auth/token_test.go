@@ -0,0 +1,70 @@
+package auth
+
+import "testing"
+
+func TestToken(t *testing.T) {
+	token, prefix, hash, err := NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := TokenPrefix(token); !ok || p != prefix {
+		t.Errorf("prefix %q %v, want %q", p, ok, prefix)
+	}
+	if !VerifyToken(token, hash) {
+		t.Error("token not verified")
+	}
+	if VerifyToken(token+"x", hash) || VerifyToken(token, "") {
+		t.Error("wrong token verified")
+	}
+
+	session, _, _, err := NewSchemeToken(SessionTokenScheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := TokenPrefix(session); ok {
+		t.Error("session token accepted as an API client token")
+	}
+	if _, ok := SchemeTokenPrefix(SessionTokenScheme, session); !ok {
+		t.Error("session token not parsed")
+	}
+
+	malformed := []string{
+		"",
+		"st",
+		"st_0123abcd",
+		"st_0123abcd_",
+		"st_0123abc_secret",
+		"st_0123abcde_secret",
+		"xx_0123abcd_secret",
+		"ST_0123abcd_secret",
+		"eyJhbGciOiJIUzI1NiJ9.e30.sig",
+	}
+	for _, s := range malformed {
+		if p, ok := TokenPrefix(s); ok {
+			t.Errorf("%q: prefix %q", s, p)
+		}
+	}
+	// the secret may contain the separator
+	if p, ok := TokenPrefix("st_0123abcd_se_cret"); !ok || p != "0123abcd" {
+		t.Errorf("separator in secret: %q %v", p, ok)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"Bearer abc", "abc"},
+		{"bearer  abc ", "abc"},
+		{" BEARER abc", "abc"},
+		{"Basic abc", ""},
+		{"Bearer", ""},
+		{"Bearerabc", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := BearerToken(tt.header); got != tt.want {
+			t.Errorf("%q: %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package btc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+var ErrMalformedTx = errors.New("malformed transaction")
+
+// OutPoint references an output of a previous transaction
+type OutPoint struct {
+	TxID string
+	Vout uint32
+}
+
+type TxIn struct {
+	PrevOut OutPoint
+}
+
+type TxOut struct {
+	Value  int64
+	Script []byte
+}
+
+// Tx is a decoded bitcoin transaction
+type Tx struct {
+	TxID string
+	// WTxID commits to witness data too, it equals TxID for transactions without witnesses
+	WTxID   string
+	Inputs  []TxIn
+	Outputs []TxOut
+}
+
+// Coinbase reports whether the transaction creates new coins
+func (tx *Tx) Coinbase() bool {
+	return len(tx.Inputs) == 1 && tx.Inputs[0].PrevOut.TxID == hex.EncodeToString(make([]byte, 32))
+}
+
+type txReader struct {
+	r *bytes.Reader
+	// non-witness serialization used for the transaction id
+	stripped bytes.Buffer
+	keep     bool
+}
+
+func (t *txReader) read(n int) ([]byte, error) {
+	if n < 0 || n > t.r.Len() {
+		return nil, ErrMalformedTx
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(t.r, b); err != nil {
+		return nil, ErrMalformedTx
+	}
+	if t.keep {
+		t.stripped.Write(b)
+	}
+
+	return b, nil
+}
+
+func (t *txReader) uint32() (uint32, error) {
+	b, err := t.read(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (t *txReader) varInt() (uint64, error) {
+	b, err := t.read(1)
+	if err != nil {
+		return 0, err
+	}
+
+	switch b[0] {
+	case 0xfd:
+		v, err := t.read(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.LittleEndian.Uint16(v)), nil
+	case 0xfe:
+		v, err := t.read(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.LittleEndian.Uint32(v)), nil
+	case 0xff:
+		v, err := t.read(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.LittleEndian.Uint64(v), nil
+	}
+
+	return uint64(b[0]), nil
+}
+
+func (t *txReader) varBytes() ([]byte, error) {
+	n, err := t.varInt()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(t.r.Len()) {
+		return nil, ErrMalformedTx
+	}
+
+	return t.read(int(n))
+}
+
+// reverseHex encodes a hash in the byte order used by RPC and block explorers
+func reverseHex(b []byte) string {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+
+	return hex.EncodeToString(r)
+}
+
+// DecodeTx decodes a serialized transaction with or without witness data
+func DecodeTx(raw []byte) (*Tx, error) {
+	t := &txReader{r: bytes.NewReader(raw), keep: true}
+	tx := new(Tx)
+
+	if _, err := t.read(4); err != nil { // version
+		return nil, err
+	}
+
+	// segwit marker and flag are not part of the transaction id serialization
+	segwit := len(raw) > 6 && raw[4] == 0 && raw[5] != 0
+	if segwit {
+		t.keep = false
+		if _, err := t.read(2); err != nil {
+			return nil, err
+		}
+		t.keep = true
+	}
+
+	nIn, err := t.varInt()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < nIn; i++ {
+		hash, err := t.read(32)
+		if err != nil {
+			return nil, err
+		}
+		vout, err := t.uint32()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := t.varBytes(); err != nil { // script sig
+			return nil, err
+		}
+		if _, err := t.read(4); err != nil { // sequence
+			return nil, err
+		}
+		tx.Inputs = append(tx.Inputs, TxIn{PrevOut: OutPoint{TxID: reverseHex(hash), Vout: vout}})
+	}
+
+	nOut, err := t.varInt()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < nOut; i++ {
+		v, err := t.read(8)
+		if err != nil {
+			return nil, err
+		}
+		script, err := t.varBytes()
+		if err != nil {
+			return nil, err
+		}
+		tx.Outputs = append(tx.Outputs, TxOut{Value: int64(binary.LittleEndian.Uint64(v)), Script: script})
+	}
+
+	if segwit {
+		t.keep = false
+		for i := uint64(0); i < nIn; i++ {
+			n, err := t.varInt()
+			if err != nil {
+				return nil, err
+			}
+			for j := uint64(0); j < n; j++ {
+				if _, err := t.varBytes(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		t.keep = true
+	}
+
+	if _, err := t.read(4); err != nil { // lock time
+		return nil, err
+	}
+	if t.r.Len() != 0 {
+		return nil, ErrMalformedTx
+	}
+
+	h := sha256.Sum256(t.stripped.Bytes())
+	h = sha256.Sum256(h[:])
+	tx.TxID = reverseHex(h[:])
+	h = sha256.Sum256(raw)
+	h = sha256.Sum256(h[:])
+	tx.WTxID = reverseHex(h[:])
+
+	return tx, nil
+}
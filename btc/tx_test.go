@@ -0,0 +1,92 @@
+package btc
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+const (
+	// mainnet genesis coinbase
+	genesisTx   = "01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000"
+	genesisTxID = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+
+	// BIP-143 native P2WPKH example, one legacy and one witness input
+	segwitTx    = "01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635711000000"
+	segwitTxID  = "e8151a2af31c368a35053ddd4bdb285a8595c769a3ad83e0fa02314a602d4609"
+	segwitWTxID = "c36c38370907df2324d9ce9d149d191192f338b37665a82e78e76a12c909b762"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestDecodeTx(t *testing.T) {
+	tests := []struct {
+		name, raw, txid, wtxid string
+		inputs, outputs        int
+		coinbase               bool
+	}{
+		{"genesis", genesisTx, genesisTxID, genesisTxID, 1, 1, true},
+		{"segwit", segwitTx, segwitTxID, segwitWTxID, 2, 2, false},
+	}
+	for _, tt := range tests {
+		tx, err := DecodeTx(decodeHex(t, tt.raw))
+		if err != nil {
+			t.Errorf("%v: %v", tt.name, err)
+			continue
+		}
+		if tx.TxID != tt.txid {
+			t.Errorf("%v: txid %v, want %v", tt.name, tx.TxID, tt.txid)
+		}
+		if tx.WTxID != tt.wtxid {
+			t.Errorf("%v: wtxid %v, want %v", tt.name, tx.WTxID, tt.wtxid)
+		}
+		if len(tx.Inputs) != tt.inputs || len(tx.Outputs) != tt.outputs {
+			t.Errorf("%v: %v inputs, %v outputs", tt.name, len(tx.Inputs), len(tx.Outputs))
+		}
+		if tx.Coinbase() != tt.coinbase {
+			t.Errorf("%v: coinbase %v", tt.name, tx.Coinbase())
+		}
+	}
+
+	tx, err := DecodeTx(decodeHex(t, genesisTx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Outputs[0].Value != 50e8 {
+		t.Errorf("genesis output value %v", tx.Outputs[0].Value)
+	}
+
+	tx, err = DecodeTx(decodeHex(t, segwitTx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := OutPoint{TxID: "9f96ade4b41d5433f4eda31e1738ec2b36f6e7d1420d94a6af99801a88f7f7ff", Vout: 0}
+	if tx.Inputs[0].PrevOut != want {
+		t.Errorf("segwit input %+v, want %+v", tx.Inputs[0].PrevOut, want)
+	}
+	if tx.Outputs[1].Value != 223450000 {
+		t.Errorf("segwit output value %v", tx.Outputs[1].Value)
+	}
+}
+
+func TestDecodeTxMalformed(t *testing.T) {
+	raw := decodeHex(t, segwitTx)
+	for _, b := range [][]byte{
+		nil,
+		raw[:len(raw)-1],
+		append(append([]byte{}, raw...), 0),
+		raw[:40],
+	} {
+		if _, err := DecodeTx(b); err == nil {
+			t.Errorf("decoded %d bytes", len(b))
+		}
+	}
+}
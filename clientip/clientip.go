@@ -0,0 +1,100 @@
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Resolver finds the address of the client that sent a request. X-Forwarded-For is only
+// believed when the peer is a trusted proxy, and is read from the right so a client cannot
+// prepend addresses of its choice
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// NewResolver trusts proxies in the CIDR ranges, a single address is taken as a host range
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, s := range trustedProxies {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %v: %v", s, err)
+		}
+		r.trusted = append(r.trusted, n)
+	}
+
+	return r, nil
+}
+
+func (res *Resolver) isTrusted(ip net.IP) bool {
+	for _, n := range res.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Resolve returns the client address of a request, nil if it cannot be parsed
+func (res *Resolver) Resolve(r *http.Request) net.IP {
+	ip := peerIP(r)
+	if ip == nil || !res.isTrusted(ip) {
+		return ip
+	}
+
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// the chain is broken, the last trusted proxy is the best known client
+			return ip
+		}
+		ip = hop
+		if !res.isTrusted(hop) {
+			return hop
+		}
+	}
+
+	return ip
+}
+
+func peerIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return net.ParseIP(host)
+}
+
+type contextKey struct{}
+
+// ToContext instruments context with the resolved client address
+func ToContext(ctx context.Context, ip net.IP) context.Context {
+	return context.WithValue(ctx, contextKey{}, ip)
+}
+
+// FromRequest returns the address resolved for the request, or the peer address if
+// no resolver ran
+func FromRequest(r *http.Request) net.IP {
+	if ip, ok := r.Context().Value(contextKey{}).(net.IP); ok {
+		return ip
+	}
+
+	return peerIP(r)
+}
@@ -0,0 +1,61 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	res, err := NewResolver([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"direct", "203.0.113.7:1234", nil, "203.0.113.7"},
+		{"spoofed by an untrusted peer", "203.0.113.7:1234", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:1234", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"trusted proxy without header", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"single trusted address", "192.0.2.1:1234", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"next to a trusted address", "192.0.2.2:1234", []string{"203.0.113.7"}, "192.0.2.2"},
+		// a client prepends addresses of its choice, the right-most untrusted hop wins
+		{"chain of trusted proxies", "10.0.0.1:1234", []string{"198.51.100.1, 203.0.113.7, 10.0.0.2", "10.0.0.3"}, "203.0.113.7"},
+		{"all hops trusted", "10.0.0.1:1234", []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"broken hop", "10.0.0.1:1234", []string{"203.0.113.7, garbage, 10.0.0.2"}, "10.0.0.2"},
+		{"broken last hop", "10.0.0.1:1234", []string{"203.0.113.7, unknown"}, "10.0.0.1"},
+		{"ipv6 proxy", "[2001:db8::1]:1234", []string{"2001:db9::7"}, "2001:db9::7"},
+		{"ipv6 client", "[2001:db9::7]:1234", []string{"203.0.113.7"}, "2001:db9::7"},
+		{"unparsable peer", "pipe", []string{"203.0.113.7"}, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		for _, h := range tt.xff {
+			r.Header.Add("X-Forwarded-For", h)
+		}
+
+		got := res.Resolve(r)
+		if tt.want == "" {
+			if got != nil {
+				t.Errorf("%v: %v, want nil", tt.name, got)
+			}
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("%v: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewResolver(t *testing.T) {
+	for _, s := range []string{"10.0.0.0/33", "proxy.local", "10.0.0"} {
+		if _, err := NewResolver([]string{s}); err == nil {
+			t.Errorf("%v: accepted", s)
+		}
+	}
+}
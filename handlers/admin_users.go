@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"service_template/auth"
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+)
+
+var usernameRe = regexp.MustCompile(`^[A-Za-z0-9_.@\-]{1,64}$`)
+
+// Admin user create request
+// swagger:model AdminUserRequest
+type AdminUserRequest struct {
+	Username    string   `json:"username"`
+	Password    string   `json:"password"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// Admin user update request, disabling a user revokes its sessions
+// swagger:model AdminUserUpdateRequest
+type AdminUserUpdateRequest struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	Disabled    bool     `json:"disabled"`
+}
+
+// Admin password reset request
+// swagger:model AdminPasswordRequest
+type AdminPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// findAdminUser reads the `id` path variable and loads the user
+func findAdminUser(db *storage.Storage, w http.ResponseWriter, r *http.Request) (*models.AdminUser, bool) {
+	ctx := r.Context()
+
+	id, ok := parseID(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	user, err := db.GetAdminUser(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).WithField("m", "findAdminUser").Errorf("GetAdminUser error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return nil, false
+	}
+	if user == nil {
+		ERROR_AUTH_USER_NOT_FOUND(w, mux.Vars(r)["id"])
+
+		return nil, false
+	}
+
+	return user, true
+}
+
+// findManagedAdminUser loads the user like findAdminUser and checks that the caller holds
+// all of its permissions, so a user cannot take over an account more privileged than its own
+func findManagedAdminUser(db *storage.Storage, w http.ResponseWriter, r *http.Request) (*models.AdminUser, bool) {
+	user, ok := findAdminUser(db, w, r)
+	if !ok {
+		return nil, false
+	}
+	if !checkGrantable(r.Context(), db, w, user.Roles, user.Permissions) {
+		return nil, false
+	}
+
+	return user, true
+}
+
+// GetAdminUsers returns admin users sorted by username
+func GetAdminUsers(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetAdminUsers")
+	log.Debugf("GetAdminUsers:: ")
+
+	users, err := db.GetAdminUsers(ctx)
+	if err != nil {
+		log.Errorf("GetAdminUsers error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if users == nil {
+		users = []models.AdminUser{}
+	}
+
+	ReturnResult(ctx, w, users)
+}
+
+// PostAdminUser creates an admin user
+func PostAdminUser(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PostAdminUser")
+	log.Debugf("PostAdminUser:: ")
+
+	var req AdminUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if !usernameRe.MatchString(req.Username) {
+		ERROR_BAD_REQUEST(w, "invalid username: "+req.Username)
+
+		return
+	}
+	if err := auth.CheckPasswordPolicy(req.Password); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	sc, ok := validateScope(ctx, db, w, req.Roles, req.Permissions, nil)
+	if !ok {
+		return
+	}
+
+	existing, err := db.GetAdminUserByName(ctx, req.Username)
+	if err != nil {
+		log.Errorf("GetAdminUserByName error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if existing != nil {
+		ERROR_BAD_REQUEST(w, "user already exists: "+req.Username)
+
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		log.Errorf("HashPassword error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	user := &models.AdminUser{
+		Username:     req.Username,
+		PasswordHash: hash,
+		Roles:        sc.Roles,
+		Permissions:  sc.Permissions,
+	}
+	if err := db.CreateAdminUser(ctx, user); err != nil {
+		log.Errorf("CreateAdminUser error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	log.Infof("admin user %v created by %v", user.Username, auth.FromContext(ctx))
+
+	ReturnResultWithCode(ctx, w, http.StatusCreated, user)
+}
+
+// PutAdminUser replaces the roles and permissions of an admin user and enables or disables it
+func PutAdminUser(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PutAdminUser")
+	log.Debugf("PutAdminUser:: %v", mux.Vars(r))
+
+	user, ok := findManagedAdminUser(db, w, r)
+	if !ok {
+		return
+	}
+
+	var req AdminUserUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	sc, ok := validateScope(ctx, db, w, req.Roles, req.Permissions, nil)
+	if !ok {
+		return
+	}
+	user.Roles, user.Permissions, user.Disabled = sc.Roles, sc.Permissions, req.Disabled
+
+	if _, err := db.UpdateAdminUser(ctx, user); err != nil {
+		log.Errorf("UpdateAdminUser error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if user.Disabled {
+		if _, err := db.RevokeAdminSessions(ctx, user.ID, 0); err != nil {
+			log.Errorf("RevokeAdminSessions error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+	}
+	log.Infof("admin user %v updated by %v, disabled: %v", user.Username, auth.FromContext(ctx), user.Disabled)
+
+	ReturnResult(ctx, w, user)
+}
+
+// PostAdminUserUnlock lifts the lockout of an admin user
+func PostAdminUserUnlock(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PostAdminUserUnlock")
+	log.Debugf("PostAdminUserUnlock:: %v", mux.Vars(r))
+
+	user, ok := findManagedAdminUser(db, w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := db.UnlockAdminUser(ctx, user.ID); err != nil {
+		log.Errorf("UnlockAdminUser error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	log.Infof("admin user %v unlocked by %v", user.Username, auth.FromContext(ctx))
+
+	ReturnResult(ctx, w, user.ID)
+}
+
+// PutAdminUserPassword sets the password of an admin user and revokes all its sessions
+func PutAdminUserPassword(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PutAdminUserPassword")
+	log.Debugf("PutAdminUserPassword:: %v", mux.Vars(r))
+
+	user, ok := findManagedAdminUser(db, w, r)
+	if !ok {
+		return
+	}
+
+	var req AdminPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+	if err := auth.CheckPasswordPolicy(req.Password); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		log.Errorf("HashPassword error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if err := db.SetAdminUserPassword(ctx, user.ID, hash); err != nil {
+		log.Errorf("SetAdminUserPassword error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if _, err := db.RevokeAdminSessions(ctx, user.ID, 0); err != nil {
+		log.Errorf("RevokeAdminSessions error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	log.Infof("admin user %v password reset by %v", user.Username, auth.FromContext(ctx))
+
+	ReturnResult(ctx, w, user.ID)
+}
+
+// DeleteAdminUserSessions revokes all sessions of an admin user
+func DeleteAdminUserSessions(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "DeleteAdminUserSessions")
+	log.Debugf("DeleteAdminUserSessions:: %v", mux.Vars(r))
+
+	user, ok := findManagedAdminUser(db, w, r)
+	if !ok {
+		return
+	}
+
+	n, err := db.RevokeAdminSessions(ctx, user.ID, 0)
+	if err != nil {
+		log.Errorf("RevokeAdminSessions error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	log.Infof("%d sessions of admin user %v revoked by %v", n, user.Username, auth.FromContext(ctx))
+
+	ReturnResult(ctx, w, n)
+}
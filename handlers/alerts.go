@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"service_template/auth"
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+)
+
+var alertRuleKinds = map[string]bool{
+	models.AlertRuleTxAmount:        true,
+	models.AlertRulePeriodAmount:    true,
+	models.AlertRuleNewAddressBurst: true,
+}
+
+var alertSeverities = map[string]bool{
+	models.AlertSeverityInfo:     true,
+	models.AlertSeverityWarning:  true,
+	models.AlertSeverityCritical: true,
+}
+
+var alertStatuses = map[string]bool{
+	models.AlertOpen:         true,
+	models.AlertAcknowledged: true,
+	models.AlertResolved:     true,
+}
+
+// Alert rule create/update request, threshold is in whole units of the asset
+// swagger:model AlertRuleRequest
+type AlertRuleRequest struct {
+	Name            string `json:"name"`
+	Kind            string `json:"kind"`
+	AssetID         string `json:"asset_id"`
+	UserID          string `json:"user_id"`
+	Threshold       string `json:"threshold"`
+	Count           int    `json:"count"`
+	WindowSeconds   int64  `json:"window_seconds"`
+	IncludeInternal bool   `json:"include_internal"`
+	Severity        string `json:"severity"`
+	Enabled         *bool  `json:"enabled"`
+}
+
+// Alerts page
+// swagger:model AlertsResult
+type AlertsResult struct {
+	Total  int            `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+	Alerts []models.Alert `json:"alerts"`
+}
+
+// Alert acknowledgement request
+// swagger:model AlertAckRequest
+type AlertAckRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// parseID reads the numeric `id` path variable
+func parseID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, "invalid id: "+mux.Vars(r)["id"])
+
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+// decodeAlertRuleRequest reads and validates a rule
+func decodeAlertRuleRequest(ctx context.Context, db *storage.Storage, w http.ResponseWriter, r *http.Request) (*models.AlertRule, bool) {
+	var req AlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return nil, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		ERROR_BAD_REQUEST(w, "name is not set")
+
+		return nil, false
+	}
+	if !alertRuleKinds[req.Kind] {
+		ERROR_BAD_REQUEST(w, "invalid kind: "+req.Kind)
+
+		return nil, false
+	}
+	if req.Severity == "" {
+		req.Severity = models.AlertSeverityWarning
+	}
+	if !alertSeverities[req.Severity] {
+		ERROR_BAD_REQUEST(w, "invalid severity: "+req.Severity)
+
+		return nil, false
+	}
+	if req.WindowSeconds < 0 {
+		ERROR_BAD_REQUEST(w, "invalid window_seconds")
+
+		return nil, false
+	}
+	if req.Kind == models.AlertRuleNewAddressBurst && req.Count <= 0 {
+		ERROR_BAD_REQUEST(w, "count must be positive")
+
+		return nil, false
+	}
+
+	asset, ok := findAsset(ctx, db, w, req.AssetID)
+	if !ok {
+		return nil, false
+	}
+
+	threshold, err := models.ParseAmount(req.Threshold, asset.Decimals)
+	if err != nil || threshold.Sign() <= 0 {
+		ERROR_BAD_REQUEST(w, "invalid threshold: "+req.Threshold)
+
+		return nil, false
+	}
+
+	rule := &models.AlertRule{
+		Name:            req.Name,
+		Kind:            req.Kind,
+		AssetID:         asset.AssetID,
+		UserID:          strings.TrimSpace(req.UserID),
+		Threshold:       threshold,
+		Decimals:        asset.Decimals,
+		Count:           req.Count,
+		WindowSeconds:   req.WindowSeconds,
+		IncludeInternal: req.IncludeInternal,
+		Severity:        req.Severity,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+	}
+
+	return rule, true
+}
+
+// GetAlertRules returns all alert rules
+func GetAlertRules(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetAlertRules")
+	log.Debugf("GetAlertRules:: ")
+
+	rules, err := db.GetAlertRules(ctx, "")
+	if err != nil {
+		log.Errorf("GetAlertRules error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if rules == nil {
+		rules = []models.AlertRule{}
+	}
+
+	ReturnResult(ctx, w, rules)
+}
+
+// PostAlertRule adds an alert rule
+func PostAlertRule(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PostAlertRule")
+	log.Debugf("PostAlertRule:: ")
+
+	rule, ok := decodeAlertRuleRequest(ctx, db, w, r)
+	if !ok {
+		return
+	}
+
+	if err := db.CreateAlertRule(ctx, rule); err != nil {
+		log.Errorf("CreateAlertRule error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	ReturnResultWithCode(ctx, w, http.StatusCreated, rule)
+}
+
+// PutAlertRule replaces an alert rule, alerts it raised are kept
+func PutAlertRule(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PutAlertRule")
+	log.Debugf("PutAlertRule:: %v", mux.Vars(r))
+
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	rule, ok := decodeAlertRuleRequest(ctx, db, w, r)
+	if !ok {
+		return
+	}
+	rule.ID = id
+
+	found, err := db.UpdateAlertRule(ctx, rule)
+	if err != nil {
+		log.Errorf("UpdateAlertRule error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if !found {
+		ERROR_NOT_FOUND(w, mux.Vars(r)["id"])
+
+		return
+	}
+
+	updated, err := db.GetAlertRule(ctx, id)
+	if err != nil {
+		log.Errorf("GetAlertRule error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	ReturnResult(ctx, w, updated)
+}
+
+// DeleteAlertRule removes an alert rule
+func DeleteAlertRule(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "DeleteAlertRule")
+	log.Debugf("DeleteAlertRule:: %v", mux.Vars(r))
+
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	found, err := db.DeleteAlertRule(ctx, id)
+	if err != nil {
+		log.Errorf("DeleteAlertRule error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if !found {
+		ERROR_NOT_FOUND(w, mux.Vars(r)["id"])
+
+		return
+	}
+
+	ReturnResult(ctx, w, id)
+}
+
+// GetAlerts returns alerts sorted from newest to oldest.
+// Query: status, severity, user_id, asset_id, rule_id, from, to, limit, offset
+func GetAlerts(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetAlerts")
+	log.Debugf("GetAlerts:: %v", r.URL.RawQuery)
+
+	q := r.URL.Query()
+
+	tr, err := parseTimeRange(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	f := storage.AlertFilter{
+		Status:   q.Get("status"),
+		Severity: q.Get("severity"),
+		UserID:   q.Get("user_id"),
+		AssetID:  q.Get("asset_id"),
+		Range:    tr,
+	}
+	if f.Status != "" && !alertStatuses[f.Status] {
+		ERROR_BAD_REQUEST(w, "invalid status: "+f.Status)
+
+		return
+	}
+	if f.Severity != "" && !alertSeverities[f.Severity] {
+		ERROR_BAD_REQUEST(w, "invalid severity: "+f.Severity)
+
+		return
+	}
+	if s := q.Get("rule_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			ERROR_BAD_REQUEST(w, "invalid rule_id: "+s)
+
+			return
+		}
+		f.RuleID = uint(id)
+	}
+
+	alerts, total, err := db.GetAlerts(ctx, f, page)
+	if err != nil {
+		log.Errorf("GetAlerts error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if alerts == nil {
+		alerts = []models.Alert{}
+	}
+
+	ReturnResult(ctx, w, AlertsResult{
+		Total:  total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+		Alerts: alerts,
+	})
+}
+
+// PutAlert acknowledges or resolves an alert
+func PutAlert(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PutAlert")
+	log.Debugf("PutAlert:: %v", mux.Vars(r))
+
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	var req AlertAckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+	if !alertStatuses[req.Status] {
+		ERROR_BAD_REQUEST(w, "invalid status: "+req.Status)
+
+		return
+	}
+
+	alert, err := db.AcknowledgeAlert(ctx, id, req.Status, req.Note, auth.FromContext(ctx).String())
+	if err != nil {
+		log.Errorf("AcknowledgeAlert error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if alert == nil {
+		ERROR_NOT_FOUND(w, mux.Vars(r)["id"])
+
+		return
+	}
+
+	ReturnResult(ctx, w, alert)
+}
@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"service_template/auth"
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+)
+
+const (
+	defaultRotationOverlap = 24 * time.Hour
+	maxRotationOverlap     = 7 * 24 * time.Hour
+	// tokens generated before giving up on prefix collisions
+	maxTokenAttempts = 3
+)
+
+var apiClientKinds = map[string]bool{
+	models.APIClientAdmin:   true,
+	models.APIClientService: true,
+}
+
+// API client create request, a zero ttl_seconds creates a token that does not expire
+// swagger:model APIClientRequest
+type APIClientRequest struct {
+	Name        string   `json:"name"`
+	Kind        string   `json:"kind"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	AllowedIPs  []string `json:"allowed_ips"`
+	TTLSeconds  int64    `json:"ttl_seconds"`
+}
+
+// API client scope update request
+// swagger:model APIClientScopeRequest
+type APIClientScopeRequest struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	AllowedIPs  []string `json:"allowed_ips"`
+}
+
+// API client rotation request. The replaced token stays valid for overlap_seconds, 24h by default,
+// a positive ttl_seconds sets a new expiry
+// swagger:model APIClientRotateRequest
+type APIClientRotateRequest struct {
+	OverlapSeconds *int64 `json:"overlap_seconds"`
+	TTLSeconds     int64  `json:"ttl_seconds"`
+}
+
+// API client with its token, the token is only returned once
+// swagger:model APIClientTokenResult
+type APIClientTokenResult struct {
+	Client *models.APIClient `json:"client"`
+	Token  string            `json:"token"`
+}
+
+// parseCIDRs normalizes ranges, a single address is taken as a host range
+func parseCIDRs(ranges []string) ([]string, string) {
+	ret := []string{}
+	for _, s := range ranges {
+		s = strings.TrimSpace(s)
+		if ip := net.ParseIP(s); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			s = (&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}).String()
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, s
+		}
+		ret = append(ret, n.String())
+	}
+
+	return ret, ""
+}
+
+// scope is what a credential grants and where it is accepted from
+type scope struct {
+	Roles       []string
+	Permissions []string
+	AllowedIPs  []string
+}
+
+// validateScope normalizes a requested scope and checks that the caller may grant it:
+// every permission, directly or through a role, must be one the caller holds
+func validateScope(ctx context.Context, db *storage.Storage, w http.ResponseWriter, roles, permissions, allowedIPs []string) (*scope, bool) {
+	c := &scope{Roles: []string{}, Permissions: []string{}}
+	for _, role := range roles {
+		role = strings.TrimSpace(role)
+		if !roleRe.MatchString(role) {
+			ERROR_BAD_REQUEST(w, "invalid role: "+role)
+
+			return nil, false
+		}
+		c.Roles = append(c.Roles, role)
+	}
+	for _, p := range permissions {
+		p = strings.TrimSpace(p)
+		if !permissionRe.MatchString(p) {
+			ERROR_BAD_REQUEST(w, "invalid permission: "+p)
+
+			return nil, false
+		}
+		c.Permissions = append(c.Permissions, p)
+	}
+
+	ips, bad := parseCIDRs(allowedIPs)
+	if bad != "" {
+		ERROR_BAD_REQUEST(w, "invalid allowed_ips range: "+bad)
+
+		return nil, false
+	}
+	c.AllowedIPs = ips
+
+	if !checkGrantable(ctx, db, w, c.Roles, c.Permissions) {
+		return nil, false
+	}
+
+	return c, true
+}
+
+// findManagedAPIClient reads the `id` path variable, loads the client and checks that the
+// caller holds all of its permissions, so a caller cannot obtain or revoke a stronger token
+func findManagedAPIClient(db *storage.Storage, w http.ResponseWriter, r *http.Request) (*models.APIClient, bool) {
+	ctx := r.Context()
+
+	id, ok := parseID(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	client, err := db.GetAPIClient(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).WithField("m", "findManagedAPIClient").Errorf("GetAPIClient error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return nil, false
+	}
+	if client == nil {
+		ERROR_NOT_FOUND(w, mux.Vars(r)["id"])
+
+		return nil, false
+	}
+	if !checkGrantable(ctx, db, w, client.Roles, client.Permissions) {
+		return nil, false
+	}
+
+	return client, true
+}
+
+// GetAPIClients returns API clients sorted by name, revoked ones with `revoked=true`
+func GetAPIClients(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetAPIClients")
+	log.Debugf("GetAPIClients:: %v", r.URL.RawQuery)
+
+	clients, err := db.GetAPIClients(ctx, r.URL.Query().Get("revoked") == "true")
+	if err != nil {
+		log.Errorf("GetAPIClients error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if clients == nil {
+		clients = []models.APIClient{}
+	}
+
+	ReturnResult(ctx, w, clients)
+}
+
+// PostAPIClient creates an API client and returns its token
+func PostAPIClient(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PostAPIClient")
+	log.Debugf("PostAPIClient:: ")
+
+	var req APIClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 128 {
+		ERROR_BAD_REQUEST(w, "invalid name: "+req.Name)
+
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = models.APIClientService
+	}
+	if !apiClientKinds[req.Kind] {
+		ERROR_BAD_REQUEST(w, "invalid kind: "+req.Kind)
+
+		return
+	}
+	if req.TTLSeconds < 0 {
+		ERROR_BAD_REQUEST(w, "invalid ttl_seconds")
+
+		return
+	}
+
+	sc, ok := validateScope(ctx, db, w, req.Roles, req.Permissions, req.AllowedIPs)
+	if !ok {
+		return
+	}
+
+	existing, err := db.GetAPIClientByName(ctx, req.Name)
+	if err != nil {
+		log.Errorf("GetAPIClientByName error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if existing != nil {
+		ERROR_BAD_REQUEST(w, "client already exists: "+req.Name)
+
+		return
+	}
+
+	client := &models.APIClient{
+		Name:        req.Name,
+		Kind:        req.Kind,
+		Roles:       sc.Roles,
+		Permissions: sc.Permissions,
+		AllowedIPs:  sc.AllowedIPs,
+		CreatedBy:   auth.FromContext(ctx).String(),
+	}
+	if req.TTLSeconds > 0 {
+		expires := time.Now().Add(time.Duration(req.TTLSeconds) * time.Second)
+		client.ExpiresAt = &expires
+	}
+
+	var token string
+	for attempt := 1; ; attempt++ {
+		if token, client.TokenPrefix, client.TokenHash, err = auth.NewToken(); err != nil {
+			log.Errorf("NewToken error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		err = db.CreateAPIClient(ctx, client)
+		if err != storage.ErrTokenPrefixTaken || attempt == maxTokenAttempts {
+			break
+		}
+	}
+	// created concurrently under the same name
+	if err == storage.ErrAPIClientExists {
+		ERROR_BAD_REQUEST(w, "client already exists: "+req.Name)
+
+		return
+	}
+	if err != nil {
+		log.Errorf("CreateAPIClient error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	log.Infof("api client %v (%v) created by %v", client.Name, client.TokenPrefix, client.CreatedBy)
+
+	ReturnSecretResult(ctx, w, http.StatusCreated, APIClientTokenResult{Client: client, Token: token})
+}
+
+// PutAPIClient replaces the roles, permissions and allowed ranges of an API client
+func PutAPIClient(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PutAPIClient")
+	log.Debugf("PutAPIClient:: %v", mux.Vars(r))
+
+	target, ok := findManagedAPIClient(db, w, r)
+	if !ok {
+		return
+	}
+	id := target.ID
+
+	var req APIClientScopeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	sc, ok := validateScope(ctx, db, w, req.Roles, req.Permissions, req.AllowedIPs)
+	if !ok {
+		return
+	}
+
+	found, err := db.UpdateAPIClientScope(ctx, &models.APIClient{
+		DBModel:     models.DBModel{ID: id},
+		Roles:       sc.Roles,
+		Permissions: sc.Permissions,
+		AllowedIPs:  sc.AllowedIPs,
+	})
+	if err != nil {
+		log.Errorf("UpdateAPIClientScope error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if !found {
+		ERROR_NOT_FOUND(w, mux.Vars(r)["id"])
+
+		return
+	}
+
+	client, err := db.GetAPIClient(ctx, id)
+	if err != nil {
+		log.Errorf("GetAPIClient error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	log.Infof("api client %v scope set by %v", client.Name, auth.FromContext(ctx))
+
+	ReturnResult(ctx, w, client)
+}
+
+// RotateAPIClient issues a new token for an API client, the replaced token is accepted
+// during the overlap window
+func RotateAPIClient(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "RotateAPIClient")
+	log.Debugf("RotateAPIClient:: %v", mux.Vars(r))
+
+	target, ok := findManagedAPIClient(db, w, r)
+	if !ok {
+		return
+	}
+	id := target.ID
+
+	var req APIClientRotateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			ERROR_BAD_REQUEST(w, err.Error())
+
+			return
+		}
+	}
+
+	overlap := defaultRotationOverlap
+	if req.OverlapSeconds != nil {
+		overlap = time.Duration(*req.OverlapSeconds) * time.Second
+	}
+	if overlap < 0 || overlap > maxRotationOverlap {
+		ERROR_BAD_REQUEST(w, "invalid overlap_seconds")
+
+		return
+	}
+	if req.TTLSeconds < 0 {
+		ERROR_BAD_REQUEST(w, "invalid ttl_seconds")
+
+		return
+	}
+
+	now := time.Now()
+	var expires *time.Time
+	if req.TTLSeconds > 0 {
+		t := now.Add(time.Duration(req.TTLSeconds) * time.Second)
+		expires = &t
+	}
+
+	var token, prefix string
+	var found bool
+	var err error
+	for attempt := 1; ; attempt++ {
+		var hash string
+		if token, prefix, hash, err = auth.NewToken(); err != nil {
+			log.Errorf("NewToken error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		found, err = db.RotateAPIClient(ctx, id, prefix, hash, now.Add(overlap), expires)
+		if err != storage.ErrTokenPrefixTaken || attempt == maxTokenAttempts {
+			break
+		}
+	}
+	if err != nil {
+		log.Errorf("RotateAPIClient error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if !found {
+		ERROR_NOT_FOUND(w, mux.Vars(r)["id"])
+
+		return
+	}
+
+	client, err := db.GetAPIClient(ctx, id)
+	if err != nil {
+		log.Errorf("GetAPIClient error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	log.Infof("api client %v rotated to %v by %v, previous token valid for %v", client.Name, prefix, auth.FromContext(ctx), overlap)
+
+	ReturnSecretResult(ctx, w, http.StatusOK, APIClientTokenResult{Client: client, Token: token})
+}
+
+// DeleteAPIClient revokes the tokens of an API client, the client is kept for audit
+func DeleteAPIClient(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "DeleteAPIClient")
+	log.Debugf("DeleteAPIClient:: %v", mux.Vars(r))
+
+	target, ok := findManagedAPIClient(db, w, r)
+	if !ok {
+		return
+	}
+	id := target.ID
+
+	found, err := db.RevokeAPIClientByID(ctx, id)
+	if err != nil {
+		log.Errorf("RevokeAPIClientByID error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if !found {
+		ERROR_NOT_FOUND(w, mux.Vars(r)["id"])
+
+		return
+	}
+	log.Infof("api client %v revoked by %v", target.Name, auth.FromContext(ctx))
+
+	ReturnResult(ctx, w, id)
+}
@@ -0,0 +1,494 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"service_template/address"
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+)
+
+const maxAssetDecimals = 36
+
+var chainKinds = map[string]bool{
+	models.ChainKindBTC: true,
+	models.ChainKindETH: true,
+	models.ChainKindTRX: true,
+}
+
+// findAsset resolves an asset from the catalog, writing the error response if it cannot
+func findAsset(ctx context.Context, db *storage.Storage, w http.ResponseWriter, assetID string) (*models.Asset, bool) {
+	if assetID == "" {
+		ERROR_ASSET_NOT_SET(w)
+
+		return nil, false
+	}
+	if !validAssetID(assetID) {
+		ERROR_ASSET_INVALID(w, assetID)
+
+		return nil, false
+	}
+
+	asset, err := db.LookupAsset(ctx, assetID)
+	if err != nil {
+		logger.FromContext(ctx).WithField("m", "findAsset").Errorf("LookupAsset error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return nil, false
+	}
+	if asset == nil {
+		ERROR_ASSET_NOT_FOUND(w, assetID)
+
+		return nil, false
+	}
+
+	return asset, true
+}
+
+// supportedAsset resolves an asset that is enabled together with its chain
+func supportedAsset(ctx context.Context, db *storage.Storage, w http.ResponseWriter, assetID string) (*models.Asset, bool) {
+	asset, ok := findAsset(ctx, db, w, assetID)
+	if !ok {
+		return nil, false
+	}
+
+	chain, ok := findChain(ctx, db, w, asset.Chain)
+	if !ok {
+		return nil, false
+	}
+	if !asset.Enabled || !chain.Enabled {
+		ERROR_CURRENCY_NOT_SUPPORTED(w, assetID)
+
+		return nil, false
+	}
+
+	return asset, true
+}
+
+// findChain resolves a chain from the catalog, writing the error response if it cannot
+func findChain(ctx context.Context, db *storage.Storage, w http.ResponseWriter, name string) (*models.Chain, bool) {
+	if name == "" {
+		ERROR_CURRENCY_NOT_SET(w)
+
+		return nil, false
+	}
+
+	chain, err := db.LookupChain(ctx, name)
+	if err != nil {
+		logger.FromContext(ctx).WithField("m", "findChain").Errorf("LookupChain error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return nil, false
+	}
+	if chain == nil {
+		ERROR_CURRENCY_NOT_FOUND(w)
+
+		return nil, false
+	}
+
+	return chain, true
+}
+
+// normalizeAddress validates an address on the asset's chain, writing the error response if it is invalid
+func normalizeAddress(ctx context.Context, db *storage.Storage, w http.ResponseWriter, asset *models.Asset, addr string) (string, bool) {
+	chain, ok := findChain(ctx, db, w, asset.Chain)
+	if !ok {
+		return "", false
+	}
+
+	ret, err := address.Normalize(chain, addr)
+	switch address.ReasonOf(err) {
+	case "":
+		return ret, true
+	case address.ReasonEmpty:
+		ERROR_ADDRESS_NOT_SET(w)
+	case address.ReasonUnsupportedChain:
+		ERROR_CURRENCY_NOT_SUPPORTED(w, chain.Name)
+	default:
+		ERROR_ADDRESS_INVALID(w, string(address.ReasonOf(err)))
+	}
+
+	return "", false
+}
+
+// Chain create/update request
+// swagger:model ChainRequest
+type ChainRequest struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// Asset create/update request
+// swagger:model AssetRequest
+type AssetRequest struct {
+	AssetID         string `json:"asset_id"`
+	Symbol          string `json:"symbol"`
+	Chain           string `json:"chain"`
+	ContractAddress string `json:"contract_address"`
+	Decimals        int    `json:"decimals"`
+	Enabled         *bool  `json:"enabled"`
+}
+
+// GetSupportedAssets returns enabled assets of enabled chains
+func GetSupportedAssets(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetSupportedAssets")
+	log.Debugf("GetSupportedAssets:: ")
+
+	assets, err := db.GetAssets(ctx, true)
+	if err != nil {
+		log.Errorf("GetAssets error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	ReturnResult(ctx, w, assets)
+}
+
+// GetChains returns all chains of the catalog
+func GetChains(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetChains")
+	log.Debugf("GetChains:: ")
+
+	chains, err := db.GetChains(ctx)
+	if err != nil {
+		log.Errorf("GetChains error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	ReturnResult(ctx, w, chains)
+}
+
+func decodeChainRequest(w http.ResponseWriter, r *http.Request) (req ChainRequest, ok bool) {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return req, false
+	}
+
+	req.Kind = strings.ToLower(strings.TrimSpace(req.Kind))
+	if !chainKinds[req.Kind] {
+		ERROR_BAD_REQUEST(w, "invalid kind: "+req.Kind)
+
+		return req, false
+	}
+	if req.Enabled == nil {
+		enabled := true
+		req.Enabled = &enabled
+	}
+
+	return req, true
+}
+
+// PostChain adds a chain to the catalog
+func PostChain(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PostChain")
+	log.Debugf("PostChain:: ")
+
+	req, ok := decodeChainRequest(w, r)
+	if !ok {
+		return
+	}
+
+	req.Name = normalizeChain(req.Name)
+	if req.Name == "" {
+		ERROR_CURRENCY_NOT_SET(w)
+
+		return
+	}
+	if !validAssetID(req.Name) {
+		ERROR_BAD_REQUEST(w, "invalid name: "+req.Name)
+
+		return
+	}
+
+	existing, err := db.LookupChain(ctx, req.Name)
+	if err != nil {
+		log.Errorf("LookupChain error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if existing != nil {
+		ERROR_BAD_REQUEST(w, "chain already exists: "+req.Name)
+
+		return
+	}
+
+	chain := &models.Chain{Name: req.Name, Kind: req.Kind, Enabled: *req.Enabled}
+	if err := db.CreateChain(ctx, chain); err != nil {
+		log.Errorf("CreateChain error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	ReturnResultWithCode(ctx, w, http.StatusCreated, chain)
+}
+
+// PutChain updates kind and enabled flag of a chain
+func PutChain(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PutChain")
+	log.Debugf("PutChain:: ")
+
+	name := normalizeChain(mux.Vars(r)["chain"])
+	req, ok := decodeChainRequest(w, r)
+	if !ok {
+		return
+	}
+
+	found, err := db.UpdateChain(ctx, name, req.Kind, *req.Enabled)
+	if err != nil {
+		log.Errorf("UpdateChain error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if !found {
+		ERROR_CURRENCY_NOT_FOUND(w)
+
+		return
+	}
+
+	chain, ok := findChain(ctx, db, w, name)
+	if !ok {
+		return
+	}
+
+	ReturnResult(ctx, w, chain)
+}
+
+// DeleteChain removes a chain that has no assets
+func DeleteChain(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "DeleteChain")
+	log.Debugf("DeleteChain:: ")
+
+	name := normalizeChain(mux.Vars(r)["chain"])
+
+	n, err := db.CountChainAssets(ctx, name)
+	if err != nil {
+		log.Errorf("CountChainAssets error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if n > 0 {
+		ERROR_BAD_REQUEST(w, "chain has assets: "+name)
+
+		return
+	}
+
+	found, err := db.DeleteChain(ctx, name)
+	if err != nil {
+		log.Errorf("DeleteChain error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if !found {
+		ERROR_CURRENCY_NOT_FOUND(w)
+
+		return
+	}
+
+	ReturnResult(ctx, w, name)
+}
+
+// GetAssets returns all assets of the catalog including disabled ones
+func GetAssets(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetAssets")
+	log.Debugf("GetAssets:: ")
+
+	assets, err := db.GetAssets(ctx, false)
+	if err != nil {
+		log.Errorf("GetAssets error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	ReturnResult(ctx, w, assets)
+}
+
+// GetAsset returns an asset of the catalog
+func GetAsset(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetAsset")
+	log.Debugf("GetAsset:: ")
+
+	asset, ok := findAsset(ctx, db, w, mux.Vars(r)["asset_id"])
+	if !ok {
+		return
+	}
+
+	ReturnResult(ctx, w, asset)
+}
+
+// decodeAssetRequest reads and validates an asset, the asset id is taken from the path when set
+func decodeAssetRequest(ctx context.Context, db *storage.Storage, w http.ResponseWriter, r *http.Request) (*models.Asset, bool) {
+	var req AssetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return nil, false
+	}
+
+	if id, ok := mux.Vars(r)["asset_id"]; ok {
+		req.AssetID = id
+	}
+	req.AssetID = strings.TrimSpace(req.AssetID)
+	if req.AssetID == "" {
+		ERROR_ASSET_NOT_SET(w)
+
+		return nil, false
+	}
+	if !validAssetID(req.AssetID) {
+		ERROR_ASSET_INVALID(w, req.AssetID)
+
+		return nil, false
+	}
+
+	req.Symbol = strings.TrimSpace(req.Symbol)
+	if req.Symbol == "" {
+		ERROR_BAD_REQUEST(w, "symbol is not set")
+
+		return nil, false
+	}
+	if req.Decimals < 0 || req.Decimals > maxAssetDecimals {
+		ERROR_ASSET_INVALID(w, "invalid decimals")
+
+		return nil, false
+	}
+
+	chain, ok := findChain(ctx, db, w, normalizeChain(req.Chain))
+	if !ok {
+		return nil, false
+	}
+
+	req.ContractAddress = strings.TrimSpace(req.ContractAddress)
+	if req.ContractAddress != "" {
+		contract, err := address.NormalizeContract(chain, req.ContractAddress)
+		if err != nil {
+			ERROR_ADDRESS_INVALID(w, string(address.ReasonOf(err)))
+
+			return nil, false
+		}
+		req.ContractAddress = contract
+	}
+
+	asset := &models.Asset{
+		AssetID:         req.AssetID,
+		Symbol:          req.Symbol,
+		Chain:           chain.Name,
+		ContractAddress: req.ContractAddress,
+		Decimals:        req.Decimals,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+	}
+
+	return asset, true
+}
+
+// PostAsset adds an asset to the catalog
+func PostAsset(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PostAsset")
+	log.Debugf("PostAsset:: ")
+
+	asset, ok := decodeAssetRequest(ctx, db, w, r)
+	if !ok {
+		return
+	}
+
+	existing, err := db.LookupAsset(ctx, asset.AssetID)
+	if err != nil {
+		log.Errorf("LookupAsset error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if existing != nil {
+		ERROR_BAD_REQUEST(w, "asset already exists: "+asset.AssetID)
+
+		return
+	}
+
+	if err := db.CreateAsset(ctx, asset); err != nil {
+		log.Errorf("CreateAsset error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	ReturnResultWithCode(ctx, w, http.StatusCreated, asset)
+}
+
+// PutAsset updates an asset of the catalog
+func PutAsset(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PutAsset")
+	log.Debugf("PutAsset:: ")
+
+	asset, ok := decodeAssetRequest(ctx, db, w, r)
+	if !ok {
+		return
+	}
+
+	found, err := db.UpdateAsset(ctx, asset)
+	if err != nil {
+		log.Errorf("UpdateAsset error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if !found {
+		ERROR_ASSET_NOT_FOUND(w, asset.AssetID)
+
+		return
+	}
+
+	asset, ok = findAsset(ctx, db, w, asset.AssetID)
+	if !ok {
+		return
+	}
+
+	ReturnResult(ctx, w, asset)
+}
+
+// DeleteAsset removes an asset from the catalog
+func DeleteAsset(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "DeleteAsset")
+	log.Debugf("DeleteAsset:: ")
+
+	assetID := mux.Vars(r)["asset_id"]
+
+	found, err := db.DeleteAsset(ctx, assetID)
+	if err != nil {
+		log.Errorf("DeleteAsset error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if !found {
+		ERROR_ASSET_NOT_FOUND(w, assetID)
+
+		return
+	}
+
+	ReturnResult(ctx, w, assetID)
+}
@@ -57,6 +57,12 @@ func ERROR_ADDRESS_NOT_FOUND(w http.ResponseWriter, payload string) {
 	buildForeignError(w, http.StatusNotFound, "ERROR_ADDRESS_NOT_FOUND", payload)
 }
 
+// Address invalid
+// ERROR_ADDRESS_INVALID
+func ERROR_ADDRESS_INVALID(w http.ResponseWriter, payload string) {
+	buildForeignError(w, http.StatusBadRequest, "ERROR_ADDRESS_INVALID", payload)
+}
+
 // Bad request
 // ERROR_BAD_REQUEST
 func ERROR_BAD_REQUEST(w http.ResponseWriter, payload string) {
@@ -165,6 +171,24 @@ func ERROR_AUTH_SEED_NOT_FOUND(w http.ResponseWriter, pl string) {
 	buildForeignError(w, http.StatusForbidden, "ERROR_AUTH_SEED_NOT_FOUND", pl)
 }
 
+// Request body over the route limit
+// ERROR_REQUEST_TOO_LARGE
+func ERROR_REQUEST_TOO_LARGE(w http.ResponseWriter, pl string) {
+	buildForeignError(w, http.StatusRequestEntityTooLarge, "ERROR_REQUEST_TOO_LARGE", pl)
+}
+
+// Rate limit exceeded
+// ERROR_TOO_MANY_REQUESTS
+func ERROR_TOO_MANY_REQUESTS(w http.ResponseWriter, pl string) {
+	buildForeignError(w, http.StatusTooManyRequests, "ERROR_TOO_MANY_REQUESTS", pl)
+}
+
+// Service temporarily unavailable
+// ERROR_SERVICE_UNAVAILABLE
+func ERROR_SERVICE_UNAVAILABLE(w http.ResponseWriter, pl string) {
+	buildForeignError(w, http.StatusServiceUnavailable, "ERROR_SERVICE_UNAVAILABLE", pl)
+}
+
 // Internal server error
 // ERROR_INTERNAL_SERVER
 func ERROR_INTERNAL_SERVER(w http.ResponseWriter, pl string) {
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+	"service_template/valuation"
+)
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+
+	// rows written between flushes to the client
+	exportFlushRows = 100
+)
+
+// exporter writes rows of an export in a particular format
+type exporter interface {
+	ContentType() string
+	Header(columns []string) error
+	Row(record []string, v interface{}) error
+	Flush() error
+}
+
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func (e *csvExporter) ContentType() string { return "text/csv; charset=utf-8" }
+
+func (e *csvExporter) Header(columns []string) error { return e.w.Write(columns) }
+
+func (e *csvExporter) Row(record []string, _ interface{}) error { return e.w.Write(record) }
+
+func (e *csvExporter) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonExporter struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonExporter) ContentType() string { return "application/x-ndjson" }
+
+func (e *ndjsonExporter) Header([]string) error { return nil }
+
+func (e *ndjsonExporter) Row(_ []string, v interface{}) error { return e.enc.Encode(v) }
+
+func (e *ndjsonExporter) Flush() error { return nil }
+
+// exportFormat picks the format from the `format` parameter or the Accept header, ndjson by default
+func exportFormat(r *http.Request) (string, bool) {
+	switch f := strings.ToLower(r.URL.Query().Get("format")); f {
+	case exportFormatCSV, exportFormatNDJSON:
+		return f, true
+	case "":
+	default:
+		return f, false
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "text/csv") {
+		return exportFormatCSV, true
+	}
+
+	return exportFormatNDJSON, true
+}
+
+func newExporter(format string, w io.Writer) exporter {
+	if format == exportFormatCSV {
+		return &csvExporter{w: csv.NewWriter(w)}
+	}
+
+	return &ndjsonExporter{enc: json.NewEncoder(w)}
+}
+
+// exportStream writes rows produced by stream to the client, flushing every exportFlushRows rows.
+// The stream is cancelled through the request context when the client disconnects
+func exportStream(w http.ResponseWriter, r *http.Request, name string, columns []string,
+	stream func(row func(record []string, v interface{}) error) error) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "exportStream")
+
+	format, ok := exportFormat(r)
+	if !ok {
+		ERROR_BAD_REQUEST(w, "unsupported format: "+format)
+
+		return
+	}
+
+	e := newExporter(format, w)
+	flusher, _ := w.(http.Flusher)
+
+	w.Header().Set("Content-Type", e.ContentType())
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+name+"."+format+"\"")
+	w.WriteHeader(http.StatusOK)
+
+	flush := func() error {
+		if err := e.Flush(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		return nil
+	}
+
+	if err := e.Header(columns); err != nil {
+		log.Errorf("export header error: %v", err)
+
+		return
+	}
+
+	n := 0
+	err := stream(func(record []string, v interface{}) error {
+		if err := e.Row(record, v); err != nil {
+			return err
+		}
+		n++
+		if n%exportFlushRows == 0 {
+			return flush()
+		}
+
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Infof("export %v cancelled after %d rows: %v", name, n, ctx.Err())
+		} else {
+			log.Errorf("export %v error after %d rows: %v", name, n, err)
+		}
+
+		return
+	}
+
+	log.Infof("export %v finished, rows: %d", name, n)
+}
+
+// ExportWallets streams wallets as CSV or NDJSON.
+// Query: user_id, asset_id, wallet (requires asset_id), from, to, format (csv | ndjson)
+func ExportWallets(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "ExportWallets")
+	log.Debugf("ExportWallets:: %v", r.URL.RawQuery)
+
+	tr, err := parseTimeRange(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	f := storage.WalletFilter{
+		UserID:  r.URL.Query().Get("user_id"),
+		AssetID: r.URL.Query().Get("asset_id"),
+		Range:   tr,
+	}
+	if f.AssetID != "" {
+		asset, ok := findAsset(ctx, db, w, f.AssetID)
+		if !ok {
+			return
+		}
+		if s := r.URL.Query().Get("wallet"); s != "" {
+			if f.Address, ok = normalizeAddress(ctx, db, w, asset, s); !ok {
+				return
+			}
+		}
+	} else if r.URL.Query().Get("wallet") != "" {
+		ERROR_ASSET_NOT_SET(w)
+
+		return
+	}
+
+	columns := []string{"user_id", "asset_id", "wallet", "timestamp"}
+	exportStream(w, r, "wallets", columns, func(row func([]string, interface{}) error) error {
+		return db.StreamWallets(ctx, f, func(wl *models.Wallet) error {
+			return row([]string{
+				wl.UserID,
+				wl.AssetID,
+				wl.Address,
+				wl.Timestamp.UTC().Format(time.RFC3339),
+			}, wl)
+		})
+	})
+}
+
+// ExportOutgoingTransactions streams outgoing transactions as CSV or NDJSON.
+// Query: user_id, asset_id, chain, internal, from, to, format (csv | ndjson), fiat (adds fiat and fiat_amount)
+func ExportOutgoingTransactions(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "ExportOutgoingTransactions")
+	log.Debugf("ExportOutgoingTransactions:: %v", r.URL.RawQuery)
+
+	f, ok := parseTransactionFilter(db, w, r)
+	if !ok {
+		return
+	}
+
+	fiat, err := parseFiat(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	columns := []string{"chain", "user_id", "asset_id", "amount", "tx_id", "internal", "timestamp"}
+	var v *valuation.Valuer
+	if fiat != "" {
+		v = valuation.New(db, fiat)
+		columns = append(columns, "fiat", "fiat_amount")
+	}
+
+	exportStream(w, r, "outgoing_transactions", columns, func(row func([]string, interface{}) error) error {
+		return db.StreamOutgoingTransactions(ctx, f, func(tx *models.OutgoingTransaction) error {
+			record := []string{
+				tx.Chain,
+				tx.UserID,
+				tx.AssetID,
+				tx.Amount.String(),
+				tx.TxID,
+				strconv.FormatBool(tx.Internal),
+				tx.Timestamp.UTC().Format(time.RFC3339),
+			}
+			if v != nil {
+				if err := v.ValueTransaction(ctx, tx); err != nil {
+					return err
+				}
+				fiatAmount := ""
+				if tx.FiatAmount != nil {
+					fiatAmount = tx.FiatAmount.String()
+				}
+				record = append(record, tx.Fiat, fiatAmount)
+			}
+
+			return row(record, tx)
+		})
+	})
+}
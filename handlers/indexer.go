@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+)
+
+// GetIndexerStatusGenerator returns the indexer status handler. Checkpoints of all indexed chains
+// are listed, chains indexed by this process report their live progress from running
+func GetIndexerStatusGenerator(running func() []models.IndexerStatus) func(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	return func(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := logger.FromContext(ctx).WithField("m", "GetIndexerStatus")
+		log.Debugf("GetIndexerStatus:: ")
+
+		checkpoints, err := db.GetCheckpoints(ctx)
+		if err != nil {
+			log.Errorf("GetCheckpoints error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+
+		live := map[string]models.IndexerStatus{}
+		for _, s := range running() {
+			live[s.Chain] = s
+		}
+
+		ret := make([]models.IndexerStatus, 0, len(checkpoints)+len(live))
+		for _, cp := range checkpoints {
+			if s, ok := live[cp.Chain]; ok {
+				ret = append(ret, s)
+				delete(live, cp.Chain)
+				continue
+			}
+			ret = append(ret, models.IndexerStatus{
+				Chain:     cp.Chain,
+				Height:    cp.Height,
+				Hash:      cp.Hash,
+				UpdatedAt: cp.UpdatedAt,
+			})
+		}
+		// running indexers that have not stored a block yet
+		for _, s := range running() {
+			if _, ok := live[s.Chain]; ok {
+				ret = append(ret, s)
+			}
+		}
+
+		ReturnResult(ctx, w, ret)
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+)
+
+const maxInternalTransactionsBatch = 1000
+
+// Internal transaction submitted by the wallet
+// swagger:model InternalTransactionRequest
+type InternalTransactionRequest struct {
+	// Chain of the transaction, optional, must match the asset's chain if set
+	Chain   string `json:"chain"`
+	AssetID string `json:"asset_id"`
+	TxID    string `json:"tx_id"`
+	UserID  string `json:"user_id"`
+	// Amount sent in base units, optional
+	Amount string `json:"amount"`
+}
+
+// Batch of internal transactions
+// swagger:model InternalTransactionsRequest
+type InternalTransactionsRequest struct {
+	Transactions []InternalTransactionRequest `json:"transactions"`
+}
+
+// swagger:model InternalTransactionsResult
+type InternalTransactionsResult struct {
+	Received   int `json:"received"`
+	Inserted   int `json:"inserted"`
+	Duplicates int `json:"duplicates"`
+}
+
+func normalizeTxID(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func normalizeChain(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
+
+// PostInternalTransactions stores ids of transactions created by our wallet.
+// Body is either a single transaction or {"transactions": [...]}
+func PostInternalTransactions(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PostInternalTransactions")
+	log.Debugf("PostInternalTransactions:: ")
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	var batch InternalTransactionsRequest
+	if err := json.Unmarshal(raw, &batch); err != nil || batch.Transactions == nil {
+		var single InternalTransactionRequest
+		if err := json.Unmarshal(raw, &single); err != nil {
+			ERROR_BAD_REQUEST(w, err.Error())
+
+			return
+		}
+		batch.Transactions = []InternalTransactionRequest{single}
+	}
+
+	if len(batch.Transactions) == 0 {
+		ERROR_TRX_NOT_SET(w)
+
+		return
+	}
+	if len(batch.Transactions) > maxInternalTransactionsBatch {
+		ERROR_BAD_REQUEST(w, fmt.Sprintf("too many transactions, max %d", maxInternalTransactionsBatch))
+
+		return
+	}
+
+	txs := make([]models.InternalTransaction, 0, len(batch.Transactions))
+	seen := map[string]bool{}
+	for _, t := range batch.Transactions {
+		tx := models.InternalTransaction{
+			Chain:   normalizeChain(t.Chain),
+			AssetID: strings.TrimSpace(t.AssetID),
+			TxID:    normalizeTxID(t.TxID),
+			UserID:  strings.TrimSpace(t.UserID),
+		}
+		if tx.TxID == "" {
+			ERROR_TRX_NOT_SET(w)
+
+			return
+		}
+		asset, ok := supportedAsset(ctx, db, w, tx.AssetID)
+		if !ok {
+			return
+		}
+		if tx.Chain != "" && tx.Chain != asset.Chain {
+			ERROR_ASSET_INVALID(w, fmt.Sprintf("asset %v is not on chain %v", tx.AssetID, tx.Chain))
+
+			return
+		}
+		tx.Chain = asset.Chain
+
+		if amount := strings.TrimSpace(t.Amount); amount != "" {
+			v, err := models.ParseUnits(amount, asset.Decimals)
+			if err != nil || v.Sign() < 0 {
+				ERROR_BAD_REQUEST(w, "invalid amount: "+amount)
+
+				return
+			}
+			amount = v.Units().String()
+			tx.Amount = &amount
+		}
+
+		key := tx.Chain + ":" + tx.TxID
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		txs = append(txs, tx)
+	}
+
+	inserted, err := db.AddInternalTransactions(ctx, txs)
+	if err != nil {
+		log.Errorf("AddInternalTransactions error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	ReturnResult(ctx, w, InternalTransactionsResult{
+		Received:   len(batch.Transactions),
+		Inserted:   inserted,
+		Duplicates: len(batch.Transactions) - inserted,
+	})
+}
+
+// GetInternalTransaction looks up a registered transaction by chain and tx id
+func GetInternalTransaction(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetInternalTransaction")
+	log.Debugf("GetInternalTransaction:: ")
+
+	vars := mux.Vars(r)
+	chain, ok := findChain(ctx, db, w, normalizeChain(vars["chain"]))
+	if !ok {
+		return
+	}
+	txID := normalizeTxID(vars["tx_id"])
+	if txID == "" {
+		ERROR_TRX_NOT_SET(w)
+
+		return
+	}
+
+	tx, err := db.GetInternalTransaction(ctx, chain.Name, txID)
+	if err != nil {
+		log.Errorf("GetInternalTransaction error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if tx == nil {
+		ERROR_TRX_NOT_FOUND(w)
+
+		return
+	}
+
+	ReturnResult(ctx, w, tx)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"service_template/storage"
+	"service_template/valuation"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+var assetIDRe = regexp.MustCompile(`^[A-Za-z0-9_.\-]{1,32}$`)
+
+// parseTime accepts unix seconds or RFC3339
+func parseTime(s string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0).UTC(), nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
+
+// parseTimeRange reads optional `from` and `to` query parameters
+func parseTimeRange(r *http.Request) (tr storage.TimeRange, err error) {
+	q := r.URL.Query()
+
+	if s := q.Get("from"); s != "" {
+		if tr.From, err = parseTime(s); err != nil {
+			return tr, fmt.Errorf("invalid from: %v", s)
+		}
+	}
+	if s := q.Get("to"); s != "" {
+		if tr.To, err = parseTime(s); err != nil {
+			return tr, fmt.Errorf("invalid to: %v", s)
+		}
+	}
+	if !tr.From.IsZero() && !tr.To.IsZero() && tr.From.After(tr.To) {
+		return tr, fmt.Errorf("from is after to")
+	}
+
+	return tr, nil
+}
+
+// parsePage reads optional `limit` and `offset` query parameters
+func parsePage(r *http.Request) (p storage.Page, err error) {
+	q := r.URL.Query()
+
+	p.Limit = defaultPageLimit
+	if s := q.Get("limit"); s != "" {
+		if p.Limit, err = strconv.Atoi(s); err != nil || p.Limit <= 0 || p.Limit > maxPageLimit {
+			return p, fmt.Errorf("invalid limit: %v", s)
+		}
+	}
+	if s := q.Get("offset"); s != "" {
+		if p.Offset, err = strconv.Atoi(s); err != nil || p.Offset < 0 {
+			return p, fmt.Errorf("invalid offset: %v", s)
+		}
+	}
+
+	return p, nil
+}
+
+// validAssetID checks the asset id format
+func validAssetID(s string) bool {
+	return assetIDRe.MatchString(s)
+}
+
+// parseFiat reads the optional `fiat` query parameter, an ISO 4217 code
+func parseFiat(r *http.Request) (string, error) {
+	s := r.URL.Query().Get("fiat")
+	if s == "" {
+		return "", nil
+	}
+	fiat, ok := valuation.NormalizeFiat(s)
+	if !ok {
+		return "", fmt.Errorf("invalid fiat: %v", s)
+	}
+
+	return fiat, nil
+}
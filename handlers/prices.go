@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+	"service_template/valuation"
+)
+
+// MaxPricesBody is the largest accepted price import
+const MaxPricesBody = 32 << 20
+
+// Prices page
+// swagger:model PricesResult
+type PricesResult struct {
+	Total  int            `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+	Prices []models.Price `json:"prices"`
+}
+
+// Price import result
+// swagger:model PricesImportResult
+type PricesImportResult struct {
+	Imported int `json:"imported"`
+}
+
+// GetPrices returns the price history.
+// Query: asset_id, fiat, from, to, limit, offset
+func GetPrices(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetPrices")
+	log.Debugf("GetPrices:: %v", r.URL.RawQuery)
+
+	tr, err := parseTimeRange(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	fiat, err := parseFiat(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	f := storage.PriceFilter{
+		AssetID: r.URL.Query().Get("asset_id"),
+		Fiat:    fiat,
+		Range:   tr,
+	}
+	if f.AssetID != "" {
+		if _, ok := findAsset(ctx, db, w, f.AssetID); !ok {
+			return
+		}
+	}
+
+	prices, total, err := db.GetPrices(ctx, f, page)
+	if err != nil {
+		log.Errorf("GetPrices error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	ReturnResult(ctx, w, PricesResult{
+		Total:  total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+		Prices: prices,
+	})
+}
+
+// PostPrices imports prices from a CSV body with columns asset_id, fiat, timestamp, price.
+// Prices at existing timestamps are replaced, the whole file is rejected if any row is invalid
+func PostPrices(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PostPrices")
+	log.Debugf("PostPrices:: ")
+
+	prices, err := valuation.ParseCSV(http.MaxBytesReader(w, r.Body, MaxPricesBody))
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	checked := map[string]bool{}
+	for _, p := range prices {
+		if checked[p.AssetID] {
+			continue
+		}
+		if _, ok := findAsset(ctx, db, w, p.AssetID); !ok {
+			return
+		}
+		checked[p.AssetID] = true
+	}
+
+	n, err := db.ImportPrices(ctx, prices)
+	if err != nil {
+		log.Errorf("ImportPrices error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	log.Infof("imported %d prices", n)
+
+	ReturnResult(ctx, w, PricesImportResult{Imported: n})
+}
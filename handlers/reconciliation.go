@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"service_template/auth"
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+)
+
+var findingKinds = map[string]bool{
+	models.FindingMissingOnChain:    true,
+	models.FindingMissingInRegistry: true,
+	models.FindingAmountMismatch:    true,
+}
+
+var findingStatuses = map[string]bool{
+	models.FindingOpen:     true,
+	models.FindingResolved: true,
+	models.FindingIgnored:  true,
+}
+
+// Reconciliation findings page
+// swagger:model FindingsResult
+type FindingsResult struct {
+	Total    int                            `json:"total"`
+	Limit    int                            `json:"limit"`
+	Offset   int                            `json:"offset"`
+	Findings []models.ReconciliationFinding `json:"findings"`
+}
+
+// Finding review request
+// swagger:model FindingReviewRequest
+type FindingReviewRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// GetFindings returns reconciliation findings sorted from oldest to newest.
+// Query: kind, status, chain, from, to, limit, offset
+func GetFindings(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetFindings")
+	log.Debugf("GetFindings:: %v", r.URL.RawQuery)
+
+	q := r.URL.Query()
+
+	tr, err := parseTimeRange(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	f := storage.FindingFilter{
+		Kind:   q.Get("kind"),
+		Status: q.Get("status"),
+		Chain:  normalizeChain(q.Get("chain")),
+		Range:  tr,
+	}
+	if f.Kind != "" && !findingKinds[f.Kind] {
+		ERROR_BAD_REQUEST(w, "invalid kind: "+f.Kind)
+
+		return
+	}
+	if f.Status != "" && !findingStatuses[f.Status] {
+		ERROR_BAD_REQUEST(w, "invalid status: "+f.Status)
+
+		return
+	}
+
+	findings, total, err := db.GetFindings(ctx, f, page)
+	if err != nil {
+		log.Errorf("GetFindings error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if findings == nil {
+		findings = []models.ReconciliationFinding{}
+	}
+
+	ReturnResult(ctx, w, FindingsResult{
+		Total:    total,
+		Limit:    page.Limit,
+		Offset:   page.Offset,
+		Findings: findings,
+	})
+}
+
+// PutFinding marks a finding as reviewed
+func PutFinding(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PutFinding")
+	log.Debugf("PutFinding:: %v", mux.Vars(r))
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, "invalid id: "+mux.Vars(r)["id"])
+
+		return
+	}
+
+	var req FindingReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+	if !findingStatuses[req.Status] {
+		ERROR_BAD_REQUEST(w, "invalid status: "+req.Status)
+
+		return
+	}
+
+	finding, err := db.ReviewFinding(ctx, uint(id), req.Status, req.Note, auth.FromContext(ctx).String())
+	if err != nil {
+		log.Errorf("ReviewFinding error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if finding == nil {
+		ERROR_NOT_FOUND(w, mux.Vars(r)["id"])
+
+		return
+	}
+
+	ReturnResult(ctx, w, finding)
+}
+
+// GetFindingsSummary returns the number of findings by kind and status
+func GetFindingsSummary(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetFindingsSummary")
+	log.Debugf("GetFindingsSummary:: ")
+
+	summary, err := db.GetFindingsSummary(ctx)
+	if err != nil {
+		log.Errorf("GetFindingsSummary error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	ReturnResult(ctx, w, summary)
+}
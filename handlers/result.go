@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/ttm-keywallet/statserver/logger"
+	"service_template/logger"
 )
 
 // Response interface
@@ -15,32 +15,32 @@ type appJsonResult struct {
 	Result interface{} `json:"result"`
 }
 
+// ReturnResult writes a 200 result. Bodies are not logged here, the access log captures them
+// with sensitive fields redacted for routes that opt in
 func ReturnResult(ctx context.Context, w http.ResponseWriter, r interface{}) {
-	log := logger.FromContext(ctx).WithField("m", "ReturnResult")
-	log.Debugf("ReturnResult:: w: %v, r: %v", w, r)
-
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	respJson, err := json.Marshal(appJsonResult{r})
-	if err == nil {
-		log.Infof("Response:%v, %v", http.StatusOK, string(respJson))
-	} else {
-		log.Infof("Response error: %v", err)
-	}
-	json.NewEncoder(w).Encode(appJsonResult{r})
+	ReturnResultWithCode(ctx, w, http.StatusOK, r)
 }
 
+// ReturnResultWithCode writes a result with the status s
 func ReturnResultWithCode(ctx context.Context, w http.ResponseWriter, s int, r interface{}) {
 	log := logger.FromContext(ctx).WithField("m", "ReturnResultWithCode")
-	log.Debugf("ReturnResultWithCode:: w: %v, s: %v, r: %v", w, s, r)
+	log.Debugf("ReturnResultWithCode:: s: %v", s)
 
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(s)
-	respJson, err := json.Marshal(appJsonResult{r})
-	if err == nil {
-		log.Infof("Response: %v", s, string(respJson))
-	} else {
-		log.Infof("Response error: %v", err)
+	if err := json.NewEncoder(w).Encode(appJsonResult{r}); err != nil {
+		log.Errorf("Response error: %v", err)
 	}
+}
+
+// ReturnSecretResult writes a result holding credentials, the body is never logged
+func ReturnSecretResult(ctx context.Context, w http.ResponseWriter, s int, r interface{}) {
+	log := logger.FromContext(ctx).WithField("m", "ReturnSecretResult")
+	log.Debugf("ReturnSecretResult:: s: %v", s)
+
+	w.Header().Add("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(s)
+	log.Infof("Response: %v, <credentials omitted>", s)
 	json.NewEncoder(w).Encode(appJsonResult{r})
 }
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"service_template/auth"
+	"service_template/logger"
+	"service_template/storage"
+)
+
+var (
+	roleRe       = regexp.MustCompile(`^[a-z0-9_.\-]{1,64}$`)
+	permissionRe = regexp.MustCompile(`^(\*|[a-z0-9_]+(\.[a-z0-9_]+)*(\.\*)?)$`)
+)
+
+// Role permissions request
+// swagger:model RoleRequest
+type RoleRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// Effective permissions of the caller
+// swagger:model PermissionsResult
+type PermissionsResult struct {
+	ID          string   `json:"id"`
+	Kind        string   `json:"kind"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// checkGrantable checks that the caller holds every permission, directly or through a role.
+// It guards both granting permissions and changing a role, user or client that already holds them
+func checkGrantable(ctx context.Context, db *storage.Storage, w http.ResponseWriter, roles, permissions []string) bool {
+	log := logger.FromContext(ctx).WithField("m", "checkGrantable")
+
+	rolePerms, err := db.RolePermissions(ctx, roles)
+	if err != nil {
+		log.Errorf("RolePermissions error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return false
+	}
+	caller := auth.FromContext(ctx)
+	for _, p := range append(append([]string{}, permissions...), rolePerms...) {
+		if !caller.Can(p) {
+			ERROR_AUTH_NO_PERMISSION(w, p)
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetRoles returns roles with their permissions
+func GetRoles(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetRoles")
+	log.Debugf("GetRoles:: ")
+
+	roles, err := db.GetRoles(ctx)
+	if err != nil {
+		log.Errorf("GetRoles error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	ReturnResult(ctx, w, roles)
+}
+
+// PutRole replaces the permissions of a role, an empty list removes the role
+func PutRole(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PutRole")
+	log.Debugf("PutRole:: %v", mux.Vars(r))
+
+	role := mux.Vars(r)["role"]
+	if !roleRe.MatchString(role) {
+		ERROR_BAD_REQUEST(w, "invalid role: "+role)
+
+		return
+	}
+
+	var req RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+	for i, p := range req.Permissions {
+		req.Permissions[i] = strings.TrimSpace(p)
+		if !permissionRe.MatchString(req.Permissions[i]) {
+			ERROR_BAD_REQUEST(w, "invalid permission: "+p)
+
+			return
+		}
+	}
+	// a role cannot grant more than its editor holds, nor can an editor take away
+	// permissions of the role it does not hold itself
+	if !checkGrantable(ctx, db, w, []string{role}, req.Permissions) {
+		return
+	}
+
+	if err := db.SetRolePermissions(ctx, role, req.Permissions); err != nil {
+		log.Errorf("SetRolePermissions error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	log.Infof("role %v permissions set to %v by %v", role, req.Permissions, auth.FromContext(ctx))
+
+	ReturnResult(ctx, w, req)
+}
+
+// GetPermissions returns the caller with the permissions granted directly and through roles
+func GetPermissions(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetPermissions")
+	log.Debugf("GetPermissions:: ")
+
+	principal := auth.FromContext(ctx)
+	if principal == nil {
+		ERROR_AUTH_MISSING(w)
+
+		return
+	}
+
+	perms := append([]string{}, principal.Permissions...)
+	sort.Strings(perms)
+	roles := append([]string{}, principal.Roles...)
+
+	ReturnResult(ctx, w, PermissionsResult{
+		ID:          principal.ID,
+		Kind:        principal.Kind,
+		Roles:       roles,
+		Permissions: perms,
+	})
+}
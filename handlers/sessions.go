@@ -0,0 +1,485 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"service_template/auth"
+	"service_template/clientip"
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+)
+
+// SessionConfig sets token lifetimes and the lockout of admin user logins
+type SessionConfig struct {
+	// lifetime of an access token, a refresh issues a new one
+	AccessTTL time.Duration
+	// how long a session can stay unrefreshed
+	RefreshTTL time.Duration
+	// lifetime of a session however often it is refreshed
+	MaxLifetime time.Duration
+	// consecutive failed logins that lock an account for Lockout
+	MaxFailures int
+	Lockout     time.Duration
+}
+
+// Login request
+// swagger:model LoginRequest
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Refresh request
+// swagger:model RefreshRequest
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Password change request
+// swagger:model PasswordChangeRequest
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// Session tokens, only returned by login and refresh
+// swagger:model SessionResult
+type SessionResult struct {
+	AccessToken      string    `json:"access_token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// Sessions of the caller
+// swagger:model SessionsResult
+type SessionsResult struct {
+	Current  uint                  `json:"current"`
+	Sessions []models.AdminSession `json:"sessions"`
+}
+
+// issueTokens sets new access and refresh tokens of a session and returns them
+func (c SessionConfig) issueTokens(s *models.AdminSession, now time.Time) (*SessionResult, error) {
+	access, accessPrefix, accessHash, err := auth.NewSchemeToken(auth.SessionTokenScheme)
+	if err != nil {
+		return nil, err
+	}
+	refresh, refreshPrefix, refreshHash, err := auth.NewSchemeToken(auth.RefreshTokenScheme)
+	if err != nil {
+		return nil, err
+	}
+
+	s.TokenPrefix, s.TokenHash = accessPrefix, accessHash
+	s.RefreshPrefix, s.RefreshHash = refreshPrefix, refreshHash
+	s.RefreshExpiresAt = now.Add(c.RefreshTTL)
+	if s.RefreshExpiresAt.After(s.MaxExpiresAt) {
+		s.RefreshExpiresAt = s.MaxExpiresAt
+	}
+	s.ExpiresAt = now.Add(c.AccessTTL)
+	if s.ExpiresAt.After(s.RefreshExpiresAt) {
+		s.ExpiresAt = s.RefreshExpiresAt
+	}
+
+	return &SessionResult{
+		AccessToken:      access,
+		ExpiresAt:        s.ExpiresAt,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: s.RefreshExpiresAt,
+	}, nil
+}
+
+// sessionPrincipal returns the caller if it is logged in with a session
+func sessionPrincipal(w http.ResponseWriter, r *http.Request) (*auth.Principal, bool) {
+	p := auth.FromContext(r.Context())
+	if p == nil || p.SessionID == 0 {
+		ERROR_AUTH_FORBIDDEN(w, "session login required")
+
+		return nil, false
+	}
+
+	return p, true
+}
+
+func requestIP(r *http.Request) string {
+	if ip := clientip.FromRequest(r); ip != nil {
+		return ip.String()
+	}
+
+	return ""
+}
+
+// PostLoginGenerator returns the handler that logs an admin user in with a password.
+// Unknown users, locked accounts and wrong passwords are answered alike
+func PostLoginGenerator(cfg SessionConfig) func(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	return func(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := logger.FromContext(ctx).WithField("m", "PostLogin")
+		log.Debugf("PostLogin:: ")
+
+		var req LoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			ERROR_BAD_REQUEST(w, err.Error())
+
+			return
+		}
+		req.Username = strings.TrimSpace(req.Username)
+		if req.Username == "" || req.Password == "" || len(req.Password) > auth.MaxPasswordLen {
+			ERROR_AUTH_BAD_PASSWORD(w, "")
+
+			return
+		}
+
+		user, err := db.GetAdminUserByName(ctx, req.Username)
+		if err != nil {
+			log.Errorf("GetAdminUserByName error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		if user == nil {
+			auth.VerifyDummyPassword(req.Password)
+			log.Infof("login of unknown user %v from %v", req.Username, requestIP(r))
+			ERROR_AUTH_BAD_PASSWORD(w, "")
+
+			return
+		}
+
+		now := time.Now()
+		if user.Locked(now) {
+			auth.VerifyDummyPassword(req.Password)
+			log.Infof("login of locked user %v from %v", user.Username, requestIP(r))
+			ERROR_AUTH_BAD_PASSWORD(w, "")
+
+			return
+		}
+
+		ok, rehash, err := auth.VerifyPassword(req.Password, user.PasswordHash)
+		if err != nil {
+			log.Errorf("VerifyPassword error for user %v: %v", user.Username, err)
+		}
+		if !ok {
+			locked, err := db.RecordLoginFailure(ctx, user.ID, cfg.MaxFailures, now.Add(cfg.Lockout))
+			if err != nil {
+				log.Errorf("RecordLoginFailure error: %v", err)
+			}
+			if locked {
+				log.Warnf("user %v locked for %v after %d failed logins", user.Username, cfg.Lockout, cfg.MaxFailures)
+			}
+			log.Infof("failed login of user %v from %v", user.Username, requestIP(r))
+			ERROR_AUTH_BAD_PASSWORD(w, "")
+
+			return
+		}
+		if user.Disabled {
+			log.Infof("login of disabled user %v from %v", user.Username, requestIP(r))
+			ERROR_AUTH_FORBIDDEN(w, "account disabled")
+
+			return
+		}
+
+		newHash := ""
+		if rehash {
+			if newHash, err = auth.HashPassword(req.Password); err != nil {
+				log.Errorf("HashPassword error: %v", err)
+				newHash = ""
+			}
+		}
+		if err := db.RecordLoginSuccess(ctx, user.ID, newHash); err != nil {
+			log.Errorf("RecordLoginSuccess error: %v", err)
+		}
+
+		session := &models.AdminSession{
+			UserID:       user.ID,
+			MaxExpiresAt: now.Add(cfg.MaxLifetime),
+			IP:           requestIP(r),
+			UserAgent:    truncate(r.UserAgent(), 255),
+		}
+		result, err := cfg.issueTokens(session, now)
+		if err != nil {
+			log.Errorf("issueTokens error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		if err := db.CreateAdminSession(ctx, session); err != nil {
+			log.Errorf("CreateAdminSession error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		log.Infof("user %v logged in from %v, session %v", user.Username, session.IP, session.ID)
+
+		ReturnSecretResult(ctx, w, http.StatusOK, result)
+	}
+}
+
+// PostRefreshGenerator returns the handler that exchanges a refresh token for new session tokens,
+// a refresh token can be used once
+func PostRefreshGenerator(cfg SessionConfig) func(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	return func(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := logger.FromContext(ctx).WithField("m", "PostRefresh")
+		log.Debugf("PostRefresh:: ")
+
+		var req RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			ERROR_BAD_REQUEST(w, err.Error())
+
+			return
+		}
+		prefix, ok := auth.SchemeTokenPrefix(auth.RefreshTokenScheme, req.RefreshToken)
+		if !ok {
+			ERROR_AUTH_CANNOT_PARSE_TOKEN(w)
+
+			return
+		}
+
+		session, err := db.GetAdminSessionByRefreshPrefix(ctx, prefix)
+		if err != nil {
+			log.Errorf("GetAdminSessionByRefreshPrefix error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		hash := ""
+		if session != nil {
+			hash = session.RefreshHash
+		}
+		now := time.Now()
+		if !auth.VerifyToken(req.RefreshToken, hash) || session == nil || session.RevokedAt != nil ||
+			!now.Before(session.RefreshExpiresAt) {
+			ERROR_AUTH_TOKEN_INVALID(w)
+
+			return
+		}
+
+		user, err := db.GetAdminUser(ctx, session.UserID)
+		if err != nil {
+			log.Errorf("GetAdminUser error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		if user == nil || user.Disabled {
+			ERROR_AUTH_TOKEN_INVALID(w)
+
+			return
+		}
+
+		result, err := cfg.issueTokens(session, now)
+		if err != nil {
+			log.Errorf("issueTokens error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		refreshed, err := db.RefreshAdminSession(ctx, session, prefix)
+		if err != nil {
+			log.Errorf("RefreshAdminSession error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		if !refreshed {
+			// refreshed concurrently with the same token
+			ERROR_AUTH_TOKEN_INVALID(w)
+
+			return
+		}
+
+		ReturnSecretResult(ctx, w, http.StatusOK, result)
+	}
+}
+
+// PostLogout revokes the session of the caller
+func PostLogout(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PostLogout")
+	log.Debugf("PostLogout:: ")
+
+	p, ok := sessionPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := db.RevokeAdminSession(ctx, p.SessionID); err != nil {
+		log.Errorf("RevokeAdminSession error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	log.Infof("user %v logged out, session %v", p.ID, p.SessionID)
+
+	ReturnResult(ctx, w, p.SessionID)
+}
+
+// PostLogoutAll revokes all sessions of the caller, including the current one
+func PostLogoutAll(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "PostLogoutAll")
+	log.Debugf("PostLogoutAll:: ")
+
+	p, ok := sessionPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := db.GetAdminUserByName(ctx, p.ID)
+	if err != nil || user == nil {
+		log.Errorf("GetAdminUserByName error: %v, found: %v", err, user != nil)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	n, err := db.RevokeAdminSessions(ctx, user.ID, 0)
+	if err != nil {
+		log.Errorf("RevokeAdminSessions error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	log.Infof("user %v logged out of %d sessions", p.ID, n)
+
+	ReturnResult(ctx, w, n)
+}
+
+// GetSessions returns the active sessions of the caller
+func GetSessions(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetSessions")
+	log.Debugf("GetSessions:: ")
+
+	p, ok := sessionPrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := db.GetAdminUserByName(ctx, p.ID)
+	if err != nil || user == nil {
+		log.Errorf("GetAdminUserByName error: %v, found: %v", err, user != nil)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	sessions, err := db.GetAdminSessions(ctx, user.ID)
+	if err != nil {
+		log.Errorf("GetAdminSessions error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if sessions == nil {
+		sessions = []models.AdminSession{}
+	}
+
+	ReturnResult(ctx, w, SessionsResult{Current: p.SessionID, Sessions: sessions})
+}
+
+// PutPasswordGenerator returns the handler that changes the password of the caller and revokes
+// its other sessions. Wrong current passwords count towards the lockout like failed logins
+func PutPasswordGenerator(cfg SessionConfig) func(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	return func(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := logger.FromContext(ctx).WithField("m", "PutPassword")
+		log.Debugf("PutPassword:: ")
+
+		p, ok := sessionPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		var req PasswordChangeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			ERROR_BAD_REQUEST(w, err.Error())
+
+			return
+		}
+		if err := auth.CheckPasswordPolicy(req.NewPassword); err != nil {
+			ERROR_BAD_REQUEST(w, err.Error())
+
+			return
+		}
+		if req.NewPassword == req.CurrentPassword {
+			ERROR_BAD_REQUEST(w, "new password must differ from the current one")
+
+			return
+		}
+
+		user, err := db.GetAdminUserByName(ctx, p.ID)
+		if err != nil || user == nil {
+			log.Errorf("GetAdminUserByName error: %v, found: %v", err, user != nil)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		if len(req.CurrentPassword) > auth.MaxPasswordLen {
+			ERROR_AUTH_BAD_PASSWORD(w, "")
+
+			return
+		}
+		now := time.Now()
+		if user.Locked(now) {
+			auth.VerifyDummyPassword(req.CurrentPassword)
+			log.Infof("password change of locked user %v", user.Username)
+			ERROR_AUTH_BAD_PASSWORD(w, "")
+
+			return
+		}
+		if ok, _, _ := auth.VerifyPassword(req.CurrentPassword, user.PasswordHash); !ok {
+			locked, err := db.RecordLoginFailure(ctx, user.ID, cfg.MaxFailures, now.Add(cfg.Lockout))
+			if err != nil {
+				log.Errorf("RecordLoginFailure error: %v", err)
+			}
+			if locked {
+				log.Warnf("user %v locked for %v after %d failed password checks", user.Username, cfg.Lockout, cfg.MaxFailures)
+			}
+			log.Infof("password change of user %v with a wrong current password", user.Username)
+			ERROR_AUTH_BAD_PASSWORD(w, "")
+
+			return
+		}
+
+		hash, err := auth.HashPassword(req.NewPassword)
+		if err != nil {
+			log.Errorf("HashPassword error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		if err := db.SetAdminUserPassword(ctx, user.ID, hash); err != nil {
+			log.Errorf("SetAdminUserPassword error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		n, err := db.RevokeAdminSessions(ctx, user.ID, p.SessionID)
+		if err != nil {
+			log.Errorf("RevokeAdminSessions error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		log.Infof("user %v changed password, %d other sessions revoked", user.Username, n)
+
+		ReturnResult(ctx, w, user.ID)
+	}
+}
+
+// truncate cuts s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
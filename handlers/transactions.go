@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+	"service_template/valuation"
+)
+
+// Outgoing transactions page
+// swagger:model OutgoingTransactionsResult
+type OutgoingTransactionsResult struct {
+	Total        int                          `json:"total"`
+	Limit        int                          `json:"limit"`
+	Offset       int                          `json:"offset"`
+	Transactions []models.OutgoingTransaction `json:"transactions"`
+}
+
+// parseTransactionFilter reads query parameters shared by transaction endpoints
+func parseTransactionFilter(db *storage.Storage, w http.ResponseWriter, r *http.Request) (f storage.TransactionFilter, ok bool) {
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	tr, err := parseTimeRange(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return f, false
+	}
+
+	f = storage.TransactionFilter{
+		UserID:  q.Get("user_id"),
+		AssetID: q.Get("asset_id"),
+		Chain:   normalizeChain(q.Get("chain")),
+		Range:   tr,
+	}
+	if f.AssetID != "" {
+		if _, ok := findAsset(ctx, db, w, f.AssetID); !ok {
+			return f, false
+		}
+	}
+	if f.Chain != "" {
+		if _, ok := findChain(ctx, db, w, f.Chain); !ok {
+			return f, false
+		}
+	}
+
+	if s := q.Get("internal"); s != "" {
+		internal, err := strconv.ParseBool(s)
+		if err != nil {
+			ERROR_BAD_REQUEST(w, "invalid internal: "+s)
+
+			return f, false
+		}
+		f.Internal = &internal
+	}
+
+	return f, true
+}
+
+// GetOutgoingTransactions returns outgoing transactions of BTC, ETH-like and TRX chains
+// sorted by timestamp from oldest to newest.
+// Query: user_id, asset_id, chain, internal, from, to (unix seconds or RFC3339), limit, offset,
+// fiat (ISO 4217 code, adds amounts valued at the nearest earlier price)
+func GetOutgoingTransactions(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetOutgoingTransactions")
+	log.Debugf("GetOutgoingTransactions:: %v", r.URL.RawQuery)
+
+	f, ok := parseTransactionFilter(db, w, r)
+	if !ok {
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	fiat, err := parseFiat(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	txs, total, err := db.GetOutgoingTransactions(ctx, f, page)
+	if err != nil {
+		log.Errorf("GetOutgoingTransactions error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	if fiat != "" {
+		v := valuation.New(db, fiat)
+		for i := range txs {
+			if err := v.ValueTransaction(ctx, &txs[i]); err != nil {
+				log.Errorf("ValueTransaction error: %v", err)
+				ERROR_INTERNAL_SERVER(w, "")
+
+				return
+			}
+		}
+	}
+
+	ReturnResult(ctx, w, OutgoingTransactionsResult{
+		Total:        total,
+		Limit:        page.Limit,
+		Offset:       page.Offset,
+		Transactions: txs,
+	})
+}
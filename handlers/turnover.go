@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"service_template/logger"
+	"service_template/storage"
+)
+
+var turnoverGranularities = map[string]bool{
+	storage.GranularityHour:  true,
+	storage.GranularityDay:   true,
+	storage.GranularityWeek:  true,
+	storage.GranularityMonth: true,
+}
+
+var turnoverDimensions = map[string]string{
+	"chain": storage.DimensionChain,
+	"user":  storage.DimensionUser,
+}
+
+// GetTurnover returns outgoing volume, counts and internal/external split per asset and period.
+// Query: granularity (hour | day | week | month), tz (IANA name), group_by (chain,user),
+// asset_id, user_id, chain, from, to, fiat (ISO 4217 code, periods with an unpriced hour get no fiat amounts).
+// Rollups are hourly in UTC, so timezones with non-whole-hour offsets are approximated, and fiat
+// amounts value each hour at the last price at or before its start rather than at each transaction's
+// price, the transaction list and export carry exact per-transaction values
+func GetTurnover(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetTurnover")
+	log.Debugf("GetTurnover:: %v", r.URL.RawQuery)
+
+	q := r.URL.Query()
+
+	tr, err := parseTimeRange(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	tq := storage.TurnoverQuery{
+		Granularity: storage.GranularityDay,
+		Location:    time.UTC,
+		AssetID:     q.Get("asset_id"),
+		UserID:      q.Get("user_id"),
+		Chain:       normalizeChain(q.Get("chain")),
+		Range:       tr,
+	}
+
+	if tq.Fiat, err = parseFiat(r); err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	if s := q.Get("granularity"); s != "" {
+		if !turnoverGranularities[s] {
+			ERROR_BAD_REQUEST(w, "invalid granularity: "+s)
+
+			return
+		}
+		tq.Granularity = s
+	}
+
+	if s := q.Get("tz"); s != "" {
+		if tq.Location, err = time.LoadLocation(s); err != nil || s == "Local" {
+			ERROR_BAD_REQUEST(w, "invalid tz: "+s)
+
+			return
+		}
+	}
+
+	if s := q.Get("group_by"); s != "" {
+		for _, d := range strings.Split(s, ",") {
+			dim, ok := turnoverDimensions[strings.TrimSpace(d)]
+			if !ok {
+				ERROR_BAD_REQUEST(w, "invalid group_by: "+d)
+
+				return
+			}
+			tq.GroupBy = append(tq.GroupBy, dim)
+		}
+	}
+
+	if tq.AssetID != "" {
+		if _, ok := findAsset(ctx, db, w, tq.AssetID); !ok {
+			return
+		}
+	}
+	if tq.Chain != "" {
+		if _, ok := findChain(ctx, db, w, tq.Chain); !ok {
+			return
+		}
+	}
+
+	ret, err := db.GetTurnover(ctx, tq)
+	if err != nil {
+		log.Errorf("GetTurnover error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+
+	ReturnResult(ctx, w, ret)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"service_template/auth"
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+)
+
+const actionWalletRemove = "wallet.remove"
+
+// Wallet removal result
+// swagger:model WalletRemovalResult
+type WalletRemovalResult struct {
+	DryRun bool `json:"dry_run"`
+	*storage.WalletRemoval
+}
+
+// DeleteWalletGenerator returns the wallet removal handler, removal is forbidden unless allow is set.
+// DELETE /api/v1/wallets/{id}?dry_run=true lists what would be removed without removing it
+func DeleteWalletGenerator(allow bool) func(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	return func(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := logger.FromContext(ctx).WithField("m", "DeleteWallet")
+		log.Debugf("DeleteWallet:: %v", mux.Vars(r))
+
+		if !allow {
+			ERROR_AUTH_FORBIDDEN(w, "wallet removal is disabled")
+
+			return
+		}
+
+		principal := auth.FromContext(ctx)
+		if !principal.Can(auth.PermissionWalletsRemove) {
+			ERROR_AUTH_NO_PERMISSION(w, auth.PermissionWalletsRemove)
+
+			return
+		}
+
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			ERROR_BAD_REQUEST(w, "invalid id: "+mux.Vars(r)["id"])
+
+			return
+		}
+
+		dryRun := false
+		if s := r.URL.Query().Get("dry_run"); s != "" {
+			if dryRun, err = strconv.ParseBool(s); err != nil {
+				ERROR_BAD_REQUEST(w, "invalid dry_run: "+s)
+
+				return
+			}
+		}
+
+		audit := func(plan *storage.WalletRemoval) (*models.AuditEntry, error) {
+			return storage.NewAuditEntry(principal.String(), actionWalletRemove,
+				"wallet:"+strconv.FormatUint(id, 10), dryRun, plan)
+		}
+
+		var plan *storage.WalletRemoval
+		if dryRun {
+			plan, err = db.PlanWalletRemoval(ctx, uint(id))
+		} else {
+			plan, err = db.RemoveWallet(ctx, uint(id), audit)
+		}
+		if err != nil {
+			log.Errorf("wallet removal error: %v", err)
+			ERROR_INTERNAL_SERVER(w, "")
+
+			return
+		}
+		if plan == nil {
+			ERROR_NOT_FOUND(w, mux.Vars(r)["id"])
+
+			return
+		}
+
+		if dryRun {
+			entry, err := audit(plan)
+			if err == nil {
+				err = db.AddAuditEntry(ctx, entry)
+			}
+			if err != nil {
+				log.Errorf("AddAuditEntry error: %v", err)
+				ERROR_INTERNAL_SERVER(w, "")
+
+				return
+			}
+		}
+
+		log.Infof("wallet %v removed by %v, dry run: %v, transactions: %d", id, principal, dryRun, len(plan.Transactions))
+		ReturnResult(ctx, w, WalletRemovalResult{DryRun: dryRun, WalletRemoval: plan})
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+)
+
+// Wallets page
+// swagger:model WalletsResult
+type WalletsResult struct {
+	Total   int             `json:"total"`
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
+	Wallets []models.Wallet `json:"wallets"`
+}
+
+// GetWallets returns users' wallets sorted by timestamp from oldest to newest.
+// Query: user_id, asset_id, wallet (requires asset_id), from, to (unix seconds or RFC3339), limit, offset
+func GetWallets(db *storage.Storage, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx).WithField("m", "GetWallets")
+	log.Debugf("GetWallets:: %v", r.URL.RawQuery)
+
+	tr, err := parseTimeRange(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		ERROR_BAD_REQUEST(w, err.Error())
+
+		return
+	}
+
+	f := storage.WalletFilter{
+		UserID:  r.URL.Query().Get("user_id"),
+		AssetID: r.URL.Query().Get("asset_id"),
+		Range:   tr,
+	}
+	if f.AssetID != "" {
+		asset, ok := findAsset(ctx, db, w, f.AssetID)
+		if !ok {
+			return
+		}
+		if s := r.URL.Query().Get("wallet"); s != "" {
+			if f.Address, ok = normalizeAddress(ctx, db, w, asset, s); !ok {
+				return
+			}
+		}
+	} else if r.URL.Query().Get("wallet") != "" {
+		ERROR_ASSET_NOT_SET(w)
+
+		return
+	}
+
+	wallets, total, err := db.GetWallets(ctx, f, page)
+	if err != nil {
+		log.Errorf("GetWallets error: %v", err)
+		ERROR_INTERNAL_SERVER(w, "")
+
+		return
+	}
+	if wallets == nil {
+		wallets = []models.Wallet{}
+	}
+
+	ReturnResult(ctx, w, WalletsResult{
+		Total:   total,
+		Limit:   page.Limit,
+		Offset:  page.Offset,
+		Wallets: wallets,
+	})
+}
@@ -0,0 +1,69 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"service_template/logger"
+)
+
+type componentsContextKey struct{}
+
+type components struct {
+	wg sync.WaitGroup
+}
+
+// Go runs a background component until ctx is done, the component must return after that
+func Go(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	log := logger.FromContext(ctx).WithField("m", "Go").WithField("component", name)
+	log.Debugf("Go:: ")
+
+	c, ok := ctx.Value(componentsContextKey{}).(*components)
+	if !ok {
+		log.Errorf("component %v is started outside of infra context", name)
+		return
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		if err := fn(ctx); err != nil && ctx.Err() == nil {
+			log.Errorf("component %v stopped: %v", name, err)
+			return
+		}
+		log.Debugf("component %v stopped", name)
+	}()
+}
+
+// Wait blocks until components started with Go return or the graceful shutdown timeout passes
+func Wait(ctx context.Context) error {
+	config, ok := ctx.Value(configContextKey{}).(Config)
+	if !ok {
+		return ErrInvalidContext
+	}
+	c, ok := ctx.Value(componentsContextKey{}).(*components)
+	if !ok {
+		return ErrInvalidContext
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	timeout := config.GracefulShutdownTimeout
+	if timeout == 0 {
+		timeout = defaultGracefulShutdownTimeout
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return errors.New("components did not stop in time")
+	}
+}
@@ -9,6 +9,10 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"service_template/logger"
@@ -31,26 +35,11 @@ type traceContextKey struct{}
 
 var ErrInvalidContext = errors.New("invalid context")
 
-// GenerateNewTrace
-func GenerateNewTrace(ctx context.Context) context.Context {
-	var serverSpan opentracing.Span
-	serverSpan = opentracing.StartSpan("HTTP request")
-	defer serverSpan.Finish()
-
-	if sc, ok := serverSpan.Context().(jaeger.SpanContext); ok {
-		traceID := sc.TraceID().String()
-		ctx = context.WithValue(ctx, traceContextKey{}, traceID)
-	}
-	log := logger.FromContext(ctx)
-	ctx = opentracing.ContextWithSpan(ctx, serverSpan)
-	log = logger.WithTraceId(log, serverSpan)
-	return logger.ToContext(ctx, log)
-}
-
 // Context
 func Context(config Config, wr io.Writer) context.Context {
-	ctx, _ := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
 	ctx = context.WithValue(ctx, configContextKey{}, config)
+	ctx = context.WithValue(ctx, componentsContextKey{}, new(components))
 
 	if config.Logger != nil {
 		ctx = logger.ToContext(ctx, logger.New(*config.Logger, wr, config.LoggerHooks...))
@@ -62,8 +51,13 @@ func Context(config Config, wr io.Writer) context.Context {
 		tracer.Init(config.ServiceName, *config.Tracer, logger.FromContext(ctx))
 	}
 
-	//sigCh := make(chan os.Signal, 1)
-	//signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		log.Infof("received %v, shutting down", sig)
+		cancel()
+	}()
 
 	healthStatus := int32(http.StatusTeapot)
 	ctx = context.WithValue(ctx, healthContextKey{}, &healthStatus)
@@ -100,6 +94,7 @@ func ServeHTTP(ctx context.Context, listen string, handler http.Handler) error {
 	}
 
 	log.Debugf("listening HTTP requests on %s", listen)
+	SetHealth(ctx, http.StatusOK)
 
 	server := http.Server{Handler: handler}
 
@@ -108,6 +103,7 @@ func ServeHTTP(ctx context.Context, listen string, handler http.Handler) error {
 	}()
 
 	<-ctx.Done()
+	SetHealth(ctx, http.StatusServiceUnavailable)
 
 	shutdownTimeout := config.GracefulShutdownTimeout
 	if shutdownTimeout == 0 {
@@ -123,3 +119,23 @@ func ServeHTTP(ctx context.Context, listen string, handler http.Handler) error {
 
 	return nil
 }
+
+// SetHealth sets the status code the health endpoint answers with
+func SetHealth(ctx context.Context, status int) {
+	if h, ok := ctx.Value(healthContextKey{}).(*int32); ok {
+		atomic.StoreInt32(h, int32(status))
+	}
+}
+
+// HealthHandler answers with the health status: 418 until the server listens, 200 while it serves
+// and 503 once it shuts down
+func HealthHandler(ctx context.Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusServiceUnavailable
+		if h, ok := ctx.Value(healthContextKey{}).(*int32); ok {
+			status = int(atomic.LoadInt32(h))
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(http.StatusText(status)))
+	})
+}
@@ -0,0 +1,363 @@
+package ingest
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"time"
+
+	"service_template/address"
+	"service_template/btc"
+	"service_template/logger"
+	"service_template/models"
+	"service_template/storage"
+	"service_template/zmq"
+)
+
+// bitcoind notification topics
+const (
+	TopicRawTx     = "rawtx"
+	TopicHashBlock = "hashblock"
+)
+
+const (
+	defaultReconnectMin = time.Second
+	defaultReconnectMax = time.Minute
+	// three block intervals, a mainnet node also publishes mempool transactions in between
+	defaultIdleTimeout = 30 * time.Minute
+)
+
+// BTCIngestor subscribes to bitcoind-style ZMQ notifications of a bitcoin-like chain
+// and stores transactions touching tracked wallets
+type BTCIngestor struct {
+	Chain    string
+	Endpoint string
+	DB       *storage.Storage
+	// OnBlock is called with the hash of every announced block
+	OnBlock func(hash string)
+
+	ReconnectMin time.Duration
+	ReconnectMax time.Duration
+	// IdleTimeout is how long a subscription may stay silent before it is considered
+	// dead, e.g. after the node went away without closing the connection
+	IdleTimeout time.Duration
+
+	seq map[string]uint32
+}
+
+// Run keeps a subscription open until ctx is done, reconnecting with exponential backoff
+func (i *BTCIngestor) Run(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithField("m", "Run").WithField("chain", i.Chain)
+	log.Debugf("Run:: endpoint: %v", i.Endpoint)
+
+	minDelay, maxDelay := i.ReconnectMin, i.ReconnectMax
+	if minDelay == 0 {
+		minDelay = defaultReconnectMin
+	}
+	if maxDelay == 0 {
+		maxDelay = defaultReconnectMax
+	}
+
+	delay := minDelay
+	for {
+		start := time.Now()
+		err := i.subscribe(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		// a subscription that lived for a while was healthy, start backing off from scratch
+		if time.Since(start) > maxDelay {
+			delay = minDelay
+		}
+		log.Warnf("zmq subscription to %v lost: %v, reconnecting in %v", i.Endpoint, err, delay)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+func (i *BTCIngestor) subscribe(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithField("m", "subscribe").WithField("chain", i.Chain)
+
+	dialCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	sub, err := zmq.DialSubscriber(dialCtx, i.Endpoint, TopicRawTx, TopicHashBlock)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.Close()
+		case <-stop:
+		}
+	}()
+
+	log.Infof("subscribed to %v", i.Endpoint)
+	i.seq = map[string]uint32{}
+
+	idle := i.IdleTimeout
+	if idle == 0 {
+		idle = defaultIdleTimeout
+	}
+
+	for {
+		if err := sub.SetReadDeadline(time.Now().Add(idle)); err != nil {
+			return err
+		}
+		msg, err := sub.Recv()
+		if err, ok := err.(net.Error); ok && err.Timeout() {
+			return fmt.Errorf("no notifications for %v", idle)
+		}
+		if err != nil {
+			return err
+		}
+		if len(msg) < 2 {
+			continue
+		}
+
+		topic := string(msg[0])
+		if len(msg) > 2 && len(msg[2]) == 4 {
+			i.checkSequence(ctx, topic, binary.LittleEndian.Uint32(msg[2]))
+		}
+
+		switch topic {
+		case TopicRawTx:
+			if _, err := i.HandleRawTx(ctx, msg[1], time.Now()); err != nil {
+				log.Errorf("rawtx error: %v", err)
+			}
+		case TopicHashBlock:
+			hash := hex.EncodeToString(msg[1])
+			log.Debugf("block %v announced", hash)
+			if i.OnBlock != nil {
+				i.OnBlock(hash)
+			}
+		}
+	}
+}
+
+// checkSequence warns about notifications dropped by the publisher
+func (i *BTCIngestor) checkSequence(ctx context.Context, topic string, seq uint32) {
+	if last, ok := i.seq[topic]; ok && seq != last+1 {
+		logger.FromContext(ctx).WithField("m", "checkSequence").
+			Warnf("%v %v notifications missed, sequence %d after %d", i.Chain, topic, seq, last)
+	}
+	i.seq[topic] = seq
+}
+
+// HandleRawTx stores outputs paying to tracked wallets as incoming rows and, when the
+// transaction spends outputs of tracked wallets, the outputs paying to other addresses
+// as outgoing rows of those wallets' users, see btcShares. It returns the number of inserted rows
+func (i *BTCIngestor) HandleRawTx(ctx context.Context, raw []byte, seen time.Time) (int, error) {
+	tx, err := btc.DecodeTx(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := i.transfers(ctx, tx, seen)
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+
+	return i.DB.AddBTCTransactions(ctx, rows)
+}
+
+func (i *BTCIngestor) transfers(ctx context.Context, tx *btc.Tx, seen time.Time) ([]models.BTCTransaction, error) {
+	asset, err := i.DB.NativeAsset(ctx, i.Chain)
+	if err != nil {
+		return nil, err
+	}
+	if asset == nil {
+		return nil, fmt.Errorf("chain %v has no native asset in the catalog", i.Chain)
+	}
+
+	return btcTransfers(ctx, i.DB, i.Chain, asset, tx, seen, nil)
+}
+
+// btcTransfers returns rows of a transaction touching tracked wallets. Pending holds incoming
+// rows not stored yet, e.g. of earlier transactions of the same block, that tx may spend
+func btcTransfers(ctx context.Context, db *storage.Storage, chain string, asset *models.Asset,
+	tx *btc.Tx, ts time.Time, pending []models.BTCTransaction) ([]models.BTCTransaction, error) {
+	outAddrs := make([]string, len(tx.Outputs))
+	var addrs []string
+	for n, out := range tx.Outputs {
+		if a, ok := address.FromScript(chain, out.Script); ok {
+			outAddrs[n] = a
+			addrs = append(addrs, a)
+		}
+	}
+
+	wallets, err := db.WalletsByAddress(ctx, asset.AssetID, addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	spenders, err := btcSpenders(ctx, db, chain, tx, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []models.BTCTransaction
+	for n, out := range tx.Outputs {
+		// OP_RETURN and other scripts without an address pay no one
+		if outAddrs[n] == "" {
+			continue
+		}
+
+		w, tracked := wallets[outAddrs[n]]
+		if tracked {
+			rows = append(rows, models.BTCTransaction{
+				Chain:     chain,
+				AssetID:   asset.AssetID,
+				UserID:    w.UserID,
+				TxID:      tx.TxID,
+				Vout:      n,
+				ToAddress: outAddrs[n],
+				Amount:    models.AmountFromInt64(out.Value, models.BTCDecimals),
+				Outgoing:  false,
+				Timestamp: ts,
+			})
+		}
+
+		for _, share := range btcShares(out.Value, spenders) {
+			// change back to the sender's own wallets is not an outgoing transfer
+			if tracked && w.UserID == share.user || share.value == 0 {
+				continue
+			}
+			rows = append(rows, models.BTCTransaction{
+				Chain:       chain,
+				AssetID:     asset.AssetID,
+				UserID:      share.user,
+				TxID:        tx.TxID,
+				Vout:        n,
+				FromAddress: spenders[share.user].from,
+				ToAddress:   outAddrs[n],
+				Amount:      models.AmountFromInt64(share.value, models.BTCDecimals),
+				Outgoing:    true,
+				Timestamp:   ts,
+			})
+		}
+	}
+
+	return rows, nil
+}
+
+// btcSpender is a user whose outputs a transaction spends
+type btcSpender struct {
+	// first spent address of the user
+	from string
+	// satoshis of the spent outputs
+	value *big.Int
+}
+
+type btcShare struct {
+	user  string
+	value int64
+}
+
+// btcShares splits an output value between the spenders by the value of the outputs each of them
+// spent, so a transaction funded by several users counts each satoshi once. Inputs of untracked
+// addresses cannot be valued without their previous transactions and are left out of the split.
+// Satoshis left by rounding down go to the largest remainders, ties to the smaller user id
+func btcShares(value int64, spenders map[string]*btcSpender) []btcShare {
+	if len(spenders) == 0 {
+		return nil
+	}
+
+	users := make([]string, 0, len(spenders))
+	total := new(big.Int)
+	for user, sp := range spenders {
+		users = append(users, user)
+		total.Add(total, sp.value)
+	}
+	sort.Strings(users)
+
+	// spent outputs of zero value, split evenly
+	even := total.Sign() == 0
+	if even {
+		total.SetInt64(int64(len(users)))
+	}
+	weight := func(user string) *big.Int {
+		if even {
+			return big.NewInt(1)
+		}
+		return spenders[user].value
+	}
+
+	shares := make([]btcShare, len(users))
+	rems := make([]*big.Int, len(users))
+	left := value
+	for i, user := range users {
+		q, r := new(big.Int).QuoRem(new(big.Int).Mul(big.NewInt(value), weight(user)), total, new(big.Int))
+		shares[i] = btcShare{user: user, value: q.Int64()}
+		rems[i] = r
+		left -= shares[i].value
+	}
+
+	order := make([]int, len(users))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return rems[order[a]].Cmp(rems[order[b]]) > 0 })
+	for _, i := range order[:left] {
+		shares[i].value++
+	}
+
+	return shares
+}
+
+// btcSpenders returns users whose stored or pending outputs the transaction spends
+func btcSpenders(ctx context.Context, db *storage.Storage, chain string, tx *btc.Tx,
+	pending []models.BTCTransaction) (map[string]*btcSpender, error) {
+	ret := map[string]*btcSpender{}
+	if tx.Coinbase() {
+		return ret, nil
+	}
+
+	var prevIDs []string
+	for _, in := range tx.Inputs {
+		prevIDs = append(prevIDs, in.PrevOut.TxID)
+	}
+
+	outputs, err := db.BTCIncomingOutputs(ctx, chain, prevIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range pending {
+		if !o.Outgoing {
+			outputs = append(outputs, o)
+		}
+	}
+
+	for _, in := range tx.Inputs {
+		for _, o := range outputs {
+			if o.TxID != in.PrevOut.TxID || o.Vout != int(in.PrevOut.Vout) {
+				continue
+			}
+			sp, ok := ret[o.UserID]
+			if !ok {
+				sp = &btcSpender{from: o.ToAddress, value: new(big.Int)}
+				ret[o.UserID] = sp
+			}
+			sp.value.Add(sp.value, o.Amount.Units())
+		}
+	}
+
+	return ret, nil
+}
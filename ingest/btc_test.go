@@ -0,0 +1,75 @@
+package ingest
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"service_template/zmq"
+)
+
+func TestBTCShares(t *testing.T) {
+	spenders := func(values map[string]int64) map[string]*btcSpender {
+		ret := map[string]*btcSpender{}
+		for user, v := range values {
+			ret[user] = &btcSpender{value: big.NewInt(v)}
+		}
+		return ret
+	}
+
+	tests := []struct {
+		name     string
+		value    int64
+		spenders map[string]int64
+		want     []btcShare
+	}{
+		{"no spenders", 1000, nil, nil},
+		{"single", 1000, map[string]int64{"a": 7}, []btcShare{{"a", 1000}}},
+		{"by input value", 1000, map[string]int64{"b": 3000, "a": 1000}, []btcShare{{"a", 250}, {"b", 750}}},
+		// 100/3 each, the satoshi left goes to the smaller user id
+		{"even remainder", 100, map[string]int64{"c": 5, "b": 5, "a": 5}, []btcShare{{"a", 34}, {"b", 33}, {"c", 33}}},
+		// 10*2/3 = 6.67 and 10*1/3 = 3.33, the larger remainder gets the satoshi
+		{"largest remainder", 10, map[string]int64{"a": 1, "b": 2}, []btcShare{{"a", 3}, {"b", 7}}},
+		{"zero value inputs", 9, map[string]int64{"a": 0, "b": 0}, []btcShare{{"a", 5}, {"b", 4}}},
+		// the product overflows int64
+		{"max supply", 2100000000000000, map[string]int64{"a": 2100000000000000, "b": 2100000000000000},
+			[]btcShare{{"a", 1050000000000000}, {"b", 1050000000000000}}},
+	}
+	for _, tt := range tests {
+		got := btcShares(tt.value, spenders(tt.spenders))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: %v, want %v", tt.name, got, tt.want)
+		}
+		var sum int64
+		for _, s := range got {
+			sum += s.value
+		}
+		if len(got) > 0 && sum != tt.value {
+			t.Errorf("%v: shares sum to %v", tt.name, sum)
+		}
+	}
+}
+
+func TestSubscribeIdleTimeout(t *testing.T) {
+	pub, err := zmq.NewPublisher("tcp://127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the publisher stays connected but silent, like a half-open connection
+	i := &BTCIngestor{Chain: "BTC", Endpoint: pub.Endpoint(), IdleTimeout: 50 * time.Millisecond}
+	err = i.subscribe(ctx)
+	if ctx.Err() != nil {
+		t.Fatal("subscription not dropped")
+	}
+	if err == nil || !strings.Contains(err.Error(), "no notifications") {
+		t.Errorf("error %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"service_template/models"
+	"service_template/nodes/ethereum"
+	"service_template/storage"
+)
+
+// ethSource reads blocks of an ethereum-like chain, native transfers from block transactions
+// and token transfers from Transfer events of catalog contracts
+type ethSource struct {
+	chain  string
+	client *ethereum.Client
+	db     *storage.Storage
+}
+
+func NewETHSource(chain string, client *ethereum.Client, db *storage.Storage) Source {
+	return &ethSource{chain: chain, client: client, db: db}
+}
+
+func (s *ethSource) Tip(ctx context.Context) (int64, error) {
+	n, err := s.client.BlockNumber(ctx)
+
+	return int64(n), err
+}
+
+func (s *ethSource) Blocks(ctx context.Context, from int64, count int) ([]Block, error) {
+	raw, err := s.client.BlocksByNumber(ctx, uint64(from), count)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]Block, len(raw))
+	for i, b := range raw {
+		number, err := ethereum.ParseQuantity(b.Number)
+		if err != nil {
+			return nil, fmt.Errorf("block %v: %v", b.Hash, err)
+		}
+		ts, err := ethereum.ParseQuantity(b.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("block %v: %v", b.Hash, err)
+		}
+		blocks[i] = Block{
+			Height:     int64(number),
+			Hash:       b.Hash,
+			ParentHash: b.ParentHash,
+			Time:       time.Unix(int64(ts), 0).UTC(),
+			raw:        b,
+		}
+	}
+
+	return blocks, nil
+}
+
+func (s *ethSource) Transfers(ctx context.Context, blocks []Block) (storage.ChainRows, error) {
+	var rows storage.ChainRows
+
+	assets, err := s.db.ChainAssets(ctx, s.chain)
+	if err != nil {
+		return rows, err
+	}
+	var native *models.Asset
+	tokens := map[string]*models.Asset{}
+	var contracts []string
+	for i := range assets {
+		if assets[i].Native() {
+			native = &assets[i]
+			continue
+		}
+		contract := strings.ToLower(assets[i].ContractAddress)
+		tokens[contract] = &assets[i]
+		contracts = append(contracts, contract)
+	}
+
+	var transfers []transfer
+	byHash := map[string]Block{}
+	for _, b := range blocks {
+		byHash[b.Hash] = b
+		if native == nil {
+			continue
+		}
+		for _, tx := range b.raw.(*ethereum.Block).Transactions {
+			value, err := ethereum.ParseBig(tx.Value)
+			if err != nil {
+				return rows, fmt.Errorf("tx %v: %v", tx.Hash, err)
+			}
+			if value.Sign() == 0 {
+				continue
+			}
+			transfers = append(transfers, transfer{
+				asset:    native,
+				txID:     strings.ToLower(tx.Hash),
+				logIndex: -1,
+				from:     strings.ToLower(tx.From),
+				to:       strings.ToLower(tx.To),
+				amount:   value,
+				block:    b,
+			})
+		}
+	}
+
+	if len(contracts) > 0 {
+		logs, err := s.client.GetLogs(ctx, ethereum.FilterQuery{
+			FromBlock: uint64(blocks[0].Height),
+			ToBlock:   uint64(blocks[len(blocks)-1].Height),
+			Addresses: contracts,
+			Topics:    [][]string{{ethereum.TransferTopic}},
+		})
+		if err != nil {
+			return rows, err
+		}
+		for _, l := range logs {
+			t, ok, err := tokenTransfer(l, tokens, byHash)
+			if err != nil {
+				return rows, err
+			}
+			if ok {
+				transfers = append(transfers, t)
+			}
+		}
+	}
+
+	matched, err := matchTransfers(ctx, s.db, transfers)
+	if err != nil {
+		return rows, err
+	}
+	matched, err = s.succeeded(ctx, matched)
+	if err != nil {
+		return rows, err
+	}
+
+	for _, t := range matched {
+		row := models.ETHTransaction{
+			Chain:       s.chain,
+			AssetID:     t.asset.AssetID,
+			UserID:      t.userID,
+			TxID:        t.txID,
+			LogIndex:    t.logIndex,
+			FromAddress: t.from,
+			ToAddress:   t.to,
+			Amount:      models.NewAmount(t.amount, t.asset.Decimals),
+			Decimals:    t.asset.Decimals,
+			Outgoing:    t.outgoing,
+			Timestamp:   t.block.Time,
+		}
+		setBlock(&row.BlockHeight, &row.BlockHash, t.block)
+		rows.ETH = append(rows.ETH, row)
+	}
+
+	return rows, nil
+}
+
+func tokenTransfer(l ethereum.Log, tokens map[string]*models.Asset, byHash map[string]Block) (transfer, bool, error) {
+	asset, ok := tokens[strings.ToLower(l.Address)]
+	// Transfer events of non-standard tokens with unindexed arguments are not supported
+	if !ok || l.Removed || len(l.Topics) != 3 {
+		return transfer{}, false, nil
+	}
+	b, ok := byHash[l.BlockHash]
+	if !ok {
+		return transfer{}, false, fmt.Errorf("log of tx %v is in block %v which is not in the batch", l.TxHash, l.BlockHash)
+	}
+
+	from, err := ethereum.TopicAddress(l.Topics[1])
+	if err != nil {
+		return transfer{}, false, err
+	}
+	to, err := ethereum.TopicAddress(l.Topics[2])
+	if err != nil {
+		return transfer{}, false, err
+	}
+	amount, err := ethereum.ParseBig(l.Data)
+	if err != nil {
+		return transfer{}, false, fmt.Errorf("log of tx %v: %v", l.TxHash, err)
+	}
+	index, err := ethereum.ParseQuantity(l.LogIndex)
+	if err != nil {
+		return transfer{}, false, fmt.Errorf("log of tx %v: %v", l.TxHash, err)
+	}
+
+	return transfer{
+		asset:    asset,
+		txID:     strings.ToLower(l.TxHash),
+		logIndex: int(index),
+		from:     from,
+		to:       to,
+		amount:   amount,
+		block:    b,
+	}, true, nil
+}
+
+// succeeded drops native transfers of reverted transactions, events of reverted
+// transactions are never emitted
+func (s *ethSource) succeeded(ctx context.Context, transfers []walletTransfer) ([]walletTransfer, error) {
+	status := map[string]bool{}
+	ret := transfers[:0]
+	for _, t := range transfers {
+		if t.logIndex >= 0 {
+			ret = append(ret, t)
+			continue
+		}
+
+		ok, known := status[t.txID]
+		if !known {
+			r, err := s.client.TransactionReceipt(ctx, t.txID)
+			if err != nil {
+				return nil, err
+			}
+			if r == nil {
+				return nil, fmt.Errorf("receipt of %v not found", t.txID)
+			}
+			ok = r.Status != "0x0"
+			status[t.txID] = ok
+		}
+		if ok {
+			ret = append(ret, t)
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,266 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"service_template/logger"
+	"service_template/metrics"
+	"service_template/models"
+	"service_template/storage"
+)
+
+const (
+	defaultBatchSize    = 50
+	defaultPollInterval = 10 * time.Second
+	defaultReorgWindow  = 100
+)
+
+// Block is a block fetched by a Source, raw keeps the chain specific payload for Transfers
+type Block struct {
+	Height     int64
+	Hash       string
+	ParentHash string
+	Time       time.Time
+	raw        interface{}
+}
+
+// Source reads blocks of one chain from its node
+type Source interface {
+	// Tip returns the height of the best block
+	Tip(ctx context.Context) (int64, error)
+	// Blocks returns up to count consecutive blocks starting at from, fewer at the tip
+	Blocks(ctx context.Context, from int64, count int) ([]Block, error)
+	// Transfers extracts rows of tracked wallets from consecutive blocks returned by Blocks
+	Transfers(ctx context.Context, blocks []Block) (storage.ChainRows, error)
+}
+
+// Indexer walks the blocks of a chain from its stored checkpoint, stores transfers touching
+// tracked wallets and rolls back blocks orphaned by reorganizations
+type Indexer struct {
+	Chain  *models.Chain
+	Source Source
+	DB     *storage.Storage
+
+	// StartHeight is the first block indexed when the chain has no checkpoint, negative starts at the tip
+	StartHeight  int64
+	BatchSize    int
+	PollInterval time.Duration
+	// ReorgWindow is the number of recent blocks kept to find the fork point of a reorganization
+	ReorgWindow int64
+
+	notify chan struct{}
+	mu     sync.Mutex
+	status models.IndexerStatus
+}
+
+func NewIndexer(chain *models.Chain, source Source, db *storage.Storage) *Indexer {
+	return &Indexer{
+		Chain:        chain,
+		Source:       source,
+		DB:           db,
+		StartHeight:  -1,
+		BatchSize:    defaultBatchSize,
+		PollInterval: defaultPollInterval,
+		ReorgWindow:  defaultReorgWindow,
+		notify:       make(chan struct{}, 1),
+		status:       models.IndexerStatus{Chain: chain.Name},
+	}
+}
+
+// Notify wakes up a waiting indexer, e.g. when the node announces a new block
+func (x *Indexer) Notify() {
+	select {
+	case x.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the progress of the indexer
+func (x *Indexer) Status() models.IndexerStatus {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return x.status
+}
+
+// Run indexes until ctx is done, waiting for PollInterval or a notification once at the tip
+// and after errors
+func (x *Indexer) Run(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithField("m", "Run").WithField("chain", x.Chain.Name)
+	log.Debugf("Run:: ")
+
+	x.mu.Lock()
+	x.status.Running = true
+	x.mu.Unlock()
+	defer func() {
+		x.mu.Lock()
+		x.status.Running = false
+		x.mu.Unlock()
+	}()
+
+	for {
+		caughtUp, err := x.Step(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			log.Errorf("indexing failed: %v", err)
+			metrics.IndexerErrors.WithLabelValues(x.Chain.Name).Inc()
+			now := time.Now()
+			x.mu.Lock()
+			x.status.LastError = err.Error()
+			x.status.LastErrorAt = &now
+			x.mu.Unlock()
+		}
+		if !caughtUp && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-x.notify:
+		case <-time.After(x.PollInterval):
+		}
+	}
+}
+
+// Step indexes one batch of blocks and reports whether the checkpoint reached the tip
+func (x *Indexer) Step(ctx context.Context) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "Step").WithField("chain", x.Chain.Name)
+
+	cp, err := x.DB.GetCheckpoint(ctx, x.Chain.Name)
+	if err != nil {
+		return false, err
+	}
+	tip, err := x.Source.Tip(ctx)
+	if err != nil {
+		return false, err
+	}
+	x.progress(cp, tip)
+
+	next, parent := x.StartHeight, ""
+	if cp != nil {
+		next, parent = cp.Height+1, cp.Hash
+	} else if next < 0 || next > tip {
+		next = tip
+	}
+	if next > tip {
+		return true, nil
+	}
+
+	count := tip - next + 1
+	if count > int64(x.BatchSize) {
+		count = int64(x.BatchSize)
+	}
+	log.Debugf("Step:: from: %v, count: %v, tip: %v", next, count, tip)
+
+	blocks, err := x.Source.Blocks(ctx, next, int(count))
+	if err != nil {
+		return false, err
+	}
+	if len(blocks) == 0 {
+		return true, nil
+	}
+
+	for i, b := range blocks {
+		if b.Height != next+int64(i) {
+			return false, fmt.Errorf("node returned block %v at height %v", b.Height, next+int64(i))
+		}
+		if i == 0 && parent != "" && b.ParentHash != parent {
+			return false, x.rollback(ctx, cp)
+		}
+		// the node switched branches while the batch was fetched, the next step sees the fork
+		if i > 0 && b.ParentHash != blocks[i-1].Hash {
+			blocks = blocks[:i]
+			break
+		}
+	}
+
+	rows, err := x.Source.Transfers(ctx, blocks)
+	if err != nil {
+		return false, err
+	}
+
+	indexed := make([]models.IndexedBlock, len(blocks))
+	for i, b := range blocks {
+		indexed[i] = models.IndexedBlock{Height: b.Height, Hash: b.Hash, ParentHash: b.ParentHash, Timestamp: b.Time}
+	}
+	stored, err := x.DB.StoreBlocks(ctx, x.Chain.Name, indexed, rows)
+	if err != nil {
+		return false, err
+	}
+	metrics.IndexerRows.WithLabelValues(x.Chain.Name).Add(float64(stored))
+
+	last := blocks[len(blocks)-1]
+	if err := x.DB.PruneIndexedBlocks(ctx, x.Chain.Name, last.Height-x.ReorgWindow); err != nil {
+		return false, err
+	}
+	x.progress(&models.IndexerCheckpoint{Chain: x.Chain.Name, Height: last.Height, Hash: last.Hash, UpdatedAt: time.Now()}, tip)
+	if stored > 0 {
+		log.Infof("blocks %v-%v indexed, %v rows stored", blocks[0].Height, last.Height, stored)
+	}
+
+	return last.Height >= tip, nil
+}
+
+// rollback finds the last kept block the node still agrees with and rolls back everything above it
+func (x *Indexer) rollback(ctx context.Context, cp *models.IndexerCheckpoint) error {
+	log := logger.FromContext(ctx).WithField("m", "rollback").WithField("chain", x.Chain.Name)
+	log.Debugf("rollback:: checkpoint: %v", cp.Height)
+
+	for h := cp.Height - 1; h >= 0 && h >= cp.Height-x.ReorgWindow; h-- {
+		kept, err := x.DB.GetIndexedBlock(ctx, x.Chain.Name, h)
+		if err != nil {
+			return err
+		}
+		if kept == nil {
+			break
+		}
+
+		blocks, err := x.Source.Blocks(ctx, h, 1)
+		if err != nil {
+			return err
+		}
+		if len(blocks) == 0 || blocks[0].Hash != kept.Hash {
+			continue
+		}
+
+		rows, err := x.DB.RollbackBlocks(ctx, x.Chain, kept)
+		if err != nil {
+			return err
+		}
+		metrics.IndexerReorgs.WithLabelValues(x.Chain.Name).Inc()
+		x.mu.Lock()
+		x.status.Reorgs++
+		x.mu.Unlock()
+		log.Warnf("reorganization: rolled back blocks %v-%v to %v, %v rows removed", h+1, cp.Height, kept.Hash, rows)
+
+		return nil
+	}
+
+	return fmt.Errorf("reorganization deeper than the kept blocks below %v, the checkpoint has to be reset", cp.Height)
+}
+
+func (x *Indexer) progress(cp *models.IndexerCheckpoint, tip int64) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.status.Tip = tip
+	if cp != nil {
+		x.status.Height = cp.Height
+		x.status.Hash = cp.Hash
+		x.status.UpdatedAt = cp.UpdatedAt
+		x.status.Lag = tip - cp.Height
+		if x.status.Lag < 0 {
+			x.status.Lag = 0
+		}
+	}
+
+	metrics.IndexerTip.WithLabelValues(x.Chain.Name).Set(float64(tip))
+	metrics.IndexerHeight.WithLabelValues(x.Chain.Name).Set(float64(x.status.Height))
+	metrics.IndexerLag.WithLabelValues(x.Chain.Name).Set(float64(x.status.Lag))
+}
@@ -0,0 +1,146 @@
+package ingest
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"service_template/btc"
+	"service_template/models"
+	"service_template/nodes/bitcoind"
+	"service_template/storage"
+)
+
+// btcSource reads blocks of a bitcoin-like chain from a bitcoind compatible node
+type btcSource struct {
+	chain  string
+	client *bitcoind.Client
+	db     *storage.Storage
+}
+
+func NewBTCSource(chain string, client *bitcoind.Client, db *storage.Storage) Source {
+	return &btcSource{chain: chain, client: client, db: db}
+}
+
+func (s *btcSource) Tip(ctx context.Context) (int64, error) {
+	return s.client.GetBlockCount(ctx)
+}
+
+func (s *btcSource) Blocks(ctx context.Context, from int64, count int) ([]Block, error) {
+	hashes, err := s.client.GetBlockHashes(ctx, from, count)
+	if err != nil || len(hashes) == 0 {
+		return nil, err
+	}
+	raw, err := s.client.GetBlocks(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]Block, len(raw))
+	for i, b := range raw {
+		blocks[i] = Block{
+			Height:     b.Height,
+			Hash:       b.Hash,
+			ParentHash: b.PreviousBlockHash,
+			Time:       time.Unix(b.Time, 0).UTC(),
+			raw:        b,
+		}
+	}
+
+	return blocks, nil
+}
+
+func (s *btcSource) Transfers(ctx context.Context, blocks []Block) (storage.ChainRows, error) {
+	var rows storage.ChainRows
+
+	asset, err := s.db.NativeAsset(ctx, s.chain)
+	if err != nil {
+		return rows, err
+	}
+	if asset == nil {
+		return rows, fmt.Errorf("chain %v has no native asset in the catalog", s.chain)
+	}
+
+	for _, b := range blocks {
+		block := b.raw.(*bitcoind.Block)
+		for _, t := range block.Tx {
+			raw, err := hex.DecodeString(t.Hex)
+			if err != nil {
+				return rows, fmt.Errorf("block %v tx %v: %v", b.Hash, t.TxID, err)
+			}
+			tx, err := btc.DecodeTx(raw)
+			if err != nil {
+				return rows, fmt.Errorf("block %v tx %v: %v", b.Hash, t.TxID, err)
+			}
+
+			// outputs created earlier in the batch are not stored yet but may be spent
+			txRows, err := btcTransfers(ctx, s.db, s.chain, asset, tx, b.Time, rows.BTC)
+			if err != nil {
+				return rows, err
+			}
+			for i := range txRows {
+				setBlock(&txRows[i].BlockHeight, &txRows[i].BlockHash, b)
+			}
+			rows.BTC = append(rows.BTC, txRows...)
+		}
+	}
+
+	return rows, nil
+}
+
+func setBlock(height **int64, hash **string, b Block) {
+	h, id := b.Height, b.Hash
+	*height, *hash = &h, &id
+}
+
+// transfer is a value movement on an account based chain
+type transfer struct {
+	asset    *models.Asset
+	txID     string
+	logIndex int
+	from     string
+	to       string
+	amount   *big.Int
+	block    Block
+}
+
+// walletTransfer is a transfer seen from a tracked wallet's user
+type walletTransfer struct {
+	transfer
+	userID   string
+	outgoing bool
+}
+
+// matchTransfers keeps transfers touching tracked wallets, as incoming for the receiving user
+// and outgoing for the sending user unless both wallets belong to the same user
+func matchTransfers(ctx context.Context, db *storage.Storage, transfers []transfer) ([]walletTransfer, error) {
+	addrs := map[string][]string{}
+	for _, t := range transfers {
+		addrs[t.asset.AssetID] = append(addrs[t.asset.AssetID], t.from, t.to)
+	}
+
+	wallets := map[string]map[string]models.Wallet{}
+	for assetID, list := range addrs {
+		w, err := db.WalletsByAddress(ctx, assetID, list)
+		if err != nil {
+			return nil, err
+		}
+		wallets[assetID] = w
+	}
+
+	var ret []walletTransfer
+	for _, t := range transfers {
+		from, fromOK := wallets[t.asset.AssetID][t.from]
+		to, toOK := wallets[t.asset.AssetID][t.to]
+		if toOK {
+			ret = append(ret, walletTransfer{transfer: t, userID: to.UserID})
+		}
+		if fromOK && !(toOK && to.UserID == from.UserID) {
+			ret = append(ret, walletTransfer{transfer: t, userID: from.UserID, outgoing: true})
+		}
+	}
+
+	return ret, nil
+}
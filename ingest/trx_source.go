@@ -0,0 +1,188 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"service_template/address"
+	"service_template/models"
+	"service_template/nodes/ethereum"
+	"service_template/nodes/tron"
+	"service_template/storage"
+)
+
+// trxSource reads TRON blocks, TRX transfers from block transactions and TRC20 transfers
+// from Transfer events of catalog contracts
+type trxSource struct {
+	chain  string
+	client *tron.Client
+	db     *storage.Storage
+}
+
+func NewTRXSource(chain string, client *tron.Client, db *storage.Storage) Source {
+	return &trxSource{chain: chain, client: client, db: db}
+}
+
+func (s *trxSource) Tip(ctx context.Context) (int64, error) {
+	b, err := s.client.GetNowBlock(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return b.Number(), nil
+}
+
+func (s *trxSource) Blocks(ctx context.Context, from int64, count int) ([]Block, error) {
+	raw, err := s.client.GetBlocks(ctx, from, count)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]Block, len(raw))
+	for i, b := range raw {
+		blocks[i] = Block{
+			Height:     b.Number(),
+			Hash:       b.BlockID,
+			ParentHash: b.ParentHash(),
+			Time:       b.Time(),
+			raw:        b,
+		}
+	}
+
+	return blocks, nil
+}
+
+func (s *trxSource) Transfers(ctx context.Context, blocks []Block) (storage.ChainRows, error) {
+	var rows storage.ChainRows
+
+	assets, err := s.db.ChainAssets(ctx, s.chain)
+	if err != nil {
+		return rows, err
+	}
+	var native *models.Asset
+	tokens := map[string]*models.Asset{}
+	for i := range assets {
+		if assets[i].Native() {
+			native = &assets[i]
+			continue
+		}
+		tokens[assets[i].ContractAddress] = &assets[i]
+	}
+
+	var transfers []transfer
+	for _, b := range blocks {
+		block := b.raw.(*tron.Block)
+		hasTokenCalls := false
+		for _, tx := range block.Transactions {
+			if !tx.Success() || len(tx.RawData.Contract) == 0 {
+				continue
+			}
+			c := tx.RawData.Contract[0]
+			switch c.Type {
+			case "TransferContract":
+				if native == nil {
+					continue
+				}
+				from, ok1 := address.TRXFromHex(c.Parameter.Value.OwnerAddress)
+				to, ok2 := address.TRXFromHex(c.Parameter.Value.ToAddress)
+				if !ok1 || !ok2 {
+					return rows, fmt.Errorf("tx %v: invalid address", tx.TxID)
+				}
+				transfers = append(transfers, transfer{
+					asset:    native,
+					txID:     strings.ToLower(tx.TxID),
+					logIndex: -1,
+					from:     from,
+					to:       to,
+					amount:   big.NewInt(c.Parameter.Value.Amount),
+					block:    b,
+				})
+			case "TriggerSmartContract":
+				if contract, ok := address.TRXFromHex(c.Parameter.Value.ContractAddress); ok && tokens[contract] != nil {
+					hasTokenCalls = true
+				}
+			}
+		}
+
+		// events are only served per block, skip the call for blocks without token transactions
+		if !hasTokenCalls {
+			continue
+		}
+		infos, err := s.client.GetTransactionInfoByBlockNum(ctx, b.Height)
+		if err != nil {
+			return rows, err
+		}
+		for _, info := range infos {
+			t, err := trc20Transfers(info, tokens, b)
+			if err != nil {
+				return rows, err
+			}
+			transfers = append(transfers, t...)
+		}
+	}
+
+	matched, err := matchTransfers(ctx, s.db, transfers)
+	if err != nil {
+		return rows, err
+	}
+
+	for _, t := range matched {
+		row := models.TRXTransaction{
+			AssetID:     t.asset.AssetID,
+			UserID:      t.userID,
+			TxID:        t.txID,
+			LogIndex:    t.logIndex,
+			FromAddress: t.from,
+			ToAddress:   t.to,
+			Amount:      models.NewAmount(t.amount, t.asset.Decimals),
+			Decimals:    t.asset.Decimals,
+			Outgoing:    t.outgoing,
+			Timestamp:   t.block.Time,
+		}
+		setBlock(&row.BlockHeight, &row.BlockHash, t.block)
+		rows.TRX = append(rows.TRX, row)
+	}
+
+	return rows, nil
+}
+
+func trc20Transfers(info tron.TransactionInfo, tokens map[string]*models.Asset, b Block) ([]transfer, error) {
+	if info.Receipt.Result != "" && info.Receipt.Result != "SUCCESS" {
+		return nil, nil
+	}
+
+	var ret []transfer
+	for i, l := range info.Log {
+		if len(l.Topics) != 3 || "0x"+strings.ToLower(l.Topics[0]) != ethereum.TransferTopic {
+			continue
+		}
+		contract, ok := address.TRXFromHex(l.Address)
+		if !ok || tokens[contract] == nil {
+			continue
+		}
+
+		from, ok1 := address.TRXFromHex(l.Topics[1])
+		to, ok2 := address.TRXFromHex(l.Topics[2])
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("tx %v log %v: invalid address", info.ID, i)
+		}
+		amount, err := ethereum.ParseBig("0x" + l.Data)
+		if err != nil {
+			return nil, fmt.Errorf("tx %v log %v: %v", info.ID, i, err)
+		}
+
+		ret = append(ret, transfer{
+			asset:    tokens[contract],
+			txID:     strings.ToLower(info.ID),
+			logIndex: i,
+			from:     from,
+			to:       to,
+			amount:   amount,
+			block:    b,
+		})
+	}
+
+	return ret, nil
+}
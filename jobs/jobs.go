@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"service_template/logger"
+)
+
+// Run calls fn immediately and then every interval until ctx is done.
+// Errors are logged and the job keeps running
+func Run(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	log := logger.FromContext(ctx).WithField("m", "Run").WithField("job", name)
+	log.Debugf("Run:: interval: %v", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		if err := fn(ctx); err != nil {
+			log.Errorf("job %v error: %v", name, err)
+		} else {
+			log.Debugf("job %v finished in %v", name, time.Since(start))
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Debugf("job %v stopped", name)
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 
 	llog "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -63,5 +68,148 @@ func main() {
 
 	srv := &app.App{Infra: infraConfig}
 	srv.Initialize(ictx)
+
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		backfill(ictx, srv, os.Args[2:])
+
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import-prices" {
+		importPrices(ictx, srv, os.Args[2:])
+
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "create-client" {
+		createClient(ictx, srv, os.Args[2:])
+
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "create-user" {
+		createUser(ictx, srv, os.Args[2:])
+
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "revoke-client" {
+		revokeClient(ictx, srv, os.Args[2:])
+
+		return
+	}
+
 	srv.Run(ictx, bindHost)
 }
+
+// backfill recomputes rollups for a historical range:
+//
+//	statserver backfill -from 2022-01-01T00:00:00Z [-to 2022-02-01T00:00:00Z]
+func backfill(ctx context.Context, srv *app.App, args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	fromFlag := fs.String("from", "", "start of the range, RFC3339")
+	toFlag := fs.String("to", "", "end of the range, RFC3339, now by default")
+	fs.Parse(args)
+
+	from, err := time.Parse(time.RFC3339, *fromFlag)
+	if err != nil {
+		llog.Fatalln("Invalid -from", err)
+	}
+
+	to := time.Now()
+	if *toFlag != "" {
+		if to, err = time.Parse(time.RFC3339, *toFlag); err != nil {
+			llog.Fatalln("Invalid -to", err)
+		}
+	}
+
+	if err := srv.Backfill(ctx, from, to); err != nil {
+		llog.Fatalln("Backfill error", err)
+	}
+}
+
+// importPrices loads a price history CSV with columns asset_id, fiat, timestamp, price:
+//
+//	statserver import-prices -file prices.csv
+func importPrices(ctx context.Context, srv *app.App, args []string) {
+	fs := flag.NewFlagSet("import-prices", flag.ExitOnError)
+	fileFlag := fs.String("file", "", "CSV file, stdin by default")
+	fs.Parse(args)
+
+	var r io.Reader = os.Stdin
+	if *fileFlag != "" {
+		f, err := os.Open(*fileFlag)
+		if err != nil {
+			llog.Fatalln("Cannot open -file", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	n, err := srv.ImportPrices(ctx, r)
+	if err != nil {
+		llog.Fatalln("Import prices error", err)
+	}
+	llog.Infof("Imported %d prices", n)
+}
+
+// createClient adds an API client and prints its token, the token is not stored and cannot be shown again:
+//
+//	statserver create-client -name ops -kind admin -permissions wallets.remove [-ttl 720h]
+func createClient(ctx context.Context, srv *app.App, args []string) {
+	fs := flag.NewFlagSet("create-client", flag.ExitOnError)
+	nameFlag := fs.String("name", "", "unique client name")
+	kindFlag := fs.String("kind", "service", "admin or service")
+	rolesFlag := fs.String("roles", "", "comma separated roles")
+	permissionsFlag := fs.String("permissions", "", "comma separated permissions")
+	ttlFlag := fs.Duration("ttl", 0, "token lifetime, the token does not expire by default")
+	fs.Parse(args)
+
+	token, err := srv.CreateAPIClient(ctx, *nameFlag, *kindFlag, splitList(*rolesFlag), splitList(*permissionsFlag), *ttlFlag)
+	if err != nil {
+		llog.Fatalln("Create client error", err)
+	}
+	fmt.Println(token)
+}
+
+// revokeClient revokes the token of an API client:
+//
+//	statserver revoke-client -name ops
+func revokeClient(ctx context.Context, srv *app.App, args []string) {
+	fs := flag.NewFlagSet("revoke-client", flag.ExitOnError)
+	nameFlag := fs.String("name", "", "client name")
+	fs.Parse(args)
+
+	if err := srv.RevokeAPIClient(ctx, *nameFlag); err != nil {
+		llog.Fatalln("Revoke client error", err)
+	}
+}
+
+// createUser adds an admin user that logs in with a password, the password is read from the first
+// line of stdin so it does not end up in the shell history:
+//
+//	statserver create-user -username alice -roles admin < password.txt
+func createUser(ctx context.Context, srv *app.App, args []string) {
+	fs := flag.NewFlagSet("create-user", flag.ExitOnError)
+	usernameFlag := fs.String("username", "", "unique username")
+	rolesFlag := fs.String("roles", "", "comma separated roles")
+	permissionsFlag := fs.String("permissions", "", "comma separated permissions")
+	fs.Parse(args)
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		llog.Fatalln("Cannot read password", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+
+	if err := srv.CreateAdminUser(ctx, *usernameFlag, password, splitList(*rolesFlag), splitList(*permissionsFlag)); err != nil {
+		llog.Fatalln("Create user error", err)
+	}
+}
+
+func splitList(s string) []string {
+	ret := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			ret = append(ret, v)
+		}
+	}
+
+	return ret
+}
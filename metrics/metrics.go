@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "statserver"
+
+var (
+	// ReconciliationFindings is the number of reconciliation findings by kind and status
+	ReconciliationFindings = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "reconciliation",
+		Name:      "findings",
+		Help:      "Number of reconciliation findings by kind and status.",
+	}, []string{"kind", "status"})
+
+	// ReconciliationNewFindings counts findings recorded by reconciliation runs
+	ReconciliationNewFindings = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reconciliation",
+		Name:      "new_findings_total",
+		Help:      "Number of findings recorded by reconciliation runs.",
+	})
+
+	// IndexerHeight is the last indexed block by chain
+	IndexerHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "indexer",
+		Name:      "height",
+		Help:      "Height of the last indexed block.",
+	}, []string{"chain"})
+
+	// IndexerTip is the chain tip reported by the node
+	IndexerTip = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "indexer",
+		Name:      "tip",
+		Help:      "Height of the chain tip reported by the node.",
+	}, []string{"chain"})
+
+	// IndexerLag is the number of blocks the indexer is behind the tip
+	IndexerLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "indexer",
+		Name:      "lag_blocks",
+		Help:      "Number of blocks between the last indexed block and the chain tip.",
+	}, []string{"chain"})
+
+	// IndexerRows counts transaction rows stored by the indexer
+	IndexerRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "indexer",
+		Name:      "rows_total",
+		Help:      "Number of transaction rows stored by the indexer.",
+	}, []string{"chain"})
+
+	// IndexerReorgs counts chain reorganizations rolled back by the indexer
+	IndexerReorgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "indexer",
+		Name:      "reorgs_total",
+		Help:      "Number of chain reorganizations rolled back.",
+	}, []string{"chain"})
+
+	// IndexerErrors counts failed indexer steps
+	IndexerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "indexer",
+		Name:      "errors_total",
+		Help:      "Number of failed indexing steps.",
+	}, []string{"chain"})
+
+	// AlertsRaised counts alerts raised by rule kind and severity
+	AlertsRaised = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "alerts",
+		Name:      "raised_total",
+		Help:      "Number of alerts raised by rule kind and severity.",
+	}, []string{"kind", "severity"})
+
+	// AlertNotifyErrors counts failed alert deliveries by notifier
+	AlertNotifyErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "alerts",
+		Name:      "notify_errors_total",
+		Help:      "Number of failed alert deliveries by notifier.",
+	}, []string{"notifier"})
+
+	// RateLimited counts requests rejected by rate limiting by route class
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "Number of requests rejected by rate limiting by route class.",
+	}, []string{"class"})
+
+	// HTTPRequests counts served requests by method, route template and status
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of served requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes request latency by method and route template
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of served requests by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// HTTPResponseBytes counts response body bytes by method and route template
+	HTTPResponseBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "response_bytes_total",
+		Help:      "Number of response body bytes by method and route.",
+	}, []string{"method", "route"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		ReconciliationFindings,
+		ReconciliationNewFindings,
+		IndexerHeight,
+		IndexerTip,
+		IndexerLag,
+		IndexerRows,
+		IndexerReorgs,
+		IndexerErrors,
+		AlertsRaised,
+		AlertNotifyErrors,
+		RateLimited,
+		HTTPRequests,
+		HTTPRequestDuration,
+		HTTPResponseBytes,
+	)
+}
+
+// Handler serves metrics of the default registry
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
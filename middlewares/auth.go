@@ -2,39 +2,180 @@ package middlewares
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
+	"time"
 
-	"github.com/jinzhu/gorm"
-
+	"service_template/auth"
+	"service_template/clientip"
+	"service_template/handlers"
 	"service_template/logger"
+	"service_template/models"
+	"service_template/routes"
+	"service_template/storage"
 )
 
-func AuthMiddlewareGenerator(ctx context.Context, db *gorm.DB) (mw func(http.Handler) http.Handler) {
+// AuthMiddlewareGenerator authenticates requests by a bearer token of an API client, an admin
+// user session or, when jwt is set, a signed JWT, and puts the principal into the request context.
+// Routes declared public in reg are not checked
+func AuthMiddlewareGenerator(ctx context.Context, db *storage.Storage, jwt *auth.JWTVerifier, reg *routes.Registry) (mw func(http.Handler) http.Handler) {
 	log := logger.FromContext(ctx).WithField("m", "AuthMiddlewareGenerator")
 	log.Debugf("AuthMiddlewareGenerator:: ")
 
 	mw = func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			/*
-				tokenHeader := r.Header.Get("Authorization") // get token from HTTP header
+			// public, or authenticated by a middleware running earlier such as request signing
+			if reg.ForRequest(r).Public || auth.FromContext(r.Context()) != nil {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			tokenHeader := r.Header.Get("Authorization")
+			if tokenHeader == "" {
+				handlers.ERROR_AUTH_MISSING(w)
+
+				return
+			}
+
+			token := auth.BearerToken(tokenHeader)
+			if prefix, ok := auth.SchemeTokenPrefix(auth.SessionTokenScheme, token); ok {
+				principal, reason, err := sessionPrincipal(r.Context(), db, prefix, token)
+				if err != nil {
+					logger.FromContext(r.Context()).WithField("m", "AuthMiddleware").Errorf("session error: %v", err)
+					handlers.ERROR_INTERNAL_SERVER(w, "")
 
-				if tokenHeader == "" {
-					handlers.ERROR_AUTH_MISSING(w)
+					return
+				}
+				if principal == nil {
+					handlers.ERROR_AUTH_INVALID(w, reason)
 
 					return
 				}
+				next.ServeHTTP(w, withPrincipal(r, principal))
+
+				return
+			}
 
-				adminUser := new(models.AdminUser)
-				db.First(&adminUser, "password = ?", tokenHeader)
-				if adminUser.ID == 0 {
-					handlers.ERROR_AUTH_INVALID(w, tokenHeader)
+			prefix, ok := auth.TokenPrefix(token)
+			if !ok && jwt != nil {
+				principal, err := jwt.Verify(token, time.Now())
+				if errors.Is(err, auth.ErrTokenMalformed) {
+					handlers.ERROR_AUTH_CANNOT_PARSE_TOKEN(w)
+
+					return
+				}
+				if err != nil {
+					logger.FromContext(r.Context()).WithField("m", "AuthMiddleware").Infof("jwt rejected: %v", err)
+					handlers.ERROR_AUTH_TOKEN_INVALID(w)
 
 					return
 				}
-			*/
-			next.ServeHTTP(w, r)
+				next.ServeHTTP(w, withPrincipal(r, principal))
+
+				return
+			}
+			if !ok {
+				handlers.ERROR_AUTH_INVALID(w, "malformed token")
+
+				return
+			}
+
+			client, err := db.GetAPIClientByPrefix(r.Context(), prefix)
+			if err != nil {
+				logger.FromContext(r.Context()).WithField("m", "AuthMiddleware").Errorf("GetAPIClientByPrefix error: %v", err)
+				handlers.ERROR_INTERNAL_SERVER(w, "")
+
+				return
+			}
+
+			now := time.Now()
+			reason, forbidden := clientRejection(client, prefix, token, clientip.FromRequest(r), now)
+			if forbidden {
+				handlers.ERROR_AUTH_FORBIDDEN(w, reason)
+
+				return
+			}
+			if reason != "" {
+				handlers.ERROR_AUTH_INVALID(w, reason)
+
+				return
+			}
+			db.TouchAPIClient(client.ID, now)
+
+			principal := &auth.Principal{
+				ID:          client.Name,
+				Kind:        client.Kind,
+				Roles:       client.Roles,
+				Permissions: client.Permissions,
+			}
+			next.ServeHTTP(w, withPrincipal(r, principal))
 		})
 	}
 
 	return
 }
+
+// clientRejection returns why the token of an API client, nil if the prefix is unknown, is
+// rejected or "" if it is accepted. Forbidden is set for a valid token used from another address
+func clientRejection(client *models.APIClient, prefix, token string, ip net.IP, now time.Time) (reason string, forbidden bool) {
+	// hash even for unknown prefixes so both paths take the same time
+	hash := ""
+	if client != nil {
+		hash = client.TokenHashFor(prefix, now)
+	}
+	if !auth.VerifyToken(token, hash) || client == nil {
+		return "unknown token", false
+	}
+	if client.RevokedAt != nil {
+		return "token revoked", false
+	}
+	if client.Expired(now) {
+		return "token expired", false
+	}
+	if !client.AllowsIP(ip) {
+		return "address not allowed", true
+	}
+
+	return "", false
+}
+
+// sessionPrincipal returns the admin user of an active session, or a nil principal and
+// the reason the token is rejected
+func sessionPrincipal(ctx context.Context, db *storage.Storage, prefix, token string) (*auth.Principal, string, error) {
+	session, err := db.GetAdminSessionByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, "", err
+	}
+
+	hash := ""
+	if session != nil {
+		hash = session.TokenHash
+	}
+	if !auth.VerifyToken(token, hash) || session == nil {
+		return nil, "unknown token", nil
+	}
+	if session.RevokedAt != nil {
+		return nil, "session revoked", nil
+	}
+	if !time.Now().Before(session.ExpiresAt) {
+		return nil, "token expired", nil
+	}
+
+	user, err := db.GetAdminUser(ctx, session.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	if user == nil || user.Disabled {
+		return nil, "user disabled", nil
+	}
+
+	return &auth.Principal{
+		ID:          user.Username,
+		Kind:        models.AdminUserKind,
+		Roles:       user.Roles,
+		Permissions: user.Permissions,
+		SessionID:   session.ID,
+	}, "", nil
+}
@@ -0,0 +1,167 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"service_template/auth"
+	"service_template/handlers"
+	"service_template/models"
+	"service_template/routes"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "oct", "kid": "hs", "alg": auth.AlgHS256, "k": base64.RawURLEncoding.EncodeToString(secret)},
+	}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.LoadKeySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwt := func(claims map[string]interface{}) string {
+		seg := func(v interface{}) string {
+			b, _ := json.Marshal(v)
+			return base64.RawURLEncoding.EncodeToString(b)
+		}
+		signed := seg(map[string]string{"alg": auth.AlgHS256, "kid": "hs", "typ": "JWT"}) + "." + seg(claims)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	ctx := context.Background()
+	reg := routes.NewRegistry(routes.Options{})
+	router := mux.NewRouter()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := auth.FromContext(r.Context()); p != nil {
+			w.Header().Set("X-Principal", p.ID)
+		}
+	})
+	router.Handle("/private", ok)
+	reg.Set(router.Handle("/public", ok), routes.Public())
+	router.Use(AuthMiddlewareGenerator(ctx, nil, auth.NewJWTVerifier(auth.JWTConfig{}, keys), reg))
+
+	tests := []struct {
+		name, path, header string
+		status             int
+		code, principal    string
+	}{
+		{"public", "/public", "", http.StatusOK, "", ""},
+		{"missing", "/private", "", http.StatusForbidden, "ERROR_AUTH_MISSING", ""},
+		{"not bearer", "/private", "Basic dXNlcjpwYXNz", http.StatusForbidden, "ERROR_AUTH_CANNOT_PARSE_TOKEN", ""},
+		{"malformed", "/private", "Bearer st_short_secret", http.StatusForbidden, "ERROR_AUTH_CANNOT_PARSE_TOKEN", ""},
+		{"bad signature", "/private", "Bearer " + jwt(map[string]interface{}{"sub": "alice"}) + "x",
+			http.StatusForbidden, "ERROR_AUTH_TOKEN_INVALID", ""},
+		{"expired jwt", "/private", "Bearer " + jwt(map[string]interface{}{"sub": "alice", "exp": time.Now().Add(-time.Hour).Unix()}),
+			http.StatusForbidden, "ERROR_AUTH_TOKEN_INVALID", ""},
+		{"jwt", "/private", "Bearer " + jwt(map[string]interface{}{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}),
+			http.StatusOK, "", "alice"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Errorf("%v: status %v, want %v", tt.name, w.Code, tt.status)
+		}
+		var e handlers.ForeignError
+		json.Unmarshal(w.Body.Bytes(), &e)
+		if e.Code != tt.code {
+			t.Errorf("%v: code %q, want %q", tt.name, e.Code, tt.code)
+		}
+		if got := w.Header().Get("X-Principal"); got != tt.principal {
+			t.Errorf("%v: principal %q, want %q", tt.name, got, tt.principal)
+		}
+	}
+
+	// without a JWT verifier tokens of no known scheme are rejected as malformed
+	router = mux.NewRouter()
+	router.Handle("/private", ok)
+	router.Use(AuthMiddlewareGenerator(ctx, nil, nil, routes.NewRegistry(routes.Options{})))
+	r := httptest.NewRequest("GET", "/private", nil)
+	r.Header.Set("Authorization", "Bearer "+jwt(map[string]interface{}{"sub": "alice"}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	var e handlers.ForeignError
+	json.Unmarshal(w.Body.Bytes(), &e)
+	if w.Code != http.StatusForbidden || e.Code != "ERROR_AUTH_INVALID" || e.Payload != "malformed token" {
+		t.Errorf("without jwt: %v %+v", w.Code, e)
+	}
+}
+
+func TestClientRejection(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	token, prefix, hash, err := auth.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevToken, prevPrefix, prevHash, err := auth.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	client := func(edit func(c *models.APIClient)) *models.APIClient {
+		c := &models.APIClient{TokenPrefix: prefix, TokenHash: hash,
+			PreviousTokenPrefix: &prevPrefix, PreviousTokenHash: &prevHash, PreviousExpiresAt: &future}
+		if edit != nil {
+			edit(c)
+		}
+		return c
+	}
+	ip := net.ParseIP("203.0.113.7")
+
+	tests := []struct {
+		name      string
+		client    *models.APIClient
+		prefix    string
+		token     string
+		reason    string
+		forbidden bool
+	}{
+		{"valid", client(nil), prefix, token, "", false},
+		{"previous in the overlap", client(nil), prevPrefix, prevToken, "", false},
+		{"previous after the overlap", client(func(c *models.APIClient) { c.PreviousExpiresAt = &past }),
+			prevPrefix, prevToken, "unknown token", false},
+		{"unknown prefix", nil, prefix, token, "unknown token", false},
+		{"wrong secret", client(nil), prefix, token + "x", "unknown token", false},
+		{"revoked", client(func(c *models.APIClient) { c.RevokedAt = &past }), prefix, token, "token revoked", false},
+		{"expired", client(func(c *models.APIClient) { c.ExpiresAt = &now }), prefix, token, "token expired", false},
+		{"allowed address", client(func(c *models.APIClient) { c.AllowedIPs = []string{"203.0.113.0/24"} }),
+			prefix, token, "", false},
+		{"other address", client(func(c *models.APIClient) { c.AllowedIPs = []string{"2001:db8::/32"} }),
+			prefix, token, "address not allowed", true},
+		// a revoked token is not told apart by the address check
+		{"revoked from other address", client(func(c *models.APIClient) {
+			c.RevokedAt = &past
+			c.AllowedIPs = []string{"2001:db8::/32"}
+		}), prefix, token, "token revoked", false},
+	}
+	for _, tt := range tests {
+		reason, forbidden := clientRejection(tt.client, tt.prefix, tt.token, ip, now)
+		if reason != tt.reason || forbidden != tt.forbidden {
+			t.Errorf("%v: %q %v, want %q %v", tt.name, reason, forbidden, tt.reason, tt.forbidden)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"service_template/clientip"
+	"service_template/logger"
+)
+
+// ClientIPMiddlewareGenerator resolves the client address of requests once, later middlewares
+// and handlers read it with clientip.FromRequest
+func ClientIPMiddlewareGenerator(ctx context.Context, res *clientip.Resolver) (mw func(http.Handler) http.Handler) {
+	log := logger.FromContext(ctx).WithField("m", "ClientIPMiddlewareGenerator")
+	log.Debugf("ClientIPMiddlewareGenerator:: ")
+
+	mw = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(clientip.ToContext(r.Context(), res.Resolve(r))))
+		})
+	}
+
+	return
+}
@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"service_template/handlers"
+	"service_template/logger"
+	"service_template/routes"
+)
+
+// timeoutBody answers requests of routes that did not respond within their timeout
+var timeoutBody = func() string {
+	b, _ := json.Marshal(handlers.ForeignError{Code: "ERROR_SERVICE_UNAVAILABLE", Payload: "request timed out"})
+
+	return string(b)
+}()
+
+// RouteLimitsMiddlewareGenerator applies the body limit and timeout reg declares for the matched
+// route. A route that does not respond within its timeout is answered with 503, its handler runs
+// on until its next context check and its response is discarded. Streaming routes only get
+// the request context bounded
+func RouteLimitsMiddlewareGenerator(ctx context.Context, reg *routes.Registry) (mw func(http.Handler) http.Handler) {
+	log := logger.FromContext(ctx).WithField("m", "RouteLimitsMiddlewareGenerator")
+	log.Debugf("RouteLimitsMiddlewareGenerator:: ")
+
+	mw = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			opts := reg.ForRequest(r)
+
+			if opts.BodyLimit > 0 {
+				if r.ContentLength > opts.BodyLimit {
+					handlers.ERROR_REQUEST_TOO_LARGE(w, strconv.FormatInt(opts.BodyLimit, 10))
+
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, opts.BodyLimit)
+			}
+
+			h := next
+			if opts.Timeout > 0 && opts.Streaming {
+				tctx, cancel := context.WithTimeout(r.Context(), opts.Timeout)
+				defer cancel()
+				r = r.WithContext(tctx)
+			} else if opts.Timeout > 0 {
+				h = http.TimeoutHandler(next, opts.Timeout, timeoutBody)
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+
+	return
+}
@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"service_template/handlers"
+	"service_template/routes"
+)
+
+func TestRouteLimits(t *testing.T) {
+	reg := routes.NewRegistry(routes.Options{BodyLimit: 8, Timeout: time.Minute})
+	router := mux.NewRouter()
+
+	release := make(chan struct{})
+	defer close(release)
+	reg.Set(router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		// like a query ignoring the context
+		<-release
+		w.Write([]byte("late"))
+	}), routes.Timeout(20*time.Millisecond))
+	reg.Set(router.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("streaming route without a deadline")
+		}
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("streaming route cannot flush")
+		}
+		w.Write([]byte("rows"))
+	}), routes.Timeout(time.Minute), routes.Streaming())
+	router.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	})
+	router.Use(RouteLimitsMiddlewareGenerator(context.Background(), reg))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := serve("GET", "/slow", "")
+	var e handlers.ForeignError
+	json.Unmarshal(w.Body.Bytes(), &e)
+	if w.Code != http.StatusServiceUnavailable || e.Code != "ERROR_SERVICE_UNAVAILABLE" {
+		t.Errorf("slow: %v %q", w.Code, w.Body)
+	}
+
+	if w := serve("GET", "/stream", ""); w.Code != http.StatusOK || w.Body.String() != "rows" {
+		t.Errorf("stream: %v %q", w.Code, w.Body)
+	}
+
+	w = serve("POST", "/fast", "{}")
+	if w.Code != http.StatusCreated || w.Body.String() != "{}" || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("fast: %v %q %v", w.Code, w.Body, w.Header())
+	}
+
+	if w := serve("POST", "/fast", "123456789"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("large body: %v %q", w.Code, w.Body)
+	}
+}
@@ -2,22 +2,175 @@ package middlewares
 
 import (
 	"bytes"
-	"io/ioutil"
-	"log"
+	"context"
+	"encoding/json"
+	"io"
 	"net/http"
+	"strings"
+	"time"
+
+	"service_template/auth"
+	"service_template/clientip"
+	"service_template/logger"
+	"service_template/recorder"
+	"service_template/routes"
 )
 
-func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r != nil {
-			rawBody, err := ioutil.ReadAll(r.Body)
-			if err == nil {
-				log.Printf("Request: %v, %v, %v", r.Method, r.RequestURI, string(rawBody))
+const redactedValue = "<hidden>"
+
+// AccessLogConfig controls bodies in the access log
+type AccessLogConfig struct {
+	// BodyLimit is the largest body logged in bytes, larger bodies are omitted
+	BodyLimit int
+	// RedactKeys hide JSON values whose key contains one of them, case insensitive
+	RedactKeys []string
+}
+
+// accessEntry collects what inner middlewares learn about a request
+type accessEntry struct {
+	principal *auth.Principal
+}
+
+type accessEntryKey struct{}
+
+// withPrincipal authenticates a request and records its principal for the access log
+func withPrincipal(r *http.Request, p *auth.Principal) *http.Request {
+	if e, ok := r.Context().Value(accessEntryKey{}).(*accessEntry); ok {
+		e.principal = p
+	}
+
+	return r.WithContext(auth.ToContext(r.Context(), p))
+}
+
+// AccessLogMiddlewareGenerator logs every request through the context logger, which carries
+// the trace ID, once it is served. Request and response bodies are logged only for routes
+// declaring routes.LogBody, and only when they are JSON within the configured limit
+func AccessLogMiddlewareGenerator(ctx context.Context, cfg AccessLogConfig, reg *routes.Registry) (mw func(http.Handler) http.Handler) {
+	log := logger.FromContext(ctx).WithField("m", "AccessLogMiddlewareGenerator")
+	log.Debugf("AccessLogMiddlewareGenerator:: cfg: %+v", cfg)
+
+	redactKeys := make([]string, len(cfg.RedactKeys))
+	for i, k := range cfg.RedactKeys {
+		redactKeys[i] = strings.ToLower(k)
+	}
+
+	mw = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			opts := reg.ForRequest(r)
+			rec := recorder.Wrap(w)
+
+			var body interface{}
+			if opts.LogBody {
+				body = readLoggedBody(r, cfg.BodyLimit, redactKeys)
+				rec.CaptureBody(cfg.BodyLimit)
+			}
+
+			entry := &accessEntry{}
+			next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, entry)))
+
+			status := rec.Status()
+			route := routeTemplate(r)
+
+			l := logger.FromContext(r.Context()).WithField("m", "AccessLog").
+				WithField("method", r.Method).
+				WithField("route", route).
+				WithField("path", r.URL.Path).
+				WithField("status", status).
+				WithField("bytes", rec.Bytes()).
+				WithField("latency_ms", float64(time.Since(start).Microseconds())/1000).
+				WithField("principal", entry.principal.String())
+			if ip := clientip.FromRequest(r); ip != nil {
+				l = l.WithField("ip", ip.String())
+			}
+			if body != nil {
+				l = l.WithField("body", body)
+			}
+			if opts.LogBody {
+				if b, truncated := rec.Body(); len(b) > 0 {
+					l = l.WithField("response_body", loggedJSON(b, truncated, redactKeys))
+				}
+			}
+
+			if status >= http.StatusInternalServerError {
+				l.Errorf("%v %v %v", r.Method, route, status)
 			} else {
-				log.Printf("Request: %v, %v, %v", r.Method, r.RequestURI, err.Error())
+				l.Infof("%v %v %v", r.Method, route, status)
+			}
+		})
+	}
+
+	return
+}
+
+// readLoggedBody reads up to limit bytes of the body for the log and puts them back in front
+// of the rest
+func readLoggedBody(r *http.Request, limit int, redactKeys []string) interface{} {
+	head, err := io.ReadAll(io.LimitReader(r.Body, int64(limit)+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+	if err != nil {
+		return "<unreadable: " + err.Error() + ">"
+	}
+	if len(head) == 0 {
+		return nil
+	}
+
+	return loggedJSON(head, len(head) > limit, redactKeys)
+}
+
+// loggedJSON is a body with sensitive values redacted, or a note why it is not logged
+func loggedJSON(b []byte, truncated bool, redactKeys []string) interface{} {
+	if truncated {
+		return "<omitted, larger than limit>"
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return "<omitted, not JSON>"
+	}
+	redact(v, redactKeys)
+
+	// keep the redaction marker and bodies with markup readable
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "<omitted, not JSON>"
+	}
+
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// redact hides the values of sensitive keys in decoded JSON in place
+func redact(v interface{}, keys []string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if sensitiveKey(k, keys) {
+				v[k] = redactedValue
+
+				continue
 			}
-			r.Body = ioutil.NopCloser(bytes.NewBuffer(rawBody))
+			redact(item, keys)
 		}
-		next.ServeHTTP(w, r)
-	})
+	case []interface{}:
+		for _, item := range v {
+			redact(item, keys)
+		}
+	}
+}
+
+func sensitiveKey(key string, keys []string) bool {
+	key = strings.ToLower(key)
+	for _, k := range keys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+
+	return false
 }
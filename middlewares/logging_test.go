@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testRedactKeys = []string{"password", "api_key", "token"}
+
+func TestLoggedJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		truncated bool
+		want      string
+	}{
+		{"plain", `{"name":"ops","ttl_seconds":60}`, false, `{"name":"ops","ttl_seconds":60}`},
+		{"top level", `{"username":"ann","password":"hunter2"}`, false,
+			`{"password":"` + redactedValue + `","username":"ann"}`},
+		{"case insensitive", `{"Password":"a","X-API-KEY":"b","Api_Key":"c"}`, false,
+			`{"Api_Key":"` + redactedValue + `","Password":"` + redactedValue + `","X-API-KEY":"b"}`},
+		{"key containing a sensitive key", `{"refresh_token":"x","token_prefix":"y"}`, false,
+			`{"refresh_token":"` + redactedValue + `","token_prefix":"` + redactedValue + `"}`},
+		{"nested", `{"user":{"name":"ann","new_password":"x","meta":{"token":"t"}}}`, false,
+			`{"user":{"meta":{"token":"` + redactedValue + `"},"name":"ann","new_password":"` + redactedValue + `"}}`},
+		{"objects in arrays", `[{"password":"a"},{"items":[{"api_key":"b","id":1}]}]`, false,
+			`[{"password":"` + redactedValue + `"},{"items":[{"api_key":"` + redactedValue + `","id":1}]}]`},
+		// a whole sensitive object is hidden, not only its leaves
+		{"sensitive object", `{"token":{"value":"x"}}`, false, `{"token":"` + redactedValue + `"}`},
+		{"truncated", `{"password":"hunt`, true, "<omitted, larger than limit>"},
+		{"not JSON", `password=hunter2`, false, "<omitted, not JSON>"},
+		{"broken JSON", `{"password":"hunter2"`, false, "<omitted, not JSON>"},
+	}
+	for _, tt := range tests {
+		got := loggedJSON([]byte(tt.body), tt.truncated, testRedactKeys)
+		if got != tt.want {
+			t.Errorf("%v: %v, want %v", tt.name, got, tt.want)
+		}
+		if s, _ := got.(string); strings.Contains(s, "hunter2") {
+			t.Errorf("%v: secret logged", tt.name)
+		}
+	}
+}
+
+func TestReadLoggedBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		limit  int
+		logged interface{}
+	}{
+		{"empty", "", 16, nil},
+		{"within limit", `{"password":"x"}`, 16, `{"password":"` + redactedValue + `"}`},
+		{"over limit", `{"password":"hunter2"}`, 16, "<omitted, larger than limit>"},
+		{"not JSON", "a,b,c\n1,2,3\n", 64, "<omitted, not JSON>"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		if got := readLoggedBody(r, tt.limit, testRedactKeys); got != tt.logged {
+			t.Errorf("%v: logged %v, want %v", tt.name, got, tt.logged)
+		}
+
+		// the handler still reads the whole body
+		b, err := io.ReadAll(r.Body)
+		if err != nil || string(b) != tt.body {
+			t.Errorf("%v: handler read %q, %v", tt.name, b, err)
+		}
+		if err := r.Body.Close(); err != nil {
+			t.Errorf("%v: close: %v", tt.name, err)
+		}
+	}
+}
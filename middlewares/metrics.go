@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"service_template/logger"
+	"service_template/metrics"
+	"service_template/recorder"
+)
+
+// MetricsMiddlewareGenerator counts requests and observes their latency by route template,
+// it goes first so requests rejected by later middlewares are counted too. The router skips
+// middlewares for requests matching no route, so it must also wrap the router NotFoundHandler
+// and MethodNotAllowedHandler, those requests are counted with the route "unmatched"
+func MetricsMiddlewareGenerator(ctx context.Context) (mw func(http.Handler) http.Handler) {
+	log := logger.FromContext(ctx).WithField("m", "MetricsMiddlewareGenerator")
+	log.Debugf("MetricsMiddlewareGenerator:: ")
+
+	mw = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := recorder.Wrap(w)
+			next.ServeHTTP(rec, r)
+
+			route := routeTemplate(r)
+			metrics.HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(rec.Status())).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+			metrics.HTTPResponseBytes.WithLabelValues(r.Method, route).Add(float64(rec.Bytes()))
+		})
+	}
+
+	return
+}
+
+// routeTemplate is the path template of the matched route, which keeps label values bounded
+func routeTemplate(r *http.Request) string {
+	if cur := mux.CurrentRoute(r); cur != nil {
+		if tpl, err := cur.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+
+	return "unmatched"
+}
@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"service_template/auth"
+	"service_template/handlers"
+	"service_template/logger"
+	"service_template/routes"
+	"service_template/storage"
+)
+
+// PermissionMiddlewareGenerator extends the principal with the permissions of its roles and
+// rejects requests missing a permission that reg declares for the matched route
+func PermissionMiddlewareGenerator(ctx context.Context, db *storage.Storage, reg *routes.Registry) (mw func(http.Handler) http.Handler) {
+	log := logger.FromContext(ctx).WithField("m", "PermissionMiddlewareGenerator")
+	log.Debugf("PermissionMiddlewareGenerator:: ")
+
+	mw = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			perms := reg.ForRequest(r).Permissions
+
+			principal := auth.FromContext(r.Context())
+			if principal == nil {
+				if len(perms) > 0 {
+					handlers.ERROR_AUTH_MISSING(w)
+
+					return
+				}
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			effective, err := effectivePrincipal(r.Context(), db, principal)
+			if err != nil {
+				logger.FromContext(r.Context()).WithField("m", "PermissionMiddleware").Errorf("RolePermissions error: %v", err)
+				handlers.ERROR_INTERNAL_SERVER(w, "")
+
+				return
+			}
+			for _, p := range perms {
+				if !effective.Can(p) {
+					handlers.ERROR_AUTH_NO_PERMISSION(w, p)
+
+					return
+				}
+			}
+
+			next.ServeHTTP(w, withPrincipal(r, effective))
+		})
+	}
+
+	return
+}
+
+// effectivePrincipal returns a copy of p holding its own permissions and those of its roles
+func effectivePrincipal(ctx context.Context, db *storage.Storage, p *auth.Principal) (*auth.Principal, error) {
+	rolePerms, err := db.RolePermissions(ctx, p.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	effective := *p
+	effective.Permissions = nil
+	for _, perm := range append(append([]string{}, p.Permissions...), rolePerms...) {
+		if !seen[perm] {
+			seen[perm] = true
+			effective.Permissions = append(effective.Permissions, perm)
+		}
+	}
+
+	return &effective, nil
+}
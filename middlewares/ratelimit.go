@@ -0,0 +1,121 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"service_template/auth"
+	"service_template/clientip"
+	"service_template/handlers"
+	"service_template/logger"
+	"service_template/metrics"
+	"service_template/ratelimit"
+	"service_template/routes"
+)
+
+// rateClassIP labels the per address bucket taken before authentication
+const rateClassIP = "ip"
+
+// RateLimitMiddlewareGenerator limits requests with the token bucket of the route rate class in
+// limits, a class without a limit uses the default class. Buckets are kept per principal, so each
+// API client, user and service has its own, and per client address for anonymous requests.
+// It must run after authentication
+func RateLimitMiddlewareGenerator(ctx context.Context, store ratelimit.Store, limits map[string]ratelimit.Limit, reg *routes.Registry) (mw func(http.Handler) http.Handler) {
+	log := logger.FromContext(ctx).WithField("m", "RateLimitMiddlewareGenerator")
+	log.Debugf("RateLimitMiddlewareGenerator:: limits: %+v", limits)
+
+	mw = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			class := reg.ForRequest(r).RateClass
+			limit, ok := limits[class]
+			if !ok {
+				class = routes.RateDefault
+				limit = limits[class]
+			}
+			if !limit.Valid() {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if !takeRateLimit(w, r, store, class, class+":"+rateLimitKey(r), limit) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	return
+}
+
+// IPRateLimitMiddlewareGenerator limits all requests of a client address with one token bucket.
+// It runs before authentication, so requests with bad credentials, which never reach the
+// principal buckets of RateLimitMiddlewareGenerator, are throttled too. The limit should be
+// well above the default class, many callers can share an address behind NAT
+func IPRateLimitMiddlewareGenerator(ctx context.Context, store ratelimit.Store, limit ratelimit.Limit) (mw func(http.Handler) http.Handler) {
+	log := logger.FromContext(ctx).WithField("m", "IPRateLimitMiddlewareGenerator")
+	log.Debugf("IPRateLimitMiddlewareGenerator:: limit: %+v", limit)
+
+	mw = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !takeRateLimit(w, r, store, rateClassIP, rateClassIP+":"+clientIPKey(r), limit) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	return
+}
+
+// takeRateLimit takes a token of the bucket and sets the RateLimit headers, it answers 429 and
+// returns false if the bucket is empty. Store errors are logged, the store then falls back to
+// a local bucket
+func takeRateLimit(w http.ResponseWriter, r *http.Request, store ratelimit.Store, class, key string, limit ratelimit.Limit) bool {
+	res, err := store.Take(r.Context(), key, limit)
+	if err != nil {
+		logger.FromContext(r.Context()).WithField("m", "RateLimitMiddleware").Warnf("shared rate limit unavailable: %v", err)
+	}
+
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", limit.Requests, ceilSeconds(limit.Per), limit.Burst))
+	if !res.Allowed {
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+		metrics.RateLimited.WithLabelValues(class).Inc()
+		handlers.ERROR_TOO_MANY_REQUESTS(w, class)
+
+		return false
+	}
+
+	return true
+}
+
+// rateLimitKey identifies the caller of a request
+func rateLimitKey(r *http.Request) string {
+	if p := auth.FromContext(r.Context()); p != nil {
+		return "principal:" + p.String()
+	}
+
+	return "ip:" + clientIPKey(r)
+}
+
+func clientIPKey(r *http.Request) string {
+	if ip := clientip.FromRequest(r); ip != nil {
+		return ip.String()
+	}
+
+	return "unknown"
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
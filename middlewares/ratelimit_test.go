@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"service_template/ratelimit"
+)
+
+func TestIPRateLimit(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 1, Per: 10 * time.Second, Burst: 2}
+	h := IPRateLimitMiddlewareGenerator(context.Background(), ratelimit.NewMemoryStore(), limit)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(remote string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/api/v1/auth/login", nil)
+		r.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i, want := range []string{"1", "0"} {
+		w := serve("203.0.113.7:1000")
+		if w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != want {
+			t.Errorf("request %d: %v, remaining %v", i, w.Code, w.Header().Get("RateLimit-Remaining"))
+		}
+	}
+
+	w := serve("203.0.113.7:2000")
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("exhausted: %v", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "10" {
+		t.Errorf("Retry-After %q", got)
+	}
+	if got := w.Header().Get("RateLimit-Policy"); got != "1;w=10;burst=2" {
+		t.Errorf("RateLimit-Policy %q", got)
+	}
+
+	if w := serve("203.0.113.8:1000"); w.Code != http.StatusOK {
+		t.Errorf("other address: %v", w.Code)
+	}
+}
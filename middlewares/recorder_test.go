@@ -0,0 +1,109 @@
+package middlewares
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	muxhandlers "github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+
+	"service_template/routes"
+	"service_template/tracer"
+)
+
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (p *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+// Flush, Hijack and Push reach the server's writer through the recorders of the tracer,
+// metrics and access log middlewares, as the app stacks them
+func TestRecorderChain(t *testing.T) {
+	ctx := context.Background()
+	reg := routes.NewRegistry(routes.Options{})
+	router := mux.NewRouter()
+
+	proceed := make(chan struct{})
+	reg.Set(router.HandleFunc("/flush", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-proceed:
+		case <-time.After(5 * time.Second):
+		}
+		w.Write([]byte("second"))
+	}), routes.LogBody())
+	reg.Set(router.HandleFunc("/hijack", func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		rw.Flush()
+	}), routes.LogBody())
+	router.HandleFunc("/push", func(w http.ResponseWriter, r *http.Request) {
+		if err := w.(http.Pusher).Push("/style.css", nil); err != nil {
+			t.Errorf("push: %v", err)
+		}
+	})
+	router.Use(MetricsMiddlewareGenerator(ctx))
+	router.Use(AccessLogMiddlewareGenerator(ctx, AccessLogConfig{BodyLimit: 64}, reg))
+	handler := tracer.Middleware(muxhandlers.CORS()(router))
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	// the first part arrives while the handler still waits
+	var resp *http.Response
+	first := make([]byte, 5)
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		if resp, err = http.Get(srv.URL + "/flush"); err == nil {
+			_, err = io.ReadFull(resp.Body, first)
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil || string(first) != "first" {
+			t.Errorf("flushed %q, %v", first, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("flush did not reach the client")
+	}
+	close(proceed)
+	rest, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(rest) != "second" {
+		t.Errorf("rest %q, %v", rest, err)
+	}
+
+	resp, err = http.Get(srv.URL + "/hijack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(bufio.NewReader(resp.Body))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "hijacked" {
+		t.Errorf("hijacked response %v %q", resp.StatusCode, body)
+	}
+
+	w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/push", nil))
+	if len(w.pushed) != 1 || w.pushed[0] != "/style.css" {
+		t.Errorf("pushed %v", w.pushed)
+	}
+}
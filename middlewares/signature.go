@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"service_template/auth"
+	"service_template/handlers"
+	"service_template/logger"
+)
+
+// SignatureMiddlewareGenerator authenticates requests signed with the HMAC signature scheme and
+// puts the principal of the signing key into the request context. Requests with other credentials
+// are passed on untouched, so it must run before AuthMiddlewareGenerator
+func SignatureMiddlewareGenerator(ctx context.Context, v *auth.SignatureVerifier) (mw func(http.Handler) http.Handler) {
+	log := logger.FromContext(ctx).WithField("m", "SignatureMiddlewareGenerator")
+	log.Debugf("SignatureMiddlewareGenerator:: enabled: %v", v != nil)
+
+	mw = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if v == nil || !strings.HasPrefix(r.Header.Get("Authorization"), auth.SignatureScheme+" ") {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			principal, err := v.Verify(r, time.Now())
+			if errors.Is(err, auth.ErrSignatureMalformed) {
+				handlers.ERROR_AUTH_CANNOT_PARSE_TOKEN(w)
+
+				return
+			}
+			if errors.Is(err, auth.ErrNonceCacheFull) {
+				logger.FromContext(r.Context()).WithField("m", "SignatureMiddleware").Errorf("signature not checked: %v", err)
+				w.Header().Set("Retry-After", "1")
+				handlers.ERROR_SERVICE_UNAVAILABLE(w, "")
+
+				return
+			}
+			if err != nil {
+				logger.FromContext(r.Context()).WithField("m", "SignatureMiddleware").Infof("signature rejected: %v", err)
+				handlers.ERROR_AUTH_TOKEN_INVALID(w)
+
+				return
+			}
+
+			next.ServeHTTP(w, withPrincipal(r, principal))
+		})
+	}
+
+	return
+}
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE wallets (
+    id         SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    user_id    VARCHAR(64)  NOT NULL,
+    asset_id   VARCHAR(32)  NOT NULL,
+    address    VARCHAR(128) NOT NULL,
+    timestamp  TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_wallets_deleted_at ON wallets (deleted_at);
+CREATE INDEX idx_wallets_user_id ON wallets (user_id);
+CREATE INDEX idx_wallets_asset_id ON wallets (asset_id);
+CREATE INDEX idx_wallets_timestamp ON wallets (timestamp, id);
+CREATE UNIQUE INDEX idx_wallets_asset_address ON wallets (asset_id, address) WHERE deleted_at IS NULL;
+
+-- +goose Down
+DROP TABLE wallets;
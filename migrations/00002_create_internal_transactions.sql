@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE internal_transactions (
+    id         SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    chain      VARCHAR(32)  NOT NULL,
+    tx_id      VARCHAR(128) NOT NULL,
+    asset_id   VARCHAR(32)  NOT NULL,
+    user_id    VARCHAR(64)
+);
+
+CREATE INDEX idx_internal_transactions_deleted_at ON internal_transactions (deleted_at);
+CREATE UNIQUE INDEX idx_internal_transactions_chain_tx_id ON internal_transactions (chain, tx_id);
+
+-- +goose Down
+DROP TABLE internal_transactions;
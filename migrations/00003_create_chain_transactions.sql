@@ -0,0 +1,72 @@
+-- +goose Up
+CREATE TABLE btc_transactions (
+    id           SERIAL PRIMARY KEY,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at   TIMESTAMP WITH TIME ZONE,
+    chain        VARCHAR(32)  NOT NULL,
+    asset_id     VARCHAR(32)  NOT NULL,
+    user_id      VARCHAR(64)  NOT NULL,
+    tx_id        VARCHAR(128) NOT NULL,
+    vout         INTEGER      NOT NULL,
+    from_address VARCHAR(128),
+    to_address   VARCHAR(128),
+    amount       BIGINT       NOT NULL,
+    outgoing     BOOLEAN      NOT NULL,
+    timestamp    TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_btc_transactions_deleted_at ON btc_transactions (deleted_at);
+CREATE INDEX idx_btc_transactions_user_id ON btc_transactions (user_id);
+CREATE INDEX idx_btc_transactions_timestamp ON btc_transactions (timestamp, id);
+CREATE UNIQUE INDEX idx_btc_transactions_chain_tx_id_vout ON btc_transactions (chain, tx_id, vout);
+
+CREATE TABLE eth_transactions (
+    id           SERIAL PRIMARY KEY,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at   TIMESTAMP WITH TIME ZONE,
+    chain        VARCHAR(32)  NOT NULL,
+    asset_id     VARCHAR(32)  NOT NULL,
+    user_id      VARCHAR(64)  NOT NULL,
+    tx_id        VARCHAR(128) NOT NULL,
+    log_index    INTEGER      NOT NULL,
+    from_address VARCHAR(128),
+    to_address   VARCHAR(128),
+    amount       NUMERIC(78, 0) NOT NULL,
+    decimals     INTEGER      NOT NULL,
+    outgoing     BOOLEAN      NOT NULL,
+    timestamp    TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_eth_transactions_deleted_at ON eth_transactions (deleted_at);
+CREATE INDEX idx_eth_transactions_user_id ON eth_transactions (user_id);
+CREATE INDEX idx_eth_transactions_timestamp ON eth_transactions (timestamp, id);
+CREATE UNIQUE INDEX idx_eth_transactions_chain_tx_id_log_index ON eth_transactions (chain, tx_id, log_index);
+
+CREATE TABLE trx_transactions (
+    id           SERIAL PRIMARY KEY,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at   TIMESTAMP WITH TIME ZONE,
+    asset_id     VARCHAR(32)  NOT NULL,
+    user_id      VARCHAR(64)  NOT NULL,
+    tx_id        VARCHAR(128) NOT NULL,
+    log_index    INTEGER      NOT NULL,
+    from_address VARCHAR(128),
+    to_address   VARCHAR(128),
+    amount       NUMERIC(78, 0) NOT NULL,
+    decimals     INTEGER      NOT NULL,
+    outgoing     BOOLEAN      NOT NULL,
+    timestamp    TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_trx_transactions_deleted_at ON trx_transactions (deleted_at);
+CREATE INDEX idx_trx_transactions_user_id ON trx_transactions (user_id);
+CREATE INDEX idx_trx_transactions_timestamp ON trx_transactions (timestamp, id);
+CREATE UNIQUE INDEX idx_trx_transactions_tx_id_log_index ON trx_transactions (tx_id, log_index);
+
+-- +goose Down
+DROP TABLE trx_transactions;
+DROP TABLE eth_transactions;
+DROP TABLE btc_transactions;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE turnover_hourly (
+    bucket     TIMESTAMP WITH TIME ZONE NOT NULL,
+    chain      VARCHAR(32)    NOT NULL,
+    asset_id   VARCHAR(32)    NOT NULL,
+    user_id    VARCHAR(64)    NOT NULL,
+    internal   BOOLEAN        NOT NULL,
+    decimals   INTEGER        NOT NULL,
+    tx_count   BIGINT         NOT NULL,
+    amount     NUMERIC(78, 0) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (bucket, chain, asset_id, user_id, internal)
+);
+
+CREATE INDEX idx_turnover_hourly_asset_id_bucket ON turnover_hourly (asset_id, bucket);
+CREATE INDEX idx_turnover_hourly_user_id_bucket ON turnover_hourly (user_id, bucket);
+
+CREATE TABLE rollup_states (
+    name       VARCHAR(64) PRIMARY KEY,
+    until      TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE rollup_states;
+DROP TABLE turnover_hourly;
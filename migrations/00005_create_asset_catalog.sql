@@ -0,0 +1,46 @@
+-- +goose Up
+CREATE TABLE chains (
+    id         SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    name       VARCHAR(32) NOT NULL,
+    kind       VARCHAR(16) NOT NULL,
+    enabled    BOOLEAN     NOT NULL DEFAULT true
+);
+
+CREATE INDEX idx_chains_deleted_at ON chains (deleted_at);
+CREATE UNIQUE INDEX uix_chains_name ON chains (name) WHERE deleted_at IS NULL;
+
+CREATE TABLE assets (
+    id               SERIAL PRIMARY KEY,
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at       TIMESTAMP WITH TIME ZONE,
+    asset_id         VARCHAR(32)  NOT NULL,
+    symbol           VARCHAR(16)  NOT NULL,
+    chain            VARCHAR(32)  NOT NULL,
+    contract_address VARCHAR(128) NOT NULL DEFAULT '',
+    decimals         INTEGER      NOT NULL,
+    enabled          BOOLEAN      NOT NULL DEFAULT true
+);
+
+CREATE INDEX idx_assets_deleted_at ON assets (deleted_at);
+CREATE INDEX idx_assets_chain ON assets (chain);
+CREATE UNIQUE INDEX uix_assets_asset_id ON assets (asset_id) WHERE deleted_at IS NULL;
+
+INSERT INTO chains (name, kind) VALUES
+    ('BTC', 'btc'),
+    ('ETH', 'eth'),
+    ('TRX', 'trx');
+
+INSERT INTO assets (asset_id, symbol, chain, contract_address, decimals) VALUES
+    ('BTC', 'BTC', 'BTC', '', 8),
+    ('ETH', 'ETH', 'ETH', '', 18),
+    ('USDT_ERC20', 'USDT', 'ETH', '0xdac17f958d2ee523a2206206994597c13d831ec7', 6),
+    ('TRX', 'TRX', 'TRX', '', 6),
+    ('USDT_TRC20', 'USDT', 'TRX', 'TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t', 6);
+
+-- +goose Down
+DROP TABLE assets;
+DROP TABLE chains;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE audit_entries (
+    id         SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    actor      VARCHAR(128) NOT NULL,
+    action     VARCHAR(64)  NOT NULL,
+    target     VARCHAR(128) NOT NULL,
+    dry_run    BOOLEAN      NOT NULL DEFAULT false,
+    details    JSONB
+);
+
+CREATE INDEX idx_audit_entries_deleted_at ON audit_entries (deleted_at);
+CREATE INDEX idx_audit_entries_actor ON audit_entries (actor);
+CREATE INDEX idx_audit_entries_action ON audit_entries (action);
+
+-- +goose Down
+DROP TABLE audit_entries;
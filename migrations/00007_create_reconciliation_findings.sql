@@ -0,0 +1,27 @@
+-- +goose Up
+ALTER TABLE internal_transactions ADD COLUMN amount NUMERIC(78, 0);
+
+CREATE TABLE reconciliation_findings (
+    id          SERIAL PRIMARY KEY,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at  TIMESTAMP WITH TIME ZONE,
+    kind        VARCHAR(32)  NOT NULL,
+    chain       VARCHAR(32)  NOT NULL,
+    tx_id       VARCHAR(128) NOT NULL,
+    asset_id    VARCHAR(32)  NOT NULL,
+    user_id     VARCHAR(64),
+    status      VARCHAR(16)  NOT NULL DEFAULT 'open',
+    details     JSONB,
+    note        TEXT,
+    reviewed_by VARCHAR(128),
+    reviewed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_reconciliation_findings_deleted_at ON reconciliation_findings (deleted_at);
+CREATE INDEX idx_reconciliation_findings_status ON reconciliation_findings (status, kind);
+CREATE UNIQUE INDEX idx_reconciliation_findings_kind_chain_tx_id ON reconciliation_findings (kind, chain, tx_id);
+
+-- +goose Down
+DROP TABLE reconciliation_findings;
+ALTER TABLE internal_transactions DROP COLUMN amount;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- a transfer between two of our wallets is stored once as outgoing and once as incoming
+DROP INDEX idx_btc_transactions_chain_tx_id_vout;
+CREATE UNIQUE INDEX idx_btc_transactions_chain_tx_id_vout ON btc_transactions (chain, tx_id, vout, outgoing);
+CREATE INDEX idx_btc_transactions_tx_id ON btc_transactions (tx_id);
+
+DROP INDEX idx_eth_transactions_chain_tx_id_log_index;
+CREATE UNIQUE INDEX idx_eth_transactions_chain_tx_id_log_index ON eth_transactions (chain, tx_id, log_index, outgoing);
+
+DROP INDEX idx_trx_transactions_tx_id_log_index;
+CREATE UNIQUE INDEX idx_trx_transactions_tx_id_log_index ON trx_transactions (tx_id, log_index, outgoing);
+
+CREATE INDEX idx_wallets_address ON wallets (address);
+
+-- +goose Down
+DROP INDEX idx_wallets_address;
+
+DROP INDEX idx_trx_transactions_tx_id_log_index;
+CREATE UNIQUE INDEX idx_trx_transactions_tx_id_log_index ON trx_transactions (tx_id, log_index);
+
+DROP INDEX idx_eth_transactions_chain_tx_id_log_index;
+CREATE UNIQUE INDEX idx_eth_transactions_chain_tx_id_log_index ON eth_transactions (chain, tx_id, log_index);
+
+DROP INDEX idx_btc_transactions_tx_id;
+DROP INDEX idx_btc_transactions_chain_tx_id_vout;
+CREATE UNIQUE INDEX idx_btc_transactions_chain_tx_id_vout ON btc_transactions (chain, tx_id, vout);
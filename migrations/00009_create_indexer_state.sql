@@ -0,0 +1,39 @@
+-- +goose Up
+CREATE TABLE indexer_checkpoints (
+    chain      VARCHAR(32)  PRIMARY KEY,
+    height     BIGINT       NOT NULL,
+    hash       VARCHAR(128) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- recent blocks kept to find the fork point of a reorganization
+CREATE TABLE indexed_blocks (
+    chain       VARCHAR(32)  NOT NULL,
+    height      BIGINT       NOT NULL,
+    hash        VARCHAR(128) NOT NULL,
+    parent_hash VARCHAR(128) NOT NULL,
+    timestamp   TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (chain, height)
+);
+
+-- rows seen in the mempool have no block until the indexer confirms them
+ALTER TABLE btc_transactions ADD COLUMN block_height BIGINT, ADD COLUMN block_hash VARCHAR(128);
+ALTER TABLE eth_transactions ADD COLUMN block_height BIGINT, ADD COLUMN block_hash VARCHAR(128);
+ALTER TABLE trx_transactions ADD COLUMN block_height BIGINT, ADD COLUMN block_hash VARCHAR(128);
+
+CREATE INDEX idx_btc_transactions_chain_block_height ON btc_transactions (chain, block_height);
+CREATE INDEX idx_eth_transactions_chain_block_height ON eth_transactions (chain, block_height);
+CREATE INDEX idx_trx_transactions_block_height ON trx_transactions (block_height);
+
+-- +goose Down
+DROP INDEX idx_trx_transactions_block_height;
+DROP INDEX idx_eth_transactions_chain_block_height;
+DROP INDEX idx_btc_transactions_chain_block_height;
+
+ALTER TABLE trx_transactions DROP COLUMN block_height, DROP COLUMN block_hash;
+ALTER TABLE eth_transactions DROP COLUMN block_height, DROP COLUMN block_hash;
+ALTER TABLE btc_transactions DROP COLUMN block_height, DROP COLUMN block_hash;
+
+DROP TABLE indexed_blocks;
+DROP TABLE indexer_checkpoints;
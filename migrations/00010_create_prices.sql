@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE prices (
+    asset_id   VARCHAR(32)    NOT NULL,
+    fiat       VARCHAR(8)     NOT NULL,
+    timestamp  TIMESTAMP WITH TIME ZONE NOT NULL,
+    price      NUMERIC(38, 18) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (asset_id, fiat, timestamp)
+);
+
+-- +goose Down
+DROP TABLE prices;
@@ -0,0 +1,57 @@
+-- +goose Up
+CREATE TABLE alert_rules (
+    id               SERIAL PRIMARY KEY,
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at       TIMESTAMP WITH TIME ZONE,
+    name             VARCHAR(128) NOT NULL,
+    kind             VARCHAR(32)  NOT NULL,
+    asset_id         VARCHAR(32)  NOT NULL,
+    user_id          VARCHAR(64),
+    threshold        NUMERIC(78, 0) NOT NULL,
+    decimals         INTEGER      NOT NULL,
+    count            INTEGER      NOT NULL DEFAULT 0,
+    window_seconds   BIGINT       NOT NULL DEFAULT 0,
+    include_internal BOOLEAN      NOT NULL DEFAULT false,
+    severity         VARCHAR(16)  NOT NULL DEFAULT 'warning',
+    enabled          BOOLEAN      NOT NULL DEFAULT true
+);
+
+CREATE INDEX idx_alert_rules_deleted_at ON alert_rules (deleted_at);
+CREATE INDEX idx_alert_rules_asset_id ON alert_rules (asset_id);
+
+CREATE TABLE alerts (
+    id              SERIAL PRIMARY KEY,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at      TIMESTAMP WITH TIME ZONE,
+    rule_id         INTEGER      NOT NULL REFERENCES alert_rules (id),
+    rule_name       VARCHAR(128) NOT NULL,
+    kind            VARCHAR(32)  NOT NULL,
+    severity        VARCHAR(16)  NOT NULL,
+    key             VARCHAR(256) NOT NULL,
+    chain           VARCHAR(32)  NOT NULL,
+    asset_id        VARCHAR(32)  NOT NULL,
+    user_id         VARCHAR(64)  NOT NULL,
+    tx_id           VARCHAR(128),
+    amount          NUMERIC(78, 0) NOT NULL,
+    decimals        INTEGER      NOT NULL,
+    details         JSONB,
+    status          VARCHAR(16)  NOT NULL DEFAULT 'open',
+    note            TEXT,
+    acknowledged_by VARCHAR(128),
+    acknowledged_at TIMESTAMP WITH TIME ZONE,
+    notified_at     TIMESTAMP WITH TIME ZONE,
+    notify_attempts INTEGER      NOT NULL DEFAULT 0,
+    notify_error    TEXT
+);
+
+CREATE INDEX idx_alerts_deleted_at ON alerts (deleted_at);
+CREATE INDEX idx_alerts_status ON alerts (status, severity);
+CREATE INDEX idx_alerts_undelivered ON alerts (id) WHERE notified_at IS NULL;
+-- one alert per rule and transaction or window
+CREATE UNIQUE INDEX idx_alerts_rule_id_key ON alerts (rule_id, key);
+
+-- +goose Down
+DROP TABLE alerts;
+DROP TABLE alert_rules;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE api_clients (
+    id           SERIAL PRIMARY KEY,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at   TIMESTAMP WITH TIME ZONE,
+    name         VARCHAR(128) NOT NULL,
+    kind         VARCHAR(16)  NOT NULL,
+    -- public part of the token used to find the client, the token itself is only stored hashed
+    token_prefix VARCHAR(16)  NOT NULL,
+    token_hash   VARCHAR(64)  NOT NULL,
+    roles        TEXT[]       NOT NULL DEFAULT '{}',
+    permissions  TEXT[]       NOT NULL DEFAULT '{}',
+    expires_at   TIMESTAMP WITH TIME ZONE,
+    revoked_at   TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_api_clients_deleted_at ON api_clients (deleted_at);
+CREATE UNIQUE INDEX idx_api_clients_name ON api_clients (name) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_api_clients_token_prefix ON api_clients (token_prefix);
+
+-- +goose Down
+DROP TABLE api_clients;
@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE role_permissions (
+    role       VARCHAR(64)  NOT NULL,
+    permission VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (role, permission)
+);
+
+-- +goose Down
+DROP TABLE role_permissions;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE api_clients
+    -- token replaced by the last rotation, accepted until previous_expires_at
+    ADD COLUMN previous_token_prefix VARCHAR(16),
+    ADD COLUMN previous_token_hash   VARCHAR(64),
+    ADD COLUMN previous_expires_at   TIMESTAMP WITH TIME ZONE,
+    -- CIDR ranges the token is accepted from, any address if empty
+    ADD COLUMN allowed_ips           TEXT[]       NOT NULL DEFAULT '{}',
+    ADD COLUMN created_by            VARCHAR(160) NOT NULL DEFAULT '',
+    ADD COLUMN last_used_at          TIMESTAMP WITH TIME ZONE;
+
+CREATE UNIQUE INDEX idx_api_clients_previous_token_prefix ON api_clients (previous_token_prefix);
+
+-- +goose Down
+DROP INDEX idx_api_clients_previous_token_prefix;
+ALTER TABLE api_clients
+    DROP COLUMN previous_token_prefix,
+    DROP COLUMN previous_token_hash,
+    DROP COLUMN previous_expires_at,
+    DROP COLUMN allowed_ips,
+    DROP COLUMN created_by,
+    DROP COLUMN last_used_at;
@@ -0,0 +1,48 @@
+-- +goose Up
+CREATE TABLE admin_users (
+    id                  SERIAL PRIMARY KEY,
+    created_at          TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at          TIMESTAMP WITH TIME ZONE,
+    username            VARCHAR(64)  NOT NULL,
+    -- argon2id or bcrypt hash in its string format
+    password_hash       VARCHAR(255) NOT NULL,
+    roles               TEXT[]       NOT NULL DEFAULT '{}',
+    permissions         TEXT[]       NOT NULL DEFAULT '{}',
+    disabled            BOOLEAN      NOT NULL DEFAULT false,
+    -- consecutive failed logins, reset by a successful login or a lockout
+    failed_logins       INTEGER      NOT NULL DEFAULT 0,
+    locked_until        TIMESTAMP WITH TIME ZONE,
+    password_changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    last_login_at       TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_admin_users_deleted_at ON admin_users (deleted_at);
+CREATE UNIQUE INDEX idx_admin_users_username ON admin_users (username) WHERE deleted_at IS NULL;
+
+CREATE TABLE admin_sessions (
+    id                 SERIAL PRIMARY KEY,
+    created_at         TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at         TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    user_id            INTEGER      NOT NULL REFERENCES admin_users (id),
+    -- access and refresh tokens are only stored hashed, both are replaced on refresh
+    token_prefix       VARCHAR(16)  NOT NULL,
+    token_hash         VARCHAR(64)  NOT NULL,
+    expires_at         TIMESTAMP WITH TIME ZONE NOT NULL,
+    refresh_prefix     VARCHAR(16)  NOT NULL,
+    refresh_hash       VARCHAR(64)  NOT NULL,
+    refresh_expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    -- refreshes never extend a session past this time
+    max_expires_at     TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at         TIMESTAMP WITH TIME ZONE,
+    ip                 VARCHAR(64)  NOT NULL DEFAULT '',
+    user_agent         VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX idx_admin_sessions_token_prefix ON admin_sessions (token_prefix);
+CREATE UNIQUE INDEX idx_admin_sessions_refresh_prefix ON admin_sessions (refresh_prefix);
+CREATE INDEX idx_admin_sessions_user_id ON admin_sessions (user_id) WHERE revoked_at IS NULL;
+
+-- +goose Down
+DROP TABLE admin_sessions;
+DROP TABLE admin_users;
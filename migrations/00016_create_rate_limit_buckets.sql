@@ -0,0 +1,13 @@
+-- +goose Up
+-- token buckets shared by replicas when rate limiting runs in shared mode
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key        VARCHAR(255) PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    allowed    BOOLEAN NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
+
+-- +goose Down
+DROP TABLE rate_limit_buckets;
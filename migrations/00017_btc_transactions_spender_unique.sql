@@ -0,0 +1,8 @@
+-- +goose Up
+-- an output spent from wallets of several users is stored once per spender as outgoing
+DROP INDEX idx_btc_transactions_chain_tx_id_vout;
+CREATE UNIQUE INDEX idx_btc_transactions_chain_tx_id_vout ON btc_transactions (chain, tx_id, vout, outgoing, user_id);
+
+-- +goose Down
+DROP INDEX idx_btc_transactions_chain_tx_id_vout;
+CREATE UNIQUE INDEX idx_btc_transactions_chain_tx_id_vout ON btc_transactions (chain, tx_id, vout, outgoing);
@@ -0,0 +1,6 @@
+-- +goose Up
+-- notifiers that sent an alert, a retry skips them
+ALTER TABLE alerts ADD COLUMN notified_by TEXT[];
+
+-- +goose Down
+ALTER TABLE alerts DROP COLUMN notified_by;
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AdminUserKind is the principal kind of admin users logged in with a password
+const AdminUserKind = "admin_user"
+
+// AdminUser logs in with a password, only the hash of the password is stored
+//
+// swagger:model AdminUser
+type AdminUser struct {
+	DBModel
+	Username          string         `json:"username" gorm:"not null"`
+	PasswordHash      string         `json:"-" gorm:"not null"`
+	Roles             pq.StringArray `json:"roles" gorm:"type:text[]"`
+	Permissions       pq.StringArray `json:"permissions" gorm:"type:text[]"`
+	Disabled          bool           `json:"disabled"`
+	FailedLogins      int            `json:"failed_logins"`
+	LockedUntil       *time.Time     `json:"locked_until"`
+	PasswordChangedAt time.Time      `json:"password_changed_at"`
+	LastLoginAt       *time.Time     `json:"last_login_at"`
+}
+
+// Locked reports whether logins are refused at now after repeated failures
+func (u *AdminUser) Locked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// AdminSession is a login of an admin user, holding an access and a refresh token
+//
+// swagger:model AdminSession
+type AdminSession struct {
+	ID               uint       `json:"id" gorm:"primary_key"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"-"`
+	UserID           uint       `json:"user_id" gorm:"not null"`
+	TokenPrefix      string     `json:"-" gorm:"not null"`
+	TokenHash        string     `json:"-" gorm:"not null"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RefreshPrefix    string     `json:"-" gorm:"not null"`
+	RefreshHash      string     `json:"-" gorm:"not null"`
+	RefreshExpiresAt time.Time  `json:"refresh_expires_at"`
+	MaxExpiresAt     time.Time  `json:"max_expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	IP               string     `json:"ip" gorm:"column:ip"`
+	UserAgent        string     `json:"user_agent"`
+}
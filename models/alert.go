@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Alert rule kinds
+const (
+	// a single outgoing transfer of at least the threshold
+	AlertRuleTxAmount = "tx_amount"
+	// outgoing transfers of a user summing to at least the threshold within a window, a UTC day by default
+	AlertRulePeriodAmount = "period_amount"
+	// at least Count transfers of at most the threshold to addresses the user has not paid before,
+	// within a window, a UTC day by default
+	AlertRuleNewAddressBurst = "new_address_burst"
+)
+
+// Alert severities
+const (
+	AlertSeverityInfo     = "info"
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+// Alert statuses
+const (
+	AlertOpen         = "open"
+	AlertAcknowledged = "acknowledged"
+	AlertResolved     = "resolved"
+)
+
+// AlertRule is a condition on outgoing transfers of an asset, threshold is in whole units
+//
+// swagger:model AlertRule
+type AlertRule struct {
+	DBModel
+	Name    string `json:"name" gorm:"not null"`
+	Kind    string `json:"kind" gorm:"not null"`
+	AssetID string `json:"asset_id" gorm:"not null"`
+	// empty applies the rule to every user
+	UserID    string `json:"user_id"`
+	Threshold Amount `json:"threshold" gorm:"type:numeric(78,0);not null"`
+	Decimals  int    `json:"-" gorm:"not null"`
+	Count     int    `json:"count"`
+	// window of period rules in seconds, windows are aligned to the unix epoch
+	WindowSeconds   int64  `json:"window_seconds"`
+	IncludeInternal bool   `json:"include_internal"`
+	Severity        string `json:"severity" gorm:"not null"`
+	Enabled         bool   `json:"enabled"`
+}
+
+func (r *AlertRule) AfterFind() error {
+	r.Threshold = r.Threshold.WithDecimals(r.Decimals)
+	return nil
+}
+
+// Window returns the evaluation window of period rules
+func (r *AlertRule) Window() time.Duration {
+	if r.WindowSeconds <= 0 {
+		return 24 * time.Hour
+	}
+
+	return time.Duration(r.WindowSeconds) * time.Second
+}
+
+// Alert is a triggered rule. Key identifies the transaction or window the alert is raised for,
+// so a rule alerts once per transaction or window
+//
+// swagger:model Alert
+type Alert struct {
+	DBModel
+	RuleID         uint       `json:"rule_id" gorm:"not null"`
+	RuleName       string     `json:"rule_name" gorm:"not null"`
+	Kind           string     `json:"kind" gorm:"not null"`
+	Severity       string     `json:"severity" gorm:"not null"`
+	Key            string     `json:"-" gorm:"not null"`
+	Chain          string     `json:"chain" gorm:"not null"`
+	AssetID        string     `json:"asset_id" gorm:"not null"`
+	UserID         string     `json:"user_id" gorm:"not null"`
+	TxID           string     `json:"tx_id,omitempty"`
+	Amount         Amount     `json:"amount" gorm:"type:numeric(78,0);not null"`
+	Decimals       int        `json:"-" gorm:"not null"`
+	Details        string     `json:"details" gorm:"type:jsonb"`
+	Status         string     `json:"status" gorm:"not null"`
+	Note           string     `json:"note"`
+	AcknowledgedBy string     `json:"acknowledged_by"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	NotifiedAt     *time.Time `json:"notified_at"`
+	// NotifiedBy names the notifiers that sent the alert
+	NotifiedBy     pq.StringArray `json:"notified_by" gorm:"type:text[]"`
+	NotifyAttempts int            `json:"notify_attempts"`
+	NotifyError    string         `json:"notify_error,omitempty"`
+}
+
+func (a *Alert) AfterFind() error {
+	a.Amount = a.Amount.WithDecimals(a.Decimals)
+	return nil
+}
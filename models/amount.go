@@ -0,0 +1,268 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Amount is an exact quantity of an asset: an integer number of base units (satoshi, wei, sun)
+// and the number of decimals of the asset. It is stored as the integer of base units in a numeric
+// column and serialized to JSON as an exact decimal string of whole units, e.g. "0.00012"
+//
+// swagger:strfmt decimal
+type Amount struct {
+	units    *big.Int
+	decimals int
+}
+
+// NewAmount copies units, nil is zero
+func NewAmount(units *big.Int, decimals int) Amount {
+	a := Amount{units: new(big.Int), decimals: decimals}
+	if units != nil {
+		a.units.Set(units)
+	}
+
+	return a
+}
+
+func AmountFromInt64(units int64, decimals int) Amount {
+	return Amount{units: big.NewInt(units), decimals: decimals}
+}
+
+// ParseUnits parses an integer string of base units
+func ParseUnits(s string, decimals int) (Amount, error) {
+	v, ok := new(big.Int).SetString(strings.TrimSpace(s), 10)
+	if !ok {
+		return Amount{}, fmt.Errorf("invalid amount %q", s)
+	}
+
+	return Amount{units: v, decimals: decimals}, nil
+}
+
+// ParseAmount parses a decimal string of whole units, it fails if s has more fractional
+// digits than decimals
+func ParseAmount(s string, decimals int) (Amount, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		whole, frac = digits[:i], digits[i+1:]
+	}
+	frac = strings.TrimRight(frac, "0")
+	if (whole == "" && frac == "") || len(frac) > decimals || strings.ContainsAny(whole+frac, "+-.eE") {
+		return Amount{}, fmt.Errorf("invalid amount %q for %d decimals", s, decimals)
+	}
+
+	v, ok := new(big.Int).SetString("0"+whole+frac+strings.Repeat("0", decimals-len(frac)), 10)
+	if !ok {
+		return Amount{}, fmt.Errorf("invalid amount %q", s)
+	}
+	if neg {
+		v.Neg(v)
+	}
+
+	return Amount{units: v, decimals: decimals}, nil
+}
+
+func (a Amount) int() *big.Int {
+	if a.units == nil {
+		return new(big.Int)
+	}
+
+	return a.units
+}
+
+// Units returns a copy of the integer of base units
+func (a Amount) Units() *big.Int {
+	return new(big.Int).Set(a.int())
+}
+
+func (a Amount) Decimals() int {
+	return a.decimals
+}
+
+// WithDecimals sets the decimals of an amount read as base units
+func (a Amount) WithDecimals(decimals int) Amount {
+	return Amount{units: a.units, decimals: decimals}
+}
+
+// Rescale converts to more decimals, the value is unchanged
+func (a Amount) Rescale(decimals int) Amount {
+	if decimals <= a.decimals {
+		return a
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals-a.decimals)), nil)
+
+	return Amount{units: new(big.Int).Mul(a.int(), scale), decimals: decimals}
+}
+
+// align returns both amounts with the larger of their decimals
+func align(a, b Amount) (Amount, Amount) {
+	if a.decimals < b.decimals {
+		return a.Rescale(b.decimals), b
+	}
+
+	return a, b.Rescale(a.decimals)
+}
+
+func (a Amount) Add(b Amount) Amount {
+	a, b = align(a, b)
+
+	return Amount{units: new(big.Int).Add(a.int(), b.int()), decimals: a.decimals}
+}
+
+func (a Amount) Sub(b Amount) Amount {
+	a, b = align(a, b)
+
+	return Amount{units: new(big.Int).Sub(a.int(), b.int()), decimals: a.decimals}
+}
+
+func (a Amount) Neg() Amount {
+	return Amount{units: new(big.Int).Neg(a.int()), decimals: a.decimals}
+}
+
+// Cmp compares values regardless of decimals, it returns -1, 0 or +1
+func (a Amount) Cmp(b Amount) int {
+	a, b = align(a, b)
+
+	return a.int().Cmp(b.int())
+}
+
+func (a Amount) Sign() int {
+	return a.int().Sign()
+}
+
+func (a Amount) IsZero() bool {
+	return a.Sign() == 0
+}
+
+// SumAmounts adds amounts, the sum of none is zero with the given decimals
+func SumAmounts(decimals int, amounts ...Amount) Amount {
+	sum := Amount{units: new(big.Int), decimals: decimals}
+	for _, a := range amounts {
+		sum = sum.Add(a)
+	}
+
+	return sum
+}
+
+// Rat returns the value in whole units
+func (a Amount) Rat() *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(a.decimals)), nil)
+
+	return new(big.Rat).SetFrac(a.int(), scale)
+}
+
+// MulRat multiplies the value by r, e.g. a price, and rounds half away from zero to decimals
+func (a Amount) MulRat(r *big.Rat, decimals int) Amount {
+	v := new(big.Rat).Mul(a.Rat(), r)
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	v.Mul(v, new(big.Rat).SetInt(scale))
+
+	return Amount{units: roundRat(v), decimals: decimals}
+}
+
+// AmountFromRat rounds a value of whole units half away from zero to decimals
+func AmountFromRat(r *big.Rat, decimals int) Amount {
+	return AmountFromInt64(1, 0).MulRat(r, decimals)
+}
+
+func roundRat(v *big.Rat) *big.Int {
+	num := new(big.Int).Abs(v.Num())
+	q, m := new(big.Int).QuoRem(num, v.Denom(), new(big.Int))
+	if m.Mul(m, big.NewInt(2)).Cmp(v.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if v.Sign() < 0 {
+		q.Neg(q)
+	}
+
+	return q
+}
+
+// String formats whole units without trailing zeros
+func (a Amount) String() string {
+	s := new(big.Int).Abs(a.int()).String()
+	if a.decimals > 0 {
+		if len(s) <= a.decimals {
+			s = strings.Repeat("0", a.decimals-len(s)+1) + s
+		}
+		s = s[:len(s)-a.decimals] + "." + strings.TrimRight(s[len(s)-a.decimals:], "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if a.Sign() < 0 {
+		s = "-" + s
+	}
+
+	return s
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON accepts a string or a number of whole units, decimals are taken from
+// the fractional digits
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n json.Number
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("invalid amount %s", b)
+		}
+		s = n.String()
+	}
+
+	decimals := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		decimals = len(strings.TrimRight(s[i+1:], "0"))
+	}
+	v, err := ParseAmount(s, decimals)
+	if err != nil {
+		return err
+	}
+	*a = v
+
+	return nil
+}
+
+// Value stores the integer of base units
+func (a Amount) Value() (driver.Value, error) {
+	return a.int().String(), nil
+}
+
+// Scan reads an integer of base units, decimals have to be set with WithDecimals
+func (a *Amount) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*a = Amount{units: new(big.Int), decimals: a.decimals}
+		return nil
+	case int64:
+		*a = Amount{units: big.NewInt(v), decimals: a.decimals}
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("cannot scan %T into Amount", src)
+	}
+
+	// sums of numeric columns may carry a zero fraction
+	if i := strings.IndexByte(s, '.'); i >= 0 && strings.Trim(s[i+1:], "0") == "" {
+		s = s[:i]
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return fmt.Errorf("cannot scan %q into Amount", s)
+	}
+	*a = Amount{units: v, decimals: a.decimals}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestMulRat(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals int
+		rate     string
+		want     string
+	}{
+		{"1", 8, "40000", "40000"},
+		{"0.5", 8, "40000.125", "20000.06"},
+		// halves round away from zero
+		{"0.00000001", 8, "500000", "0.01"},
+		{"0.00000001", 8, "499999", "0"},
+		{"0.00000003", 8, "500000", "0.02"},
+		{"-0.00000003", 8, "500000", "-0.02"},
+		{"-0.00000001", 8, "499999", "0"},
+		{"1.000000000000000001", 18, "0.005", "0.01"},
+		{"0", 8, "40000", "0"},
+	}
+	for _, tt := range tests {
+		a, err := ParseAmount(tt.amount, tt.decimals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rate, _ := new(big.Rat).SetString(tt.rate)
+		got := a.MulRat(rate, FiatDecimals)
+		if got.String() != tt.want {
+			t.Errorf("%v * %v: %v, want %v", tt.amount, tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestRoundRat(t *testing.T) {
+	tests := []struct {
+		v    string
+		want int64
+	}{
+		{"0", 0},
+		{"1/2", 1},
+		{"-1/2", -1},
+		{"49/100", 0},
+		{"-49/100", 0},
+		{"51/100", 1},
+		{"3/2", 2},
+		{"-5/2", -3},
+		{"7", 7},
+	}
+	for _, tt := range tests {
+		v, _ := new(big.Rat).SetString(tt.v)
+		if got := roundRat(v); got.Int64() != tt.want {
+			t.Errorf("%v: %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"net"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// API client kinds
+const (
+	APIClientAdmin   = "admin"
+	APIClientService = "service"
+)
+
+// APIClient is a caller authenticated by a bearer token, only the hash of the token is stored
+//
+// swagger:model APIClient
+type APIClient struct {
+	DBModel
+	Name        string         `json:"name" gorm:"not null"`
+	Kind        string         `json:"kind" gorm:"not null"`
+	TokenPrefix string         `json:"token_prefix" gorm:"not null"`
+	TokenHash   string         `json:"-" gorm:"not null"`
+	Roles       pq.StringArray `json:"roles" gorm:"type:text[]"`
+	Permissions pq.StringArray `json:"permissions" gorm:"type:text[]"`
+	// CIDR ranges the token is accepted from, any address if empty
+	AllowedIPs pq.StringArray `json:"allowed_ips" gorm:"column:allowed_ips;type:text[]"`
+	ExpiresAt  *time.Time     `json:"expires_at"`
+	RevokedAt  *time.Time     `json:"revoked_at"`
+	CreatedBy  string         `json:"created_by"`
+	LastUsedAt *time.Time     `json:"last_used_at"`
+
+	// token replaced by the last rotation, accepted until PreviousExpiresAt
+	PreviousTokenPrefix *string    `json:"previous_token_prefix"`
+	PreviousTokenHash   *string    `json:"-"`
+	PreviousExpiresAt   *time.Time `json:"previous_expires_at"`
+}
+
+// Expired reports whether the token expired at now
+func (c *APIClient) Expired(now time.Time) bool {
+	return c.ExpiresAt != nil && !now.Before(*c.ExpiresAt)
+}
+
+// TokenHashFor returns the stored hash of the token with the prefix, the previous token
+// is only matched until its overlap window ends
+func (c *APIClient) TokenHashFor(prefix string, now time.Time) string {
+	if prefix == c.TokenPrefix {
+		return c.TokenHash
+	}
+	if c.PreviousTokenPrefix != nil && *c.PreviousTokenPrefix == prefix && c.PreviousTokenHash != nil &&
+		c.PreviousExpiresAt != nil && now.Before(*c.PreviousExpiresAt) {
+		return *c.PreviousTokenHash
+	}
+
+	return ""
+}
+
+// AllowsIP reports whether the token is accepted from ip
+func (c *APIClient) AllowsIP(ip net.IP) bool {
+	if len(c.AllowedIPs) == 0 {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+
+	for _, cidr := range c.AllowedIPs {
+		if _, n, err := net.ParseCIDR(cidr); err == nil && n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAPIClientTokenHashFor(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	later := now.Add(time.Hour)
+	prevPrefix, prevHash := "prev0001", "prevhash"
+	c := &APIClient{
+		TokenPrefix:         "curr0001",
+		TokenHash:           "currhash",
+		PreviousTokenPrefix: &prevPrefix,
+		PreviousTokenHash:   &prevHash,
+		PreviousExpiresAt:   &later,
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		at     time.Time
+		want   string
+	}{
+		{"current", "curr0001", now, "currhash"},
+		{"current after the overlap", "curr0001", later.Add(time.Hour), "currhash"},
+		{"previous in the overlap", "prev0001", now, "prevhash"},
+		{"previous at its expiry", "prev0001", later, ""},
+		{"previous after the overlap", "prev0001", later.Add(time.Second), ""},
+		{"unknown", "othr0001", now, ""},
+		{"empty", "", now, ""},
+	}
+	for _, tt := range tests {
+		if got := c.TokenHashFor(tt.prefix, tt.at); got != tt.want {
+			t.Errorf("%v: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// a rotation without an overlap window
+	c.PreviousExpiresAt = nil
+	if got := c.TokenHashFor("prev0001", now); got != "" {
+		t.Errorf("previous without expiry: %q", got)
+	}
+}
+
+func TestAPIClientExpired(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		expires *time.Time
+		want    bool
+	}{
+		{nil, false},
+		{at(time.Second), false},
+		{at(0), true},
+		{at(-time.Second), true},
+	}
+	for _, tt := range tests {
+		c := &APIClient{ExpiresAt: tt.expires}
+		if got := c.Expired(now); got != tt.want {
+			t.Errorf("expires %v: %v, want %v", tt.expires, got, tt.want)
+		}
+	}
+}
+
+func TestAPIClientAllowsIP(t *testing.T) {
+	tests := []struct {
+		allowed []string
+		ip      string
+		want    bool
+	}{
+		{nil, "203.0.113.7", true},
+		{nil, "", true},
+		{[]string{"203.0.113.0/24"}, "203.0.113.7", true},
+		{[]string{"203.0.113.0/24"}, "203.0.114.7", false},
+		{[]string{"203.0.113.7/32"}, "203.0.113.7", true},
+		{[]string{"203.0.113.0/24"}, "", false},
+		// IPv4-mapped IPv6 addresses match IPv4 ranges
+		{[]string{"203.0.113.0/24"}, "::ffff:203.0.113.7", true},
+		{[]string{"2001:db8::/32"}, "2001:db8:1::1", true},
+		{[]string{"2001:db8::/32"}, "2001:db9::1", false},
+		{[]string{"2001:db8::/32"}, "203.0.113.7", false},
+		{[]string{"10.0.0.0/8", "2001:db8::/32"}, "2001:db8::1", true},
+		{[]string{"not a cidr", "10.0.0.0/8"}, "10.1.2.3", true},
+		{[]string{"not a cidr"}, "10.1.2.3", false},
+	}
+	for _, tt := range tests {
+		c := &APIClient{AllowedIPs: tt.allowed}
+		if got := c.AllowsIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("%v from %q: %v, want %v", tt.allowed, tt.ip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+// Chain families, chains of one family share address format and transaction table
+const (
+	ChainKindBTC = "btc"
+	ChainKindETH = "eth"
+	ChainKindTRX = "trx"
+)
+
+// Chain is a blockchain network
+//
+// swagger:model Chain
+type Chain struct {
+	DBModel
+	Name    string `json:"name" gorm:"unique_index;not null"`
+	Kind    string `json:"kind" gorm:"not null"`
+	Enabled bool   `json:"enabled" gorm:"not null"`
+}
+
+// Asset is a coin or token on a chain
+//
+// swagger:model Asset
+type Asset struct {
+	DBModel
+	AssetID         string `json:"asset_id" gorm:"unique_index;not null"`
+	Symbol          string `json:"symbol" gorm:"not null"`
+	Chain           string `json:"chain" gorm:"index;not null"`
+	ContractAddress string `json:"contract_address"`
+	Decimals        int    `json:"decimals" gorm:"not null"`
+	Enabled         bool   `json:"enabled" gorm:"not null"`
+}
+
+// Native reports whether the asset is the chain's own coin
+func (a *Asset) Native() bool {
+	return a.ContractAddress == ""
+}
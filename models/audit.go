@@ -0,0 +1,13 @@
+package models
+
+// AuditEntry records an administrative action
+//
+// swagger:model AuditEntry
+type AuditEntry struct {
+	DBModel
+	Actor   string `json:"actor" gorm:"index;not null"`
+	Action  string `json:"action" gorm:"index;not null"`
+	Target  string `json:"target" gorm:"not null"`
+	DryRun  bool   `json:"dry_run" gorm:"not null"`
+	Details string `json:"details" gorm:"type:jsonb"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// IndexerCheckpoint is the last block of a chain whose transfers are stored
+type IndexerCheckpoint struct {
+	Chain     string `gorm:"primary_key"`
+	Height    int64
+	Hash      string
+	UpdatedAt time.Time
+}
+
+// IndexedBlock is a recently indexed block, kept to detect reorganizations
+type IndexedBlock struct {
+	Chain      string `gorm:"primary_key"`
+	Height     int64  `gorm:"primary_key"`
+	Hash       string
+	ParentHash string
+	Timestamp  time.Time
+	CreatedAt  time.Time
+}
+
+// IndexerStatus is the progress of a chain indexer
+//
+// swagger:model IndexerStatus
+type IndexerStatus struct {
+	Chain       string     `json:"chain"`
+	Running     bool       `json:"running"`
+	Height      int64      `json:"height"`
+	Hash        string     `json:"hash"`
+	Tip         int64      `json:"tip,omitempty"`
+	Lag         int64      `json:"lag"`
+	Reorgs      int64      `json:"reorgs"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// InternalTransaction is a transaction created by our wallet and reported by the client
+//
+// swagger:model InternalTransaction
+type InternalTransaction struct {
+	DBModel
+	Chain   string `json:"chain" gorm:"unique_index:idx_internal_transactions_chain_tx_id;not null"`
+	TxID    string `json:"tx_id" gorm:"unique_index:idx_internal_transactions_chain_tx_id;not null"`
+	AssetID string `json:"asset_id" gorm:"not null"`
+	UserID  string `json:"user_id"`
+	// Amount sent in base units, optional
+	Amount *string `json:"amount,omitempty" gorm:"type:numeric(78,0)"`
+}
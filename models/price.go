@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Price is the price of one whole unit of an asset in a fiat currency from timestamp on
+//
+// swagger:model Price
+type Price struct {
+	AssetID   string    `json:"asset_id" gorm:"primary_key"`
+	Fiat      string    `json:"fiat" gorm:"primary_key"`
+	Timestamp time.Time `json:"timestamp" gorm:"primary_key"`
+	Price     string    `json:"price" gorm:"type:numeric(38,18);not null"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// FiatDecimals is the precision of fiat valuations
+const FiatDecimals = 2
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Reconciliation finding kinds
+const (
+	// registry entry never seen in chain transaction tables
+	FindingMissingOnChain = "missing_on_chain"
+	// outgoing transfer between our own wallets missing from the registry
+	FindingMissingInRegistry = "missing_in_registry"
+	// registry amount differs from the outgoing amount on chain
+	FindingAmountMismatch = "amount_mismatch"
+)
+
+// Reconciliation finding statuses
+const (
+	FindingOpen     = "open"
+	FindingResolved = "resolved"
+	FindingIgnored  = "ignored"
+)
+
+// ReconciliationFinding is a discrepancy between the internal transaction registry and chain transactions
+//
+// swagger:model ReconciliationFinding
+type ReconciliationFinding struct {
+	DBModel
+	Kind       string     `json:"kind" gorm:"not null"`
+	Chain      string     `json:"chain" gorm:"not null"`
+	TxID       string     `json:"tx_id" gorm:"not null"`
+	AssetID    string     `json:"asset_id" gorm:"not null"`
+	UserID     string     `json:"user_id"`
+	Status     string     `json:"status" gorm:"not null"`
+	Details    string     `json:"details" gorm:"type:jsonb"`
+	Note       string     `json:"note"`
+	ReviewedBy string     `json:"reviewed_by"`
+	ReviewedAt *time.Time `json:"reviewed_at"`
+}
+
+// FindingsSummary is a number of findings of a kind in a status
+//
+// swagger:model FindingsSummary
+type FindingsSummary struct {
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// RolePermission grants a permission to holders of a role
+//
+// swagger:model RolePermission
+type RolePermission struct {
+	Role       string    `json:"role" gorm:"primary_key"`
+	Permission string    `json:"permission" gorm:"primary_key"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Role lists the permissions of a role
+//
+// swagger:model Role
+type Role struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
@@ -0,0 +1,132 @@
+package models
+
+import "time"
+
+// BTCDecimals is the number of decimals of bitcoin-like amounts
+const BTCDecimals = 8
+
+const (
+	ChainBTC = "BTC"
+	ChainETH = "ETH"
+	ChainTRX = "TRX"
+)
+
+// chain transaction tables
+const (
+	BTCTransactionsTable = "btc_transactions"
+	ETHTransactionsTable = "eth_transactions"
+	TRXTransactionsTable = "trx_transactions"
+)
+
+// BTCTransaction is a transaction output on a bitcoin-like chain, amount is stored in satoshi
+//
+// swagger:model BTCTransaction
+type BTCTransaction struct {
+	DBModel
+	Chain       string    `json:"chain" gorm:"not null"`
+	AssetID     string    `json:"asset_id" gorm:"not null"`
+	UserID      string    `json:"user_id" gorm:"index;not null"`
+	TxID        string    `json:"tx_id" gorm:"not null"`
+	Vout        int       `json:"vout" gorm:"not null"`
+	FromAddress string    `json:"from_address"`
+	ToAddress   string    `json:"to_address"`
+	Amount      Amount    `json:"amount" gorm:"type:bigint;not null"`
+	Outgoing    bool      `json:"outgoing" gorm:"not null"`
+	Timestamp   time.Time `json:"timestamp" gorm:"index;not null"`
+	BlockHeight *int64    `json:"block_height,omitempty"`
+	BlockHash   *string   `json:"block_hash,omitempty"`
+}
+
+func (BTCTransaction) TableName() string { return BTCTransactionsTable }
+
+func (t *BTCTransaction) AfterFind() error {
+	t.Amount = t.Amount.WithDecimals(BTCDecimals)
+	return nil
+}
+
+// ETHTransaction is a transfer on an ethereum-like chain (native coin or token),
+// amount is stored in base units, log index is -1 for native transfers
+//
+// swagger:model ETHTransaction
+type ETHTransaction struct {
+	DBModel
+	Chain       string    `json:"chain" gorm:"not null"`
+	AssetID     string    `json:"asset_id" gorm:"not null"`
+	UserID      string    `json:"user_id" gorm:"index;not null"`
+	TxID        string    `json:"tx_id" gorm:"not null"`
+	LogIndex    int       `json:"log_index" gorm:"not null"`
+	FromAddress string    `json:"from_address"`
+	ToAddress   string    `json:"to_address"`
+	Amount      Amount    `json:"amount" gorm:"type:numeric(78,0);not null"`
+	Decimals    int       `json:"decimals" gorm:"not null"`
+	Outgoing    bool      `json:"outgoing" gorm:"not null"`
+	Timestamp   time.Time `json:"timestamp" gorm:"index;not null"`
+	BlockHeight *int64    `json:"block_height,omitempty"`
+	BlockHash   *string   `json:"block_hash,omitempty"`
+}
+
+func (ETHTransaction) TableName() string { return ETHTransactionsTable }
+
+func (t *ETHTransaction) AfterFind() error {
+	t.Amount = t.Amount.WithDecimals(t.Decimals)
+	return nil
+}
+
+// TRXTransaction is a transfer on TRON (TRX or TRC10/TRC20 token), amount is stored in base units,
+// log index is -1 for native transfers
+//
+// swagger:model TRXTransaction
+type TRXTransaction struct {
+	DBModel
+	AssetID     string    `json:"asset_id" gorm:"not null"`
+	UserID      string    `json:"user_id" gorm:"index;not null"`
+	TxID        string    `json:"tx_id" gorm:"not null"`
+	LogIndex    int       `json:"log_index" gorm:"not null"`
+	FromAddress string    `json:"from_address"`
+	ToAddress   string    `json:"to_address"`
+	Amount      Amount    `json:"amount" gorm:"type:numeric(78,0);not null"`
+	Decimals    int       `json:"decimals" gorm:"not null"`
+	Outgoing    bool      `json:"outgoing" gorm:"not null"`
+	Timestamp   time.Time `json:"timestamp" gorm:"index;not null"`
+	BlockHeight *int64    `json:"block_height,omitempty"`
+	BlockHash   *string   `json:"block_hash,omitempty"`
+}
+
+func (TRXTransaction) TableName() string { return TRXTransactionsTable }
+
+func (t *TRXTransaction) AfterFind() error {
+	t.Amount = t.Amount.WithDecimals(t.Decimals)
+	return nil
+}
+
+// TransactionsTable returns the transaction table of a chain family
+func TransactionsTable(kind string) string {
+	switch kind {
+	case ChainKindBTC:
+		return BTCTransactionsTable
+	case ChainKindETH:
+		return ETHTransactionsTable
+	case ChainKindTRX:
+		return TRXTransactionsTable
+	}
+
+	return ""
+}
+
+// OutgoingTransaction is a row of the unified outgoing transactions view
+//
+// swagger:model OutgoingTransaction
+type OutgoingTransaction struct {
+	Chain     string    `json:"chain"`
+	UserID    string    `json:"user_id"`
+	AssetID   string    `json:"asset_id"`
+	Amount    Amount    `json:"amount"`
+	TxID      string    `json:"tx_id"`
+	Internal  bool      `json:"internal"`
+	Timestamp time.Time `json:"timestamp"`
+	// set when a fiat valuation is requested, the amount is missing without a price
+	Fiat        string     `json:"fiat,omitempty"`
+	FiatAmount  *Amount    `json:"fiat_amount,omitempty"`
+	FiatPrice   string     `json:"fiat_price,omitempty"`
+	FiatPriceAt *time.Time `json:"fiat_price_at,omitempty"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+const TurnoverHourlyTable = "turnover_hourly"
+
+// TurnoverHourly is an hourly rollup of outgoing transactions, amount is stored in base units
+type TurnoverHourly struct {
+	Bucket    time.Time `gorm:"primary_key"`
+	Chain     string    `gorm:"primary_key"`
+	AssetID   string    `gorm:"primary_key"`
+	UserID    string    `gorm:"primary_key"`
+	Internal  bool      `gorm:"primary_key"`
+	Decimals  int
+	TxCount   int64
+	Amount    Amount `gorm:"type:numeric(78,0)"`
+	UpdatedAt time.Time
+}
+
+func (TurnoverHourly) TableName() string { return TurnoverHourlyTable }
+
+func (t *TurnoverHourly) AfterFind() error {
+	t.Amount = t.Amount.WithDecimals(t.Decimals)
+	return nil
+}
+
+// RollupState stores the point up to which a rollup has been refreshed
+type RollupState struct {
+	Name      string `gorm:"primary_key"`
+	Until     time.Time
+	UpdatedAt time.Time
+}
+
+// Turnover is an aggregated outgoing volume for a period
+//
+// swagger:model Turnover
+type Turnover struct {
+	Period         time.Time `json:"period"`
+	AssetID        string    `json:"asset_id"`
+	Chain          string    `json:"chain,omitempty"`
+	UserID         string    `json:"user_id,omitempty"`
+	TxCount        int64     `json:"tx_count"`
+	Amount         Amount    `json:"amount"`
+	InternalCount  int64     `json:"internal_count"`
+	InternalAmount Amount    `json:"internal_amount"`
+	ExternalCount  int64     `json:"external_count"`
+	ExternalAmount Amount    `json:"external_amount"`
+	// set when a fiat valuation is requested, amounts are missing if an hour of the period has no price
+	Fiat               string  `json:"fiat,omitempty"`
+	FiatAmount         *Amount `json:"fiat_amount,omitempty"`
+	FiatInternalAmount *Amount `json:"fiat_internal_amount,omitempty"`
+	FiatExternalAmount *Amount `json:"fiat_external_amount,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Wallet is a user's wallet for a single asset
+//
+// swagger:model Wallet
+type Wallet struct {
+	DBModel
+	UserID    string    `json:"user_id" gorm:"index;not null"`
+	AssetID   string    `json:"asset_id" gorm:"index;not null"`
+	Address   string    `json:"wallet" gorm:"not null"`
+	Timestamp time.Time `json:"timestamp" gorm:"index;not null"`
+}
@@ -0,0 +1,190 @@
+package bitcoind
+
+import (
+	"context"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+
+	"service_template/btc"
+	"service_template/nodes/rpc"
+)
+
+// bitcoind error codes, see rpc/protocol.h
+const (
+	codeInvalidAddressOrKey = -5
+	codeInvalidParameter    = -8
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Client talks to a bitcoind compatible node (bitcoind, litecoind, dogecoind)
+type Client struct {
+	rpc *rpc.Client
+}
+
+// New creates a client for the node RPC url, e.g. http://127.0.0.1:8332
+func New(url string, opts ...rpc.Option) *Client {
+	opts = append([]rpc.Option{rpc.WithVersion(rpc.Version1)}, opts...)
+
+	return &Client{rpc: rpc.New(url, opts...)}
+}
+
+// RPC exposes the underlying client for calls without a typed wrapper
+func (c *Client) RPC() *rpc.Client {
+	return c.rpc
+}
+
+type BlockHeader struct {
+	Hash              string `json:"hash"`
+	PreviousBlockHash string `json:"previousblockhash"`
+	Height            int64  `json:"height"`
+	Time              int64  `json:"time"`
+	Confirmations     int64  `json:"confirmations"`
+}
+
+// BlockTx is a transaction of a block fetched with verbosity 2
+type BlockTx struct {
+	TxID string `json:"txid"`
+	Hex  string `json:"hex"`
+}
+
+// Decode parses the raw transaction
+func (t BlockTx) Decode() (*btc.Tx, error) {
+	raw, err := hex.DecodeString(t.Hex)
+	if err != nil {
+		return nil, errors.Wrapf(btc.ErrMalformedTx, "tx %v: %v", t.TxID, err)
+	}
+
+	return btc.DecodeTx(raw)
+}
+
+type Block struct {
+	BlockHeader
+	Tx []BlockTx `json:"tx"`
+}
+
+type BlockchainInfo struct {
+	Chain         string `json:"chain"`
+	Blocks        int64  `json:"blocks"`
+	Headers       int64  `json:"headers"`
+	BestBlockHash string `json:"bestblockhash"`
+	InitialBlock  bool   `json:"initialblockdownload"`
+}
+
+func (c *Client) GetBlockchainInfo(ctx context.Context) (*BlockchainInfo, error) {
+	var info BlockchainInfo
+	if err := c.rpc.Call(ctx, &info, "getblockchaininfo"); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+func (c *Client) GetBlockCount(ctx context.Context) (int64, error) {
+	var n int64
+	err := c.rpc.Call(ctx, &n, "getblockcount")
+
+	return n, err
+}
+
+func (c *Client) GetBestBlockHash(ctx context.Context) (string, error) {
+	var hash string
+	err := c.rpc.Call(ctx, &hash, "getbestblockhash")
+
+	return hash, err
+}
+
+// GetBlockHash returns the hash of the main chain block at height, ErrNotFound above the tip
+func (c *Client) GetBlockHash(ctx context.Context, height int64) (string, error) {
+	var hash string
+	err := c.rpc.Call(ctx, &hash, "getblockhash", height)
+
+	return hash, notFound(err)
+}
+
+// GetBlockHashes fetches hashes of consecutive heights in one batch, stopping at the tip
+func (c *Client) GetBlockHashes(ctx context.Context, from int64, count int) ([]string, error) {
+	hashes := make([]string, count)
+	batch := make([]rpc.BatchElem, count)
+	for i := range batch {
+		batch[i] = rpc.BatchElem{
+			Method: "getblockhash",
+			Params: []interface{}{from + int64(i)},
+			Result: &hashes[i],
+		}
+	}
+
+	if err := c.rpc.BatchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+	for i, e := range batch {
+		if err := notFound(e.Error); err != nil {
+			if err == ErrNotFound {
+				return hashes[:i], nil
+			}
+			return nil, err
+		}
+	}
+
+	return hashes, nil
+}
+
+func (c *Client) GetBlockHeader(ctx context.Context, hash string) (*BlockHeader, error) {
+	var h BlockHeader
+	if err := c.rpc.Call(ctx, &h, "getblockheader", hash, true); err != nil {
+		return nil, notFound(err)
+	}
+
+	return &h, nil
+}
+
+// GetBlock returns the block with its raw transactions
+func (c *Client) GetBlock(ctx context.Context, hash string) (*Block, error) {
+	var b Block
+	if err := c.rpc.Call(ctx, &b, "getblock", hash, 2); err != nil {
+		return nil, notFound(err)
+	}
+
+	return &b, nil
+}
+
+// GetBlocks fetches blocks by hash in one batch
+func (c *Client) GetBlocks(ctx context.Context, hashes []string) ([]*Block, error) {
+	blocks := make([]*Block, len(hashes))
+	batch := make([]rpc.BatchElem, len(hashes))
+	for i, h := range hashes {
+		blocks[i] = new(Block)
+		batch[i] = rpc.BatchElem{Method: "getblock", Params: []interface{}{h, 2}, Result: blocks[i]}
+	}
+
+	if err := c.rpc.BatchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+	for _, e := range batch {
+		if e.Error != nil {
+			return nil, notFound(e.Error)
+		}
+	}
+
+	return blocks, nil
+}
+
+// GetRawTransaction returns a transaction, it requires txindex or the tx in the mempool
+func (c *Client) GetRawTransaction(ctx context.Context, txID string) (*btc.Tx, error) {
+	var s string
+	if err := c.rpc.Call(ctx, &s, "getrawtransaction", txID, false); err != nil {
+		return nil, notFound(err)
+	}
+
+	return BlockTx{TxID: txID, Hex: s}.Decode()
+}
+
+func notFound(err error) error {
+	if e, ok := err.(*rpc.Error); ok &&
+		(e.Code == codeInvalidAddressOrKey || e.Code == codeInvalidParameter) {
+		return ErrNotFound
+	}
+
+	return err
+}
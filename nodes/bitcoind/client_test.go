@@ -0,0 +1,104 @@
+package bitcoind_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"service_template/btc"
+	"service_template/nodes/bitcoind"
+	"service_template/nodes/nodetest"
+	"service_template/nodes/rpc"
+)
+
+func p2pkh(b byte) []byte {
+	return append(append([]byte{0x76, 0xa9, 0x14}, make([]byte, 19)...), b, 0x88, 0xac)
+}
+
+func TestClient(t *testing.T) {
+	node := nodetest.NewBitcoind("user", "secret")
+	defer node.Close()
+	ctx := context.Background()
+	c := bitcoind.New(node.URL, rpc.WithBasicAuth("user", "secret"))
+
+	prev := btc.OutPoint{TxID: strings.Repeat("ab", 32), Vout: 1}
+	raw := nodetest.BTCTx([]btc.OutPoint{prev}, []btc.TxOut{{Value: 1000, Script: p2pkh(1)}})
+	hash1 := node.Mine(raw)
+	hash2 := node.Mine()
+
+	info, err := c.GetBlockchainInfo(ctx)
+	if err != nil || info.Blocks != 2 || info.BestBlockHash != hash2 {
+		t.Fatalf("info %+v, %v", info, err)
+	}
+	if n, err := c.GetBlockCount(ctx); err != nil || n != 2 {
+		t.Errorf("count %v, %v", n, err)
+	}
+	if h, err := c.GetBestBlockHash(ctx); err != nil || h != hash2 {
+		t.Errorf("best %v, %v", h, err)
+	}
+	if h, err := c.GetBlockHash(ctx, 1); err != nil || h != hash1 {
+		t.Errorf("hash 1 %v, %v", h, err)
+	}
+	if _, err := c.GetBlockHash(ctx, 3); err != bitcoind.ErrNotFound {
+		t.Errorf("hash above tip: %v", err)
+	}
+
+	hashes, err := c.GetBlockHashes(ctx, 1, 5)
+	if err != nil || len(hashes) != 2 || hashes[0] != hash1 || hashes[1] != hash2 {
+		t.Errorf("hashes %v, %v", hashes, err)
+	}
+
+	header, err := c.GetBlockHeader(ctx, hash2)
+	if err != nil || header.Height != 2 || header.PreviousBlockHash != hash1 || header.Confirmations != 1 {
+		t.Errorf("header %+v, %v", header, err)
+	}
+	if _, err := c.GetBlockHeader(ctx, strings.Repeat("00", 32)); err != bitcoind.ErrNotFound {
+		t.Errorf("unknown header: %v", err)
+	}
+
+	blocks, err := c.GetBlocks(ctx, []string{hash1, hash2})
+	if err != nil || len(blocks) != 2 || len(blocks[0].Tx) != 1 || len(blocks[1].Tx) != 0 {
+		t.Fatalf("blocks %+v, %v", blocks, err)
+	}
+	tx, err := blocks[0].Tx[0].Decode()
+	if err != nil || tx.TxID != blocks[0].Tx[0].TxID || tx.Inputs[0].PrevOut != prev || tx.Outputs[0].Value != 1000 {
+		t.Errorf("block tx %+v, %v", tx, err)
+	}
+	if _, err := c.GetBlocks(ctx, []string{hash1, strings.Repeat("00", 32)}); err != bitcoind.ErrNotFound {
+		t.Errorf("unknown block: %v", err)
+	}
+
+	if got, err := c.GetRawTransaction(ctx, tx.TxID); err != nil || got.TxID != tx.TxID {
+		t.Errorf("mined tx %+v, %v", got, err)
+	}
+	pending := nodetest.BTCTx([]btc.OutPoint{{TxID: tx.TxID}}, []btc.TxOut{{Value: 900, Script: p2pkh(2)}})
+	id := node.AddMempool(pending)
+	if got, err := c.GetRawTransaction(ctx, id); err != nil || got.Outputs[0].Value != 900 {
+		t.Errorf("mempool tx %+v, %v", got, err)
+	}
+	if _, err := c.GetRawTransaction(ctx, strings.Repeat("00", 32)); err != bitcoind.ErrNotFound {
+		t.Errorf("unknown tx: %v", err)
+	}
+}
+
+func TestReorg(t *testing.T) {
+	node := nodetest.NewBitcoind("", "")
+	defer node.Close()
+	ctx := context.Background()
+	c := bitcoind.New(node.URL)
+
+	node.Mine()
+	old := node.Mine()
+	node.Reorg(1)
+	replaced := node.Mine()
+	if replaced == old {
+		t.Fatal("competing block has the hash of the replaced one")
+	}
+
+	if h, err := c.GetBlockHash(ctx, 2); err != nil || h != replaced {
+		t.Errorf("hash 2 after reorg %v, %v", h, err)
+	}
+	if _, err := c.GetBlock(ctx, old); err != bitcoind.ErrNotFound {
+		t.Errorf("replaced block: %v", err)
+	}
+}
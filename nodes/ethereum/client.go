@@ -0,0 +1,205 @@
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"service_template/nodes/rpc"
+)
+
+// TransferTopic is the topic of ERC-20 Transfer(address,address,uint256) events
+const TransferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+// Client talks to an Ethereum compatible JSON-RPC node
+type Client struct {
+	rpc *rpc.Client
+}
+
+// New creates a client for the node RPC url; hosted nodes usually need
+// rpc.WithHeader("Authorization", "Bearer ...") or rpc.WithBasicAuth
+func New(url string, opts ...rpc.Option) *Client {
+	return &Client{rpc: rpc.New(url, opts...)}
+}
+
+// RPC exposes the underlying client for calls without a typed wrapper
+func (c *Client) RPC() *rpc.Client {
+	return c.rpc
+}
+
+type Transaction struct {
+	Hash        string `json:"hash"`
+	BlockNumber string `json:"blockNumber"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Value       string `json:"value"`
+	Input       string `json:"input"`
+}
+
+type Block struct {
+	Number       string        `json:"number"`
+	Hash         string        `json:"hash"`
+	ParentHash   string        `json:"parentHash"`
+	Timestamp    string        `json:"timestamp"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+type Log struct {
+	Address     string   `json:"address"`
+	Topics      []string `json:"topics"`
+	Data        string   `json:"data"`
+	BlockNumber string   `json:"blockNumber"`
+	BlockHash   string   `json:"blockHash"`
+	TxHash      string   `json:"transactionHash"`
+	LogIndex    string   `json:"logIndex"`
+	Removed     bool     `json:"removed"`
+}
+
+type Receipt struct {
+	TxHash      string `json:"transactionHash"`
+	BlockNumber string `json:"blockNumber"`
+	BlockHash   string `json:"blockHash"`
+	Status      string `json:"status"`
+	Logs        []Log  `json:"logs"`
+}
+
+// FilterQuery selects logs of a block range, nil topic positions match anything
+type FilterQuery struct {
+	FromBlock uint64
+	ToBlock   uint64
+	Addresses []string
+	Topics    [][]string
+}
+
+func (q FilterQuery) arg() map[string]interface{} {
+	arg := map[string]interface{}{
+		"fromBlock": EncodeQuantity(q.FromBlock),
+		"toBlock":   EncodeQuantity(q.ToBlock),
+	}
+	if len(q.Addresses) > 0 {
+		arg["address"] = q.Addresses
+	}
+	if len(q.Topics) > 0 {
+		topics := make([]interface{}, len(q.Topics))
+		for i, t := range q.Topics {
+			if len(t) > 0 {
+				topics[i] = t
+			}
+		}
+		arg["topics"] = topics
+	}
+
+	return arg
+}
+
+func (c *Client) ChainID(ctx context.Context) (uint64, error) {
+	var s string
+	if err := c.rpc.Call(ctx, &s, "eth_chainId"); err != nil {
+		return 0, err
+	}
+
+	return ParseQuantity(s)
+}
+
+func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	var s string
+	if err := c.rpc.Call(ctx, &s, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+
+	return ParseQuantity(s)
+}
+
+// BlockByNumber returns the block with full transactions, nil above the tip
+func (c *Client) BlockByNumber(ctx context.Context, n uint64) (*Block, error) {
+	var b *Block
+	if err := c.rpc.Call(ctx, &b, "eth_getBlockByNumber", EncodeQuantity(n), true); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// BlocksByNumber fetches consecutive blocks in one batch, stopping at the tip
+func (c *Client) BlocksByNumber(ctx context.Context, from uint64, count int) ([]*Block, error) {
+	blocks := make([]*Block, count)
+	batch := make([]rpc.BatchElem, count)
+	for i := range batch {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Params: []interface{}{EncodeQuantity(from + uint64(i)), true},
+			Result: &blocks[i],
+		}
+	}
+
+	if err := c.rpc.BatchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+	for i, e := range batch {
+		if e.Error != nil {
+			return nil, e.Error
+		}
+		if blocks[i] == nil {
+			return blocks[:i], nil
+		}
+	}
+
+	return blocks, nil
+}
+
+// TransactionReceipt returns nil for unknown or pending transactions
+func (c *Client) TransactionReceipt(ctx context.Context, hash string) (*Receipt, error) {
+	var r *Receipt
+	if err := c.rpc.Call(ctx, &r, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (c *Client) GetLogs(ctx context.Context, q FilterQuery) ([]Log, error) {
+	var logs []Log
+	if err := c.rpc.Call(ctx, &logs, "eth_getLogs", q.arg()); err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// ParseQuantity decodes a hex quantity like 0x1b4
+func ParseQuantity(s string) (uint64, error) {
+	if !strings.HasPrefix(s, "0x") || len(s) < 3 {
+		return 0, errors.Errorf("invalid quantity %q", s)
+	}
+
+	return strconv.ParseUint(s[2:], 16, 64)
+}
+
+// ParseBig decodes a hex quantity or a 32 byte word of log data
+func ParseBig(s string) (*big.Int, error) {
+	if !strings.HasPrefix(s, "0x") || len(s) < 3 {
+		return nil, errors.Errorf("invalid quantity %q", s)
+	}
+	n, ok := new(big.Int).SetString(s[2:], 16)
+	if !ok {
+		return nil, errors.Errorf("invalid quantity %q", s)
+	}
+
+	return n, nil
+}
+
+func EncodeQuantity(n uint64) string {
+	return "0x" + strconv.FormatUint(n, 16)
+}
+
+// TopicAddress extracts the address of an indexed address topic
+func TopicAddress(topic string) (string, error) {
+	if len(topic) != 66 || !strings.HasPrefix(topic, "0x") {
+		return "", errors.Errorf("invalid topic %q", topic)
+	}
+
+	return "0x" + strings.ToLower(topic[26:]), nil
+}
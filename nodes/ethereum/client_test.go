@@ -0,0 +1,103 @@
+package ethereum_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"service_template/nodes/ethereum"
+	"service_template/nodes/nodetest"
+	"service_template/nodes/rpc"
+)
+
+const (
+	alice = "0x1111111111111111111111111111111111111111"
+	bob   = "0x2222222222222222222222222222222222222222"
+	token = "0x3333333333333333333333333333333333333333"
+)
+
+func TestClient(t *testing.T) {
+	node := nodetest.NewEthereum(56, "secret")
+	defer node.Close()
+	ctx := context.Background()
+	c := ethereum.New(node.URL, rpc.WithHeader("Authorization", "Bearer secret"))
+
+	transfer := nodetest.ETHTransfer(alice, bob, big.NewInt(1e18))
+	call, event := nodetest.ERC20Transfer(token, alice, bob, big.NewInt(5000))
+	hash1 := node.Mine([]ethereum.Transaction{transfer, call}, []ethereum.Log{event})
+	node.Mine(nil, nil)
+
+	if id, err := c.ChainID(ctx); err != nil || id != 56 {
+		t.Errorf("chain id %v, %v", id, err)
+	}
+	if n, err := c.BlockNumber(ctx); err != nil || n != 2 {
+		t.Errorf("block number %v, %v", n, err)
+	}
+
+	b, err := c.BlockByNumber(ctx, 1)
+	if err != nil || b == nil || b.Hash != hash1 || len(b.Transactions) != 2 {
+		t.Fatalf("block 1 %+v, %v", b, err)
+	}
+	if v, err := ethereum.ParseBig(b.Transactions[0].Value); err != nil || v.Cmp(big.NewInt(1e18)) != 0 {
+		t.Errorf("value %v, %v", v, err)
+	}
+	if b, err := c.BlockByNumber(ctx, 3); err != nil || b != nil {
+		t.Errorf("block above tip %+v, %v", b, err)
+	}
+
+	blocks, err := c.BlocksByNumber(ctx, 1, 10)
+	if err != nil || len(blocks) != 2 || blocks[1].ParentHash != hash1 {
+		t.Errorf("blocks %+v, %v", blocks, err)
+	}
+
+	r, err := c.TransactionReceipt(ctx, b.Transactions[1].Hash)
+	if err != nil || r == nil || r.BlockHash != hash1 || len(r.Logs) != 1 {
+		t.Fatalf("receipt %+v, %v", r, err)
+	}
+	if r, err := c.TransactionReceipt(ctx, "0xdead"); err != nil || r != nil {
+		t.Errorf("unknown receipt %+v, %v", r, err)
+	}
+
+	logs, err := c.GetLogs(ctx, ethereum.FilterQuery{
+		FromBlock: 0,
+		ToBlock:   2,
+		Addresses: []string{token},
+		Topics:    [][]string{{ethereum.TransferTopic}},
+	})
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("logs %+v, %v", logs, err)
+	}
+	if from, err := ethereum.TopicAddress(logs[0].Topics[1]); err != nil || from != alice {
+		t.Errorf("from %v, %v", from, err)
+	}
+	if amount, err := ethereum.ParseBig(logs[0].Data); err != nil || amount.Int64() != 5000 {
+		t.Errorf("amount %v, %v", amount, err)
+	}
+	if logs, err := c.GetLogs(ctx, ethereum.FilterQuery{FromBlock: 0, ToBlock: 2, Addresses: []string{bob}}); err != nil || len(logs) != 0 {
+		t.Errorf("logs of another address %+v, %v", logs, err)
+	}
+
+	_, err = ethereum.New(node.URL).ChainID(ctx)
+	if _, ok := err.(*rpc.HTTPError); !ok {
+		t.Errorf("without token: %#v", err)
+	}
+}
+
+func TestQuantity(t *testing.T) {
+	for s, want := range map[string]uint64{"0x0": 0, "0x1b4": 436, "0xffffffffffffffff": 1<<64 - 1} {
+		if got, err := ethereum.ParseQuantity(s); err != nil || got != want {
+			t.Errorf("ParseQuantity(%v) = %v, %v", s, got, err)
+		}
+		if got := ethereum.EncodeQuantity(want); got != s {
+			t.Errorf("EncodeQuantity(%v) = %v", want, got)
+		}
+	}
+	for _, s := range []string{"", "0x", "1b4", "0xzz", "0x10000000000000000"} {
+		if _, err := ethereum.ParseQuantity(s); err == nil {
+			t.Errorf("ParseQuantity(%q) succeeded", s)
+		}
+	}
+	if _, err := ethereum.TopicAddress(alice); err == nil {
+		t.Error("TopicAddress accepted an address")
+	}
+}
@@ -0,0 +1,244 @@
+package nodetest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"service_template/btc"
+	"service_template/nodes/rpc"
+)
+
+type btcBlock struct {
+	hash   string
+	prev   string
+	time   int64
+	txIDs  []string
+	rawTxs [][]byte
+}
+
+// Bitcoind is an in-memory bitcoind JSON-RPC node with basic auth
+type Bitcoind struct {
+	*httptest.Server
+	faults
+
+	user     string
+	password string
+
+	mu      sync.Mutex
+	blocks  []btcBlock
+	mempool map[string][]byte
+	mined   int
+}
+
+// NewBitcoind starts a node holding only a genesis block
+func NewBitcoind(user, password string) *Bitcoind {
+	n := &Bitcoind{user: user, password: password, mempool: map[string][]byte{}}
+	n.blocks = []btcBlock{{hash: fakeHash("genesis"), time: time.Now().Unix()}}
+	n.Server = newServer(&n.faults, http.HandlerFunc(n.serveHTTP))
+
+	return n
+}
+
+// Mine appends a block with the raw transactions and returns its hash
+func (n *Bitcoind) Mine(rawTxs ...[]byte) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	tip := n.blocks[len(n.blocks)-1]
+	n.mined++
+	b := btcBlock{
+		hash:   fakeHash(tip.hash, len(n.blocks), n.mined),
+		prev:   tip.hash,
+		time:   tip.time + 600,
+		rawTxs: rawTxs,
+	}
+	for _, raw := range rawTxs {
+		tx, err := btc.DecodeTx(raw)
+		if err != nil {
+			panic(fmt.Sprintf("nodetest: mine: %v", err))
+		}
+		b.txIDs = append(b.txIDs, tx.TxID)
+		delete(n.mempool, tx.TxID)
+	}
+	n.blocks = append(n.blocks, b)
+
+	return b.hash
+}
+
+// Reorg drops the top depth blocks, following Mine calls build the competing branch
+func (n *Bitcoind) Reorg(depth int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if depth >= len(n.blocks) {
+		depth = len(n.blocks) - 1
+	}
+	n.blocks = n.blocks[:len(n.blocks)-depth]
+}
+
+// AddMempool makes an unconfirmed transaction known to getrawtransaction
+func (n *Bitcoind) AddMempool(raw []byte) string {
+	tx, err := btc.DecodeTx(raw)
+	if err != nil {
+		panic(fmt.Sprintf("nodetest: mempool: %v", err))
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.mempool[tx.TxID] = raw
+
+	return tx.TxID
+}
+
+func (n *Bitcoind) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, password, ok := r.BasicAuth(); n.user != "" && (!ok || user != n.user || password != n.password) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	serveRPC(w, r, n.handle, true)
+}
+
+func (n *Bitcoind) handle(method string, params []json.RawMessage) (interface{}, *rpc.Error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	tip := len(n.blocks) - 1
+	switch method {
+	case "getblockchaininfo":
+		return map[string]interface{}{
+			"chain":                "regtest",
+			"blocks":               tip,
+			"headers":              tip,
+			"bestblockhash":        n.blocks[tip].hash,
+			"initialblockdownload": false,
+		}, nil
+	case "getblockcount":
+		return tip, nil
+	case "getbestblockhash":
+		return n.blocks[tip].hash, nil
+	case "getblockhash":
+		var height int
+		if !param(params, 0, &height) {
+			return nil, errInvalidParams
+		}
+		if height < 0 || height > tip {
+			return nil, &rpc.Error{Code: -8, Message: "Block height out of range"}
+		}
+		return n.blocks[height].hash, nil
+	case "getblockheader", "getblock":
+		var hash string
+		if !param(params, 0, &hash) {
+			return nil, errInvalidParams
+		}
+		height := n.height(hash)
+		if height < 0 {
+			return nil, &rpc.Error{Code: -5, Message: "Block not found"}
+		}
+		return n.block(height, method == "getblock"), nil
+	case "getrawtransaction":
+		var txID string
+		if !param(params, 0, &txID) {
+			return nil, errInvalidParams
+		}
+		if raw, ok := n.mempool[txID]; ok {
+			return hex.EncodeToString(raw), nil
+		}
+		for _, b := range n.blocks {
+			for i, id := range b.txIDs {
+				if id == txID {
+					return hex.EncodeToString(b.rawTxs[i]), nil
+				}
+			}
+		}
+		return nil, &rpc.Error{Code: -5, Message: "No such mempool or blockchain transaction"}
+	}
+
+	return nil, errMethodNotFound
+}
+
+func (n *Bitcoind) height(hash string) int {
+	for i, b := range n.blocks {
+		if b.hash == hash {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (n *Bitcoind) block(height int, withTxs bool) map[string]interface{} {
+	b := n.blocks[height]
+	res := map[string]interface{}{
+		"hash":          b.hash,
+		"height":        height,
+		"time":          b.time,
+		"confirmations": len(n.blocks) - height,
+	}
+	if b.prev != "" {
+		res["previousblockhash"] = b.prev
+	}
+	if withTxs {
+		txs := make([]map[string]string, len(b.txIDs))
+		for i, id := range b.txIDs {
+			txs[i] = map[string]string{"txid": id, "hex": hex.EncodeToString(b.rawTxs[i])}
+		}
+		res["tx"] = txs
+	}
+
+	return res
+}
+
+// BTCTx serializes a legacy transaction spending inputs to outputs, scripts of inputs are empty
+func BTCTx(inputs []btc.OutPoint, outputs []btc.TxOut) []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, int32(1))
+
+	writeVarInt(&buf, uint64(len(inputs)))
+	for _, in := range inputs {
+		id, err := hex.DecodeString(in.TxID)
+		if err != nil || len(id) != 32 {
+			panic(fmt.Sprintf("nodetest: invalid txid %q", in.TxID))
+		}
+		// txids are displayed byte reversed
+		for i := len(id) - 1; i >= 0; i-- {
+			buf.WriteByte(id[i])
+		}
+		_ = binary.Write(&buf, binary.LittleEndian, in.Vout)
+		writeVarInt(&buf, 0)
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(0xffffffff))
+	}
+
+	writeVarInt(&buf, uint64(len(outputs)))
+	for _, out := range outputs {
+		_ = binary.Write(&buf, binary.LittleEndian, out.Value)
+		writeVarInt(&buf, uint64(len(out.Script)))
+		buf.Write(out.Script)
+	}
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(0))
+
+	return buf.Bytes()
+}
+
+func writeVarInt(buf *bytes.Buffer, v uint64) {
+	switch {
+	case v < 0xfd:
+		buf.WriteByte(byte(v))
+	case v <= 0xffff:
+		buf.WriteByte(0xfd)
+		_ = binary.Write(buf, binary.LittleEndian, uint16(v))
+	case v <= 0xffffffff:
+		buf.WriteByte(0xfe)
+		_ = binary.Write(buf, binary.LittleEndian, uint32(v))
+	default:
+		buf.WriteByte(0xff)
+		_ = binary.Write(buf, binary.LittleEndian, v)
+	}
+}
@@ -0,0 +1,275 @@
+package nodetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"service_template/nodes/ethereum"
+	"service_template/nodes/rpc"
+)
+
+type ethBlock struct {
+	hash   string
+	parent string
+	time   int64
+	txs    []ethereum.Transaction
+	logs   []ethereum.Log
+}
+
+// Ethereum is an in-memory Ethereum JSON-RPC node, a non-empty token requires bearer auth
+type Ethereum struct {
+	*httptest.Server
+	faults
+
+	chainID uint64
+	token   string
+
+	mu     sync.Mutex
+	blocks []ethBlock
+	mined  int
+	txs    int
+}
+
+// NewEthereum starts a node holding only a genesis block
+func NewEthereum(chainID uint64, token string) *Ethereum {
+	n := &Ethereum{chainID: chainID, token: token}
+	n.blocks = []ethBlock{{hash: "0x" + fakeHash("genesis", int(chainID)), time: time.Now().Unix()}}
+	n.Server = newServer(&n.faults, http.HandlerFunc(n.serveHTTP))
+
+	return n
+}
+
+// Mine appends a block and returns its hash. Block fields of txs and logs are filled in,
+// logs without a transaction hash belong to the last transaction
+func (n *Ethereum) Mine(txs []ethereum.Transaction, logs []ethereum.Log) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	tip := n.blocks[len(n.blocks)-1]
+	n.mined++
+	number := ethereum.EncodeQuantity(uint64(len(n.blocks)))
+	b := ethBlock{
+		hash:   "0x" + fakeHash(tip.hash, len(n.blocks), n.mined),
+		parent: tip.hash,
+		time:   tip.time + 12,
+	}
+	for _, tx := range txs {
+		if tx.Hash == "" {
+			n.txs++
+			tx.Hash = "0x" + fakeHash("tx", n.txs)
+		}
+		tx.BlockNumber = number
+		b.txs = append(b.txs, tx)
+	}
+	for i, l := range logs {
+		if l.TxHash == "" && len(b.txs) > 0 {
+			l.TxHash = b.txs[len(b.txs)-1].Hash
+		}
+		l.BlockNumber = number
+		l.BlockHash = b.hash
+		l.LogIndex = ethereum.EncodeQuantity(uint64(i))
+		b.logs = append(b.logs, l)
+	}
+	n.blocks = append(n.blocks, b)
+
+	return b.hash
+}
+
+// Reorg drops the top depth blocks, following Mine calls build the competing branch
+func (n *Ethereum) Reorg(depth int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if depth >= len(n.blocks) {
+		depth = len(n.blocks) - 1
+	}
+	n.blocks = n.blocks[:len(n.blocks)-depth]
+}
+
+func (n *Ethereum) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if n.token != "" && r.Header.Get("Authorization") != "Bearer "+n.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("invalid token"))
+		return
+	}
+
+	serveRPC(w, r, n.handle, false)
+}
+
+func (n *Ethereum) handle(method string, params []json.RawMessage) (interface{}, *rpc.Error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	tip := uint64(len(n.blocks) - 1)
+	switch method {
+	case "eth_chainId":
+		return ethereum.EncodeQuantity(n.chainID), nil
+	case "eth_blockNumber":
+		return ethereum.EncodeQuantity(tip), nil
+	case "eth_getBlockByNumber":
+		var tag string
+		if !param(params, 0, &tag) {
+			return nil, errInvalidParams
+		}
+		number, ok := n.blockNumber(tag)
+		if !ok {
+			return nil, errInvalidParams
+		}
+		if number > tip {
+			return nil, nil
+		}
+		return n.block(number), nil
+	case "eth_getTransactionReceipt":
+		var hash string
+		if !param(params, 0, &hash) {
+			return nil, errInvalidParams
+		}
+		for _, b := range n.blocks {
+			for _, tx := range b.txs {
+				if tx.Hash != hash {
+					continue
+				}
+				r := ethereum.Receipt{TxHash: hash, BlockNumber: tx.BlockNumber, BlockHash: b.hash, Status: "0x1"}
+				for _, l := range b.logs {
+					if l.TxHash == hash {
+						r.Logs = append(r.Logs, l)
+					}
+				}
+				return r, nil
+			}
+		}
+		return nil, nil
+	case "eth_getLogs":
+		var q struct {
+			FromBlock string          `json:"fromBlock"`
+			ToBlock   string          `json:"toBlock"`
+			Address   json.RawMessage `json:"address"`
+			Topics    []interface{}   `json:"topics"`
+		}
+		if !param(params, 0, &q) {
+			return nil, errInvalidParams
+		}
+		from, ok1 := n.blockNumber(q.FromBlock)
+		to, ok2 := n.blockNumber(q.ToBlock)
+		if !ok1 || !ok2 {
+			return nil, errInvalidParams
+		}
+		addresses := stringSet(q.Address)
+		var topic0 map[string]bool
+		if len(q.Topics) > 0 && q.Topics[0] != nil {
+			b, _ := json.Marshal(q.Topics[0])
+			topic0 = stringSet(b)
+		}
+
+		logs := []ethereum.Log{}
+		for i := from; i <= to && i <= tip; i++ {
+			for _, l := range n.blocks[i].logs {
+				if addresses != nil && !addresses[strings.ToLower(l.Address)] {
+					continue
+				}
+				if topic0 != nil && (len(l.Topics) == 0 || !topic0[strings.ToLower(l.Topics[0])]) {
+					continue
+				}
+				logs = append(logs, l)
+			}
+		}
+		return logs, nil
+	}
+
+	return nil, errMethodNotFound
+}
+
+func (n *Ethereum) blockNumber(tag string) (uint64, bool) {
+	switch tag {
+	case "", "latest", "safe", "finalized":
+		return uint64(len(n.blocks) - 1), true
+	case "earliest":
+		return 0, true
+	}
+	v, err := ethereum.ParseQuantity(tag)
+
+	return v, err == nil
+}
+
+func (n *Ethereum) block(number uint64) map[string]interface{} {
+	b := n.blocks[number]
+	txs := b.txs
+	if txs == nil {
+		txs = []ethereum.Transaction{}
+	}
+
+	return map[string]interface{}{
+		"number":       ethereum.EncodeQuantity(number),
+		"hash":         b.hash,
+		"parentHash":   parentOrZero(b.parent),
+		"timestamp":    ethereum.EncodeQuantity(uint64(b.time)),
+		"transactions": txs,
+	}
+}
+
+func parentOrZero(h string) string {
+	if h == "" {
+		return "0x" + strings.Repeat("0", 64)
+	}
+
+	return h
+}
+
+// stringSet decodes a string or a list of strings, nil when absent
+func stringSet(raw json.RawMessage) map[string]bool {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var list []string
+	if json.Unmarshal(raw, &list) != nil {
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			return nil
+		}
+		list = []string{s}
+	}
+	set := make(map[string]bool, len(list))
+	for _, s := range list {
+		set[strings.ToLower(s)] = true
+	}
+
+	return set
+}
+
+// ETHTransfer builds a native transfer of wei
+func ETHTransfer(from, to string, wei *big.Int) ethereum.Transaction {
+	return ethereum.Transaction{From: from, To: to, Value: "0x" + wei.Text(16), Input: "0x"}
+}
+
+// ERC20Transfer builds the call to the token contract together with its Transfer event
+func ERC20Transfer(contract, from, to string, amount *big.Int) (ethereum.Transaction, ethereum.Log) {
+	tx := ethereum.Transaction{
+		From:  from,
+		To:    contract,
+		Value: "0x0",
+		Input: fmt.Sprintf("0xa9059cbb%s%064x", word(to), amount),
+	}
+	l := ethereum.Log{
+		Address: contract,
+		Topics:  []string{ethereum.TransferTopic, "0x" + word(from), "0x" + word(to)},
+		Data:    fmt.Sprintf("0x%064x", amount),
+	}
+
+	return tx, l
+}
+
+// word left pads a hex address to 32 bytes
+func word(addr string) string {
+	addr = strings.ToLower(strings.TrimPrefix(addr, "0x"))
+	if len(addr) > 40 {
+		addr = addr[len(addr)-40:]
+	}
+
+	return strings.Repeat("0", 64-len(addr)) + addr
+}
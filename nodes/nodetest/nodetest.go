@@ -0,0 +1,176 @@
+// Package nodetest provides in-memory blockchain nodes served by httptest for exercising the
+// node clients, ingestion and indexing without a real node
+package nodetest
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"service_template/nodes/rpc"
+)
+
+// faults lets a test slow the node down or fail requests before they are handled
+type faults struct {
+	mu         sync.Mutex
+	delay      time.Duration
+	failCount  int
+	failStatus int
+	failBody   string
+	requests   int
+}
+
+// SetDelay delays every response, e.g. to trigger client timeouts
+func (f *faults) SetDelay(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delay = d
+}
+
+// FailNext answers the next n requests with status and body
+func (f *faults) FailNext(n, status int, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failCount, f.failStatus, f.failBody = n, status, body
+}
+
+// Requests returns the number of HTTP requests served, a batch counts once
+func (f *faults) Requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func (f *faults) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.requests++
+		delay := f.delay
+		fail := f.failCount > 0
+		status, body := f.failStatus, f.failBody
+		if fail {
+			f.failCount--
+		}
+		f.mu.Unlock()
+
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fail {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// fakeHash derives a distinct 32 byte hex hash from its inputs
+func fakeHash(parts ...interface{}) string {
+	h := sha256.New()
+	for _, p := range parts {
+		switch v := p.(type) {
+		case string:
+			h.Write([]byte(v))
+		case []byte:
+			h.Write(v)
+		case int64:
+			_ = binary.Write(h, binary.LittleEndian, v)
+		case int:
+			_ = binary.Write(h, binary.LittleEndian, int64(v))
+		}
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type rpcRequest struct {
+	Version string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	Version string          `json:"jsonrpc,omitempty"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+	Error   *rpc.Error      `json:"error"`
+}
+
+type rpcHandler func(method string, params []json.RawMessage) (interface{}, *rpc.Error)
+
+var (
+	errMethodNotFound = &rpc.Error{Code: -32601, Message: "Method not found"}
+	errInvalidParams  = &rpc.Error{Code: -32602, Message: "Invalid params"}
+	errParse          = &rpc.Error{Code: -32700, Message: "Parse error"}
+)
+
+// serveRPC dispatches single and batch JSON-RPC requests. Like bitcoind, a failed single call
+// is answered with status 500 when errorStatus is set
+func serveRPC(w http.ResponseWriter, r *http.Request, handle rpcHandler, errorStatus bool) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		writeJSON(w, http.StatusOK, rpcResponse{ID: json.RawMessage("null"), Error: errParse})
+		return
+	}
+
+	call := func(req rpcRequest) rpcResponse {
+		res, rpcErr := handle(req.Method, req.Params)
+		if rpcErr != nil {
+			res = nil
+		}
+		return rpcResponse{Version: req.Version, ID: req.ID, Result: res, Error: rpcErr}
+	}
+
+	if len(raw) > 0 && raw[0] == '[' {
+		var reqs []rpcRequest
+		if err := json.Unmarshal(raw, &reqs); err != nil {
+			writeJSON(w, http.StatusOK, rpcResponse{ID: json.RawMessage("null"), Error: errParse})
+			return
+		}
+		res := make([]rpcResponse, len(reqs))
+		for i, req := range reqs {
+			res[i] = call(req)
+		}
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		writeJSON(w, http.StatusOK, rpcResponse{ID: json.RawMessage("null"), Error: errParse})
+		return
+	}
+	res := call(req)
+	status := http.StatusOK
+	if res.Error != nil && errorStatus {
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, res)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func param(params []json.RawMessage, i int, v interface{}) bool {
+	return i < len(params) && json.Unmarshal(params[i], v) == nil
+}
+
+func newServer(f *faults, h http.Handler) *httptest.Server {
+	return httptest.NewServer(f.wrap(h))
+}
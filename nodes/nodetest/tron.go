@@ -0,0 +1,238 @@
+package nodetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"service_template/nodes/ethereum"
+	"service_template/nodes/tron"
+)
+
+type tronBlock struct {
+	block tron.Block
+	infos []tron.TransactionInfo
+}
+
+// Tron is an in-memory java-tron HTTP API node, a non-empty key is required in the
+// TRON-PRO-API-KEY header
+type Tron struct {
+	*httptest.Server
+	faults
+
+	apiKey string
+
+	mu     sync.Mutex
+	blocks []tronBlock
+	mined  int
+	txs    int
+}
+
+// NewTron starts a node holding only a genesis block
+func NewTron(apiKey string) *Tron {
+	n := &Tron{apiKey: apiKey}
+	genesis := tron.Block{BlockID: fakeHash("genesis")}
+	genesis.BlockHeader.RawData.Timestamp = time.Now().UnixNano() / int64(time.Millisecond)
+	n.blocks = []tronBlock{{block: genesis}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/wallet/getnowblock", n.getNowBlock)
+	mux.HandleFunc("/wallet/getblockbynum", n.getBlockByNum)
+	mux.HandleFunc("/wallet/getblockbylimitnext", n.getBlockByLimitNext)
+	mux.HandleFunc("/wallet/gettransactioninfobyblocknum", n.getTransactionInfoByBlockNum)
+	n.Server = newServer(&n.faults, n.auth(mux))
+
+	return n
+}
+
+// Mine appends a block and returns its id. Transaction ids are assigned when empty,
+// infos without an id belong to the transaction at the same position
+func (n *Tron) Mine(txs []tron.Transaction, infos []tron.TransactionInfo) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	tip := n.blocks[len(n.blocks)-1].block
+	number := int64(len(n.blocks))
+	n.mined++
+
+	var b tronBlock
+	b.block.BlockID = fakeHash(tip.BlockID, number, n.mined)
+	b.block.BlockHeader.RawData.Number = number
+	b.block.BlockHeader.RawData.ParentHash = tip.BlockID
+	b.block.BlockHeader.RawData.Timestamp = tip.BlockHeader.RawData.Timestamp + 3000
+	for _, tx := range txs {
+		if tx.TxID == "" {
+			n.txs++
+			tx.TxID = fakeHash("tx", n.txs)
+		}
+		b.block.Transactions = append(b.block.Transactions, tx)
+	}
+	for i, info := range infos {
+		if info.ID == "" && i < len(b.block.Transactions) {
+			info.ID = b.block.Transactions[i].TxID
+		}
+		info.BlockNumber = number
+		info.BlockTimeStamp = b.block.BlockHeader.RawData.Timestamp
+		b.infos = append(b.infos, info)
+	}
+	n.blocks = append(n.blocks, b)
+
+	return b.block.BlockID
+}
+
+// Reorg drops the top depth blocks, following Mine calls build the competing branch
+func (n *Tron) Reorg(depth int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if depth >= len(n.blocks) {
+		depth = len(n.blocks) - 1
+	}
+	n.blocks = n.blocks[:len(n.blocks)-depth]
+}
+
+func (n *Tron) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n.apiKey != "" && r.Header.Get(tron.APIKeyHeader) != n.apiKey {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"Error": "invalid api key"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func decodeArgs(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid request: %v", err)
+	}
+
+	return nil
+}
+
+func (n *Tron) getNowBlock(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, n.blocks[len(n.blocks)-1].block)
+}
+
+func (n *Tron) getBlockByNum(w http.ResponseWriter, r *http.Request) {
+	var arg struct {
+		Num int64 `json:"num"`
+	}
+	if err := decodeArgs(r, &arg); err != nil {
+		writeJSON(w, http.StatusOK, map[string]string{"Error": err.Error()})
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if arg.Num < 0 || arg.Num >= int64(len(n.blocks)) {
+		writeJSON(w, http.StatusOK, struct{}{})
+		return
+	}
+	writeJSON(w, http.StatusOK, n.blocks[arg.Num].block)
+}
+
+func (n *Tron) getBlockByLimitNext(w http.ResponseWriter, r *http.Request) {
+	var arg struct {
+		StartNum int64 `json:"startNum"`
+		EndNum   int64 `json:"endNum"`
+	}
+	if err := decodeArgs(r, &arg); err != nil {
+		writeJSON(w, http.StatusOK, map[string]string{"Error": err.Error()})
+		return
+	}
+	if arg.EndNum <= arg.StartNum || arg.EndNum-arg.StartNum > 100 {
+		writeJSON(w, http.StatusOK, map[string]string{"Error": "invalid block range"})
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	res := struct {
+		Block []tron.Block `json:"block,omitempty"`
+	}{}
+	for i := arg.StartNum; i < arg.EndNum && i < int64(len(n.blocks)); i++ {
+		if i >= 0 {
+			res.Block = append(res.Block, n.blocks[i].block)
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (n *Tron) getTransactionInfoByBlockNum(w http.ResponseWriter, r *http.Request) {
+	var arg struct {
+		Num int64 `json:"num"`
+	}
+	if err := decodeArgs(r, &arg); err != nil {
+		writeJSON(w, http.StatusOK, map[string]string{"Error": err.Error()})
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if arg.Num < 0 || arg.Num >= int64(len(n.blocks)) || len(n.blocks[arg.Num].infos) == 0 {
+		writeJSON(w, http.StatusOK, struct{}{})
+		return
+	}
+	writeJSON(w, http.StatusOK, n.blocks[arg.Num].infos)
+}
+
+// TRXTransfer builds a native transfer of sun between 41 prefixed hex addresses
+func TRXTransfer(from, to string, sun int64) tron.Transaction {
+	var c tron.Contract
+	c.Type = "TransferContract"
+	c.Parameter.Value.OwnerAddress = from
+	c.Parameter.Value.ToAddress = to
+	c.Parameter.Value.Amount = sun
+
+	return newTronTx(c)
+}
+
+// TRC20Transfer builds the contract call together with the execution info carrying
+// its Transfer event, addresses are 41 prefixed hex
+func TRC20Transfer(contract, from, to string, amount *big.Int) (tron.Transaction, tron.TransactionInfo) {
+	var c tron.Contract
+	c.Type = "TriggerSmartContract"
+	c.Parameter.Value.OwnerAddress = from
+	c.Parameter.Value.ContractAddress = contract
+	c.Parameter.Value.Data = fmt.Sprintf("a9059cbb%s%064x", word(tronEVM(to)), amount)
+
+	info := tron.TransactionInfo{ContractAddress: contract}
+	info.Receipt.Result = "SUCCESS"
+	info.Log = []tron.Log{{
+		Address: tronEVM(contract),
+		Topics: []string{
+			strings.TrimPrefix(ethereum.TransferTopic, "0x"),
+			word(tronEVM(from)),
+			word(tronEVM(to)),
+		},
+		Data: fmt.Sprintf("%064x", amount),
+	}}
+
+	return newTronTx(c), info
+}
+
+func newTronTx(c tron.Contract) tron.Transaction {
+	var tx tron.Transaction
+	tx.RawData.Contract = []tron.Contract{c}
+	tx.Ret = []tron.Ret{{ContractRet: "SUCCESS"}}
+
+	return tx
+}
+
+// tronEVM strips the 41 prefix, event logs carry 20 byte addresses
+func tronEVM(addr string) string {
+	addr = strings.ToLower(addr)
+	if len(addr) == 42 && strings.HasPrefix(addr, "41") {
+		return addr[2:]
+	}
+
+	return addr
+}
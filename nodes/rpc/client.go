@@ -0,0 +1,282 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"service_template/logger"
+	"service_template/tracer"
+)
+
+const (
+	Version1 = "1.0"
+	Version2 = "2.0"
+
+	defaultTimeout = 30 * time.Second
+	maxErrorBody   = 4 << 10
+)
+
+var ErrTimeout = errors.New("rpc timeout")
+
+// Error is an error returned by the node for a call
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// HTTPError is a non-JSON-RPC response of the node
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("rpc http error %d: %s", e.StatusCode, e.Body)
+}
+
+// Client is a JSON-RPC client over a traced HTTP client
+type Client struct {
+	url      string
+	version  string
+	http     tracer.HTTPClient
+	user     string
+	password string
+	headers  http.Header
+	timeout  time.Duration
+	id       uint64
+}
+
+type Option func(*Client)
+
+// WithVersion sets the jsonrpc field, 2.0 by default
+func WithVersion(v string) Option {
+	return func(c *Client) { c.version = v }
+}
+
+// WithBasicAuth authenticates requests with a user and password
+func WithBasicAuth(user, password string) Option {
+	return func(c *Client) { c.user, c.password = user, password }
+}
+
+// WithHeader adds a header to every request, e.g. an API key
+func WithHeader(key, value string) Option {
+	return func(c *Client) { c.headers.Add(key, value) }
+}
+
+// WithTimeout bounds each call unless the call context has an earlier deadline
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithHTTPClient replaces the traced default HTTP client
+func WithHTTPClient(h tracer.HTTPClient) Option {
+	return func(c *Client) { c.http = h }
+}
+
+func New(url string, opts ...Option) *Client {
+	c := &Client{
+		url:     url,
+		version: Version2,
+		headers: http.Header{},
+		timeout: defaultTimeout,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if c.http == nil {
+		c.http = tracer.NewTraceHTTPClient(nil, nil)
+	}
+
+	return c
+}
+
+type request struct {
+	Version string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+func (c *Client) newRequest(method string, params []interface{}) request {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	return request{
+		Version: c.version,
+		ID:      atomic.AddUint64(&c.id, 1),
+		Method:  method,
+		Params:  params,
+	}
+}
+
+// post sends a JSON body and returns the response body of a 2xx response, or the body of an
+// error response that still carries a JSON-RPC payload (bitcoind answers errors with 500)
+func (c *Client) post(ctx context.Context, body interface{}) ([]byte, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.headers {
+		req.Header[k] = v
+	}
+	if c.user != "" || c.password != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, errors.Wrap(ErrTimeout, err.Error())
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	respBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, errors.Wrap(ErrTimeout, err.Error())
+		}
+		return nil, err
+	}
+
+	if res.StatusCode/100 != 2 && !json.Valid(respBody) {
+		if len(respBody) > maxErrorBody {
+			respBody = respBody[:maxErrorBody]
+		}
+		return nil, &HTTPError{StatusCode: res.StatusCode, Body: string(respBody)}
+	}
+
+	return respBody, nil
+}
+
+// Call invokes a method and decodes its result into result, which may be nil
+func (c *Client) Call(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	log := logger.FromContext(ctx).WithField("m", "Call")
+	log.Tracef("Call:: method: %v", method)
+
+	body, err := c.post(ctx, c.newRequest(method, params))
+	if err != nil {
+		return err
+	}
+
+	var res response
+	if err := json.Unmarshal(body, &res); err != nil {
+		return &HTTPError{StatusCode: http.StatusOK, Body: truncate(body)}
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+
+	return decodeResult(res.Result, result)
+}
+
+// BatchElem is a call of a batch, Error is set per element
+type BatchElem struct {
+	Method string
+	Params []interface{}
+	Result interface{}
+	Error  error
+}
+
+// BatchCall sends all calls in one request. The returned error concerns the whole batch,
+// errors of single calls are set on their elements
+func (c *Client) BatchCall(ctx context.Context, batch []BatchElem) error {
+	log := logger.FromContext(ctx).WithField("m", "BatchCall")
+	log.Tracef("BatchCall:: len: %v", len(batch))
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	reqs := make([]request, len(batch))
+	byID := make(map[uint64]int, len(batch))
+	for i, e := range batch {
+		reqs[i] = c.newRequest(e.Method, e.Params)
+		byID[reqs[i].ID] = i
+	}
+
+	body, err := c.post(ctx, reqs)
+	if err != nil {
+		return err
+	}
+
+	var res []response
+	if err := json.Unmarshal(body, &res); err != nil {
+		// a node rejecting the whole batch answers with a single error object
+		var single response
+		if json.Unmarshal(body, &single) == nil && single.Error != nil {
+			return single.Error
+		}
+		return &HTTPError{StatusCode: http.StatusOK, Body: truncate(body)}
+	}
+
+	seen := make(map[int]bool, len(res))
+	for _, r := range res {
+		i, ok := byID[r.ID]
+		if !ok {
+			continue
+		}
+		seen[i] = true
+		if r.Error != nil {
+			batch[i].Error = r.Error
+			continue
+		}
+		batch[i].Error = decodeResult(r.Result, batch[i].Result)
+	}
+	for i := range batch {
+		if !seen[i] {
+			batch[i].Error = errors.Errorf("no response for %v", batch[i].Method)
+		}
+	}
+
+	return nil
+}
+
+func decodeResult(raw json.RawMessage, result interface{}) error {
+	if result == nil || len(raw) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(raw, result)
+}
+
+func truncate(b []byte) string {
+	if len(b) > maxErrorBody {
+		b = b[:maxErrorBody]
+	}
+
+	return string(b)
+}
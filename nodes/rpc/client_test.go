@@ -0,0 +1,149 @@
+package rpc_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"service_template/nodes/nodetest"
+	"service_template/nodes/rpc"
+)
+
+func TestCall(t *testing.T) {
+	node := nodetest.NewBitcoind("user", "secret")
+	defer node.Close()
+	ctx := context.Background()
+
+	c := rpc.New(node.URL, rpc.WithVersion(rpc.Version1), rpc.WithBasicAuth("user", "secret"))
+	var count int
+	if err := c.Call(ctx, &count, "getblockcount"); err != nil || count != 0 {
+		t.Fatalf("getblockcount %v, %v", count, err)
+	}
+
+	// bitcoind answers a failed call with status 500 and a JSON-RPC error
+	err := c.Call(ctx, nil, "getblockhash", 5)
+	if e, ok := err.(*rpc.Error); !ok || e.Code != -8 {
+		t.Errorf("getblockhash above tip: %#v", err)
+	}
+	err = c.Call(ctx, nil, "nosuchmethod")
+	if e, ok := err.(*rpc.Error); !ok || e.Code != -32601 {
+		t.Errorf("unknown method: %#v", err)
+	}
+
+	bad := rpc.New(node.URL, rpc.WithBasicAuth("user", "wrong"))
+	err = bad.Call(ctx, nil, "getblockcount")
+	if e, ok := err.(*rpc.HTTPError); !ok || e.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong password: %#v", err)
+	}
+
+	node.FailNext(1, http.StatusBadGateway, "<html>bad gateway</html>")
+	err = c.Call(ctx, nil, "getblockcount")
+	if e, ok := err.(*rpc.HTTPError); !ok || e.StatusCode != http.StatusBadGateway || e.Body != "<html>bad gateway</html>" {
+		t.Errorf("proxy error: %#v", err)
+	}
+
+	node.FailNext(1, http.StatusOK, "not json")
+	err = c.Call(ctx, nil, "getblockcount")
+	if _, ok := err.(*rpc.HTTPError); !ok {
+		t.Errorf("invalid body: %#v", err)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	node := nodetest.NewEthereum(1, "token")
+	defer node.Close()
+	ctx := context.Background()
+
+	var id string
+	c := rpc.New(node.URL, rpc.WithHeader("Authorization", "Bearer token"))
+	if err := c.Call(ctx, &id, "eth_chainId"); err != nil || id != "0x1" {
+		t.Errorf("eth_chainId %v, %v", id, err)
+	}
+
+	err := rpc.New(node.URL).Call(ctx, &id, "eth_chainId")
+	if e, ok := err.(*rpc.HTTPError); !ok || e.StatusCode != http.StatusUnauthorized {
+		t.Errorf("without token: %#v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	node := nodetest.NewBitcoind("", "")
+	defer node.Close()
+	node.SetDelay(time.Second)
+
+	c := rpc.New(node.URL, rpc.WithTimeout(50*time.Millisecond))
+	start := time.Now()
+	err := c.Call(context.Background(), nil, "getblockcount")
+	if errors.Cause(err) != rpc.ErrTimeout {
+		t.Errorf("delayed call: %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("timeout took %v", time.Since(start))
+	}
+
+	// an earlier deadline of the call context wins over the client timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := rpc.New(node.URL).Call(ctx, nil, "getblockcount"); errors.Cause(err) != rpc.ErrTimeout {
+		t.Errorf("context deadline: %v", err)
+	}
+}
+
+func TestBatchCall(t *testing.T) {
+	node := nodetest.NewBitcoind("", "")
+	defer node.Close()
+	node.Mine()
+	ctx := context.Background()
+
+	c := rpc.New(node.URL, rpc.WithVersion(rpc.Version1))
+	if err := c.BatchCall(ctx, nil); err != nil || node.Requests() != 0 {
+		t.Fatalf("empty batch: %v, %v requests", err, node.Requests())
+	}
+
+	var count int
+	var h0, h1 string
+	batch := []rpc.BatchElem{
+		{Method: "getblockcount", Result: &count},
+		{Method: "getblockhash", Params: []interface{}{0}, Result: &h0},
+		{Method: "getblockhash", Params: []interface{}{1}, Result: &h1},
+		{Method: "getblockhash", Params: []interface{}{2}},
+	}
+	if err := c.BatchCall(ctx, batch); err != nil {
+		t.Fatal(err)
+	}
+	if node.Requests() != 1 {
+		t.Errorf("batch sent in %v requests", node.Requests())
+	}
+	if count != 1 || h0 == "" || h1 == "" || h0 == h1 {
+		t.Errorf("results %v %q %q", count, h0, h1)
+	}
+	for i, e := range batch[:3] {
+		if e.Error != nil {
+			t.Errorf("element %v: %v", i, e.Error)
+		}
+	}
+	if e, ok := batch[3].Error.(*rpc.Error); !ok || e.Code != -8 {
+		t.Errorf("element above tip: %#v", batch[3].Error)
+	}
+
+	node.FailNext(1, http.StatusServiceUnavailable, "busy")
+	err := c.BatchCall(ctx, []rpc.BatchElem{{Method: "getblockcount"}})
+	if e, ok := err.(*rpc.HTTPError); !ok || e.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("failed batch: %#v", err)
+	}
+
+	node.FailNext(1, http.StatusOK, `{"id":null,"error":{"code":-32600,"message":"batch too large"}}`)
+	err = c.BatchCall(ctx, []rpc.BatchElem{{Method: "getblockcount"}})
+	if e, ok := err.(*rpc.Error); !ok || e.Code != -32600 {
+		t.Errorf("rejected batch: %#v", err)
+	}
+
+	node.FailNext(1, http.StatusOK, `[]`)
+	batch = []rpc.BatchElem{{Method: "getblockcount"}}
+	if err := c.BatchCall(ctx, batch); err != nil || batch[0].Error == nil {
+		t.Errorf("missing response: %v, %v", err, batch[0].Error)
+	}
+}
@@ -0,0 +1,278 @@
+package tron
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"service_template/logger"
+	"service_template/nodes/rpc"
+	"service_template/tracer"
+)
+
+const (
+	// APIKeyHeader authenticates requests to TronGrid
+	APIKeyHeader = "TRON-PRO-API-KEY"
+
+	defaultTimeout = 30 * time.Second
+	maxErrorBody   = 4 << 10
+	// getblockbylimitnext returns at most 100 blocks
+	maxBlockRange = 100
+)
+
+// APIError is an error reported by the node in a 200 response
+type APIError struct {
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return "tron api error: " + e.Message
+}
+
+// Client talks to the HTTP API of a java-tron full node
+type Client struct {
+	url     string
+	http    tracer.HTTPClient
+	apiKey  string
+	timeout time.Duration
+}
+
+type Option func(*Client)
+
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithTimeout bounds each call unless the call context has an earlier deadline
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+func WithHTTPClient(h tracer.HTTPClient) Option {
+	return func(c *Client) { c.http = h }
+}
+
+// New creates a client for the node url, e.g. https://api.trongrid.io
+func New(url string, opts ...Option) *Client {
+	c := &Client{
+		url:     strings.TrimRight(url, "/"),
+		timeout: defaultTimeout,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if c.http == nil {
+		c.http = tracer.NewTraceHTTPClient(nil, nil)
+	}
+
+	return c
+}
+
+type Contract struct {
+	Type      string `json:"type"`
+	Parameter struct {
+		Value struct {
+			OwnerAddress    string `json:"owner_address"`
+			ToAddress       string `json:"to_address"`
+			ContractAddress string `json:"contract_address"`
+			Amount          int64  `json:"amount"`
+			Data            string `json:"data"`
+		} `json:"value"`
+	} `json:"parameter"`
+}
+
+type Ret struct {
+	ContractRet string `json:"contractRet"`
+}
+
+type Transaction struct {
+	TxID    string `json:"txID"`
+	Ret     []Ret  `json:"ret"`
+	RawData struct {
+		Contract  []Contract `json:"contract"`
+		Timestamp int64      `json:"timestamp"`
+	} `json:"raw_data"`
+}
+
+// Success reports whether the node executed the transaction successfully
+func (t Transaction) Success() bool {
+	return len(t.Ret) == 0 || t.Ret[0].ContractRet == "" || t.Ret[0].ContractRet == "SUCCESS"
+}
+
+type Block struct {
+	BlockID     string `json:"blockID"`
+	BlockHeader struct {
+		RawData struct {
+			Number     int64  `json:"number"`
+			Timestamp  int64  `json:"timestamp"`
+			ParentHash string `json:"parentHash"`
+		} `json:"raw_data"`
+	} `json:"block_header"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+func (b *Block) Number() int64 {
+	return b.BlockHeader.RawData.Number
+}
+
+func (b *Block) ParentHash() string {
+	return b.BlockHeader.RawData.ParentHash
+}
+
+// Time is the block time, the API reports milliseconds
+func (b *Block) Time() time.Time {
+	return time.Unix(0, b.BlockHeader.RawData.Timestamp*int64(time.Millisecond)).UTC()
+}
+
+type Log struct {
+	Address string   `json:"address"`
+	Topics  []string `json:"topics"`
+	Data    string   `json:"data"`
+}
+
+type TransactionInfo struct {
+	ID              string `json:"id"`
+	BlockNumber     int64  `json:"blockNumber"`
+	BlockTimeStamp  int64  `json:"blockTimeStamp"`
+	ContractAddress string `json:"contract_address"`
+	Receipt         struct {
+		Result string `json:"result"`
+	} `json:"receipt"`
+	Log []Log `json:"log"`
+}
+
+func (c *Client) post(ctx context.Context, path string, body, result interface{}) error {
+	log := logger.FromContext(ctx).WithField("m", "post")
+	log.Tracef("post:: path: %v", path)
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set(APIKeyHeader, c.apiKey)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return errors.Wrap(rpc.ErrTimeout, err.Error())
+		}
+		return err
+	}
+	defer res.Body.Close()
+
+	respBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return errors.Wrap(rpc.ErrTimeout, err.Error())
+		}
+		return err
+	}
+	if res.StatusCode/100 != 2 {
+		if len(respBody) > maxErrorBody {
+			respBody = respBody[:maxErrorBody]
+		}
+		return &rpc.HTTPError{StatusCode: res.StatusCode, Body: string(respBody)}
+	}
+
+	// failures come back as 200 with an Error field
+	var apiErr struct {
+		Error string `json:"Error"`
+	}
+	if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Error != "" {
+		return &APIError{Message: apiErr.Error}
+	}
+
+	return json.Unmarshal(respBody, result)
+}
+
+func (c *Client) GetNowBlock(ctx context.Context) (*Block, error) {
+	var b Block
+	if err := c.post(ctx, "/wallet/getnowblock", struct{}{}, &b); err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// GetBlockByNum returns nil above the tip, the node answers with an empty object
+func (c *Client) GetBlockByNum(ctx context.Context, num int64) (*Block, error) {
+	var b Block
+	if err := c.post(ctx, "/wallet/getblockbynum", map[string]int64{"num": num}, &b); err != nil {
+		return nil, err
+	}
+	if b.BlockID == "" {
+		return nil, nil
+	}
+
+	return &b, nil
+}
+
+// GetBlocks fetches blocks [from, from+count) in batches of the API limit, stopping at the tip
+func (c *Client) GetBlocks(ctx context.Context, from int64, count int) ([]*Block, error) {
+	blocks := make([]*Block, 0, count)
+	for count > 0 {
+		n := count
+		if n > maxBlockRange {
+			n = maxBlockRange
+		}
+
+		var res struct {
+			Block []*Block `json:"block"`
+		}
+		arg := map[string]int64{"startNum": from, "endNum": from + int64(n)}
+		if err := c.post(ctx, "/wallet/getblockbylimitnext", arg, &res); err != nil {
+			return nil, err
+		}
+		for i, b := range res.Block {
+			if b.Number() != from+int64(i) {
+				return nil, fmt.Errorf("getblockbylimitnext: block %v at position %v", b.Number(), from+int64(i))
+			}
+		}
+		blocks = append(blocks, res.Block...)
+		if len(res.Block) < n {
+			break
+		}
+
+		from += int64(n)
+		count -= n
+	}
+
+	return blocks, nil
+}
+
+// GetTransactionInfoByBlockNum returns execution results with event logs of a block
+func (c *Client) GetTransactionInfoByBlockNum(ctx context.Context, num int64) ([]TransactionInfo, error) {
+	var raw json.RawMessage
+	if err := c.post(ctx, "/wallet/gettransactioninfobyblocknum", map[string]int64{"num": num}, &raw); err != nil {
+		return nil, err
+	}
+	// blocks without transactions are answered with an empty object
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		return nil, nil
+	}
+
+	var infos []TransactionInfo
+	err := json.Unmarshal(raw, &infos)
+
+	return infos, err
+}
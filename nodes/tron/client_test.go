@@ -0,0 +1,90 @@
+package tron_test
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"service_template/nodes/nodetest"
+	"service_template/nodes/rpc"
+	"service_template/nodes/tron"
+)
+
+const (
+	alice    = "411111111111111111111111111111111111111111"
+	bob      = "412222222222222222222222222222222222222222"
+	contract = "413333333333333333333333333333333333333333"
+)
+
+func TestClient(t *testing.T) {
+	node := nodetest.NewTron("key")
+	defer node.Close()
+	ctx := context.Background()
+	c := tron.New(node.URL+"/", tron.WithAPIKey("key"))
+
+	call, info := nodetest.TRC20Transfer(contract, alice, bob, big.NewInt(7))
+	id1 := node.Mine([]tron.Transaction{nodetest.TRXTransfer(alice, bob, 100), call}, []tron.TransactionInfo{{}, info})
+	node.Mine(nil, nil)
+
+	now, err := c.GetNowBlock(ctx)
+	if err != nil || now.Number() != 2 || now.ParentHash() != id1 {
+		t.Fatalf("now block %+v, %v", now, err)
+	}
+
+	b, err := c.GetBlockByNum(ctx, 1)
+	if err != nil || b == nil || b.BlockID != id1 || len(b.Transactions) != 2 || !b.Transactions[0].Success() {
+		t.Fatalf("block 1 %+v, %v", b, err)
+	}
+	if b.Transactions[0].RawData.Contract[0].Parameter.Value.Amount != 100 {
+		t.Errorf("amount %+v", b.Transactions[0].RawData.Contract[0])
+	}
+	if b, err := c.GetBlockByNum(ctx, 3); err != nil || b != nil {
+		t.Errorf("block above tip %+v, %v", b, err)
+	}
+
+	infos, err := c.GetTransactionInfoByBlockNum(ctx, 1)
+	if err != nil || len(infos) != 2 || infos[1].ID != b.Transactions[1].TxID || len(infos[1].Log) != 1 {
+		t.Fatalf("infos %+v, %v", infos, err)
+	}
+	if infos, err := c.GetTransactionInfoByBlockNum(ctx, 2); err != nil || infos != nil {
+		t.Errorf("infos of an empty block %+v, %v", infos, err)
+	}
+
+	_, err = tron.New(node.URL).GetNowBlock(ctx)
+	if e, ok := err.(*rpc.HTTPError); !ok || e.StatusCode != http.StatusUnauthorized {
+		t.Errorf("without key: %#v", err)
+	}
+}
+
+func TestGetBlocks(t *testing.T) {
+	node := nodetest.NewTron("")
+	defer node.Close()
+	ctx := context.Background()
+	c := tron.New(node.URL)
+
+	for i := 0; i < 150; i++ {
+		node.Mine(nil, nil)
+	}
+
+	// more than the API limit of 100 per request, stopping at the tip
+	blocks, err := c.GetBlocks(ctx, 20, 200)
+	if err != nil || len(blocks) != 131 {
+		t.Fatalf("%v blocks, %v", len(blocks), err)
+	}
+	for i, b := range blocks {
+		if b.Number() != int64(20+i) {
+			t.Fatalf("block %v at position %v", b.Number(), i)
+		}
+	}
+	if node.Requests() != 2 {
+		t.Errorf("fetched in %v requests", node.Requests())
+	}
+
+	node.FailNext(1, http.StatusOK, `{"Error":"class java.lang.NullPointerException"}`)
+	if _, err := c.GetBlocks(ctx, 0, 10); err == nil {
+		t.Error("api error ignored")
+	} else if _, ok := err.(*tron.APIError); !ok {
+		t.Errorf("api error: %#v", err)
+	}
+}
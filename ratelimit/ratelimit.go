@@ -0,0 +1,153 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket refilled with Requests tokens every Per, holding at most Burst tokens
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// Rate is the refill rate in tokens per second
+func (l Limit) Rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Valid reports whether the limit can admit requests
+func (l Limit) Valid() bool {
+	return l.Requests > 0 && l.Per > 0 && l.Burst > 0
+}
+
+// Result of taking a token
+type Result struct {
+	Allowed bool
+	// whole tokens left after the request
+	Remaining int
+	// time until the bucket is full again
+	Reset time.Duration
+	// time until a token is available, zero if the request was allowed
+	RetryAfter time.Duration
+}
+
+func result(l Limit, allowed bool, tokens float64) Result {
+	rate := l.Rate()
+	r := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     time.Duration((float64(l.Burst) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		r.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+
+	return r
+}
+
+// Store keeps token buckets
+type Store interface {
+	Take(ctx context.Context, key string, l Limit) (Result, error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryStore keeps buckets of one process
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, l Limit) (Result, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(float64(l.Burst), b.tokens+elapsed.Seconds()*l.Rate())
+		b.updated = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	return result(l, allowed, b.tokens), nil
+}
+
+// Prune drops buckets unused since before, they would be full anyway
+func (s *MemoryStore) Prune(before time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for key, b := range s.buckets {
+		if b.updated.Before(before) {
+			delete(s.buckets, key)
+			n++
+		}
+	}
+
+	return n
+}
+
+// Run prunes buckets idle for longer than idle every interval until ctx is done
+func (s *MemoryStore) Run(ctx context.Context, interval, idle time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.Prune(s.now().Add(-idle))
+		}
+	}
+}
+
+// SharedBuckets is the database side of a shared store
+type SharedBuckets interface {
+	TakeRateLimitToken(ctx context.Context, key string, rate float64, burst int) (bool, float64, error)
+}
+
+// SharedStore keeps buckets in the database so replicas share them. When the database fails
+// it falls back to per-process buckets rather than rejecting or admitting everything
+type SharedStore struct {
+	DB       SharedBuckets
+	Fallback *MemoryStore
+}
+
+func NewSharedStore(db SharedBuckets) *SharedStore {
+	return &SharedStore{DB: db, Fallback: NewMemoryStore()}
+}
+
+func (s *SharedStore) Take(ctx context.Context, key string, l Limit) (Result, error) {
+	allowed, tokens, err := s.DB.TakeRateLimitToken(ctx, key, l.Rate(), l.Burst)
+	if err != nil {
+		r, _ := s.Fallback.Take(ctx, key, l)
+
+		return r, err
+	}
+
+	return result(l, allowed, tokens), nil
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+	// one token every 6 seconds, 3 at once
+	l := Limit{Requests: 10, Per: time.Minute, Burst: 3}
+
+	steps := []struct {
+		name       string
+		advance    time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{"first", 0, true, 2, 0},
+		{"second", 0, true, 1, 0},
+		{"third", 0, true, 0, 0},
+		{"exhausted", 0, false, 0, 6 * time.Second},
+		{"partly refilled", 3 * time.Second, false, 0, 3 * time.Second},
+		{"refilled", 3 * time.Second, true, 0, 0},
+		{"full after idling", time.Hour, true, 2, 0},
+	}
+	for _, st := range steps {
+		now = now.Add(st.advance)
+		r, err := s.Take(ctx, "k", l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Allowed != st.allowed || r.Remaining != st.remaining || r.RetryAfter != st.retryAfter {
+			t.Errorf("%v: %+v, want allowed %v, remaining %v, retry after %v",
+				st.name, r, st.allowed, st.remaining, st.retryAfter)
+		}
+	}
+
+	// buckets are per key
+	if r, _ := s.Take(ctx, "other", l); !r.Allowed || r.Remaining != 2 {
+		t.Errorf("other key: %+v", r)
+	}
+
+	// an exhausted bucket is full again after Reset
+	for i := 0; i < 3; i++ {
+		s.Take(ctx, "reset", l)
+	}
+	r, _ := s.Take(ctx, "reset", l)
+	if r.Reset != 18*time.Second {
+		t.Errorf("reset %v", r.Reset)
+	}
+	now = now.Add(r.Reset)
+	if r, _ := s.Take(ctx, "reset", l); r.Remaining != 2 {
+		t.Errorf("after reset: %+v", r)
+	}
+
+	if n := s.Prune(now); n != 2 {
+		t.Errorf("pruned %v", n)
+	}
+}
+
+type fakeBuckets struct {
+	allowed bool
+	tokens  float64
+	err     error
+}
+
+func (f *fakeBuckets) TakeRateLimitToken(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+	return f.allowed, f.tokens, f.err
+}
+
+func TestSharedStoreTake(t *testing.T) {
+	ctx := context.Background()
+	l := Limit{Requests: 10, Per: time.Minute, Burst: 3}
+	db := &fakeBuckets{allowed: false, tokens: 0.5}
+	s := NewSharedStore(db)
+
+	r, err := s.Take(ctx, "k", l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Allowed || r.RetryAfter != 3*time.Second || r.Reset != 15*time.Second {
+		t.Errorf("denied: %+v", r)
+	}
+
+	// a failing database falls back to the process bucket
+	db.err = errors.New("connection refused")
+	for i := 0; i < 3; i++ {
+		if r, err := s.Take(ctx, "k", l); err == nil || !r.Allowed {
+			t.Errorf("fallback %d: %+v, %v", i, r, err)
+		}
+	}
+	if r, _ := s.Take(ctx, "k", l); r.Allowed {
+		t.Errorf("fallback not exhausted: %+v", r)
+	}
+}
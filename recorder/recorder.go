@@ -0,0 +1,136 @@
+package recorder
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+)
+
+var ErrNotHijacker = errors.New("response writer does not support hijacking")
+
+// Recorder passes a response through to the client and records its status, size and, when
+// asked to, the first bytes of its body. Flush, Hijack and Push reach the wrapped writer, Hijack
+// and Push fail when it does not support them
+type Recorder struct {
+	http.ResponseWriter
+
+	status    int
+	bytes     int64
+	body      *bytes.Buffer
+	bodyLimit int
+	truncated bool
+}
+
+// Wrap records w, a writer that already is a Recorder is returned as is so that nested
+// middlewares share it
+func Wrap(w http.ResponseWriter) *Recorder {
+	if rec, ok := w.(*Recorder); ok {
+		return rec
+	}
+
+	return &Recorder{ResponseWriter: w}
+}
+
+// CaptureBody keeps a copy of up to limit bytes of the body written from now on
+func (rec *Recorder) CaptureBody(limit int) {
+	if limit > rec.bodyLimit {
+		rec.bodyLimit = limit
+	}
+	if rec.body == nil {
+		rec.body = &bytes.Buffer{}
+	}
+}
+
+// Status is the status sent to the client, 200 when the handler sent none
+func (rec *Recorder) Status() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+
+	return rec.status
+}
+
+// Bytes is the size of the body sent to the client
+func (rec *Recorder) Bytes() int64 {
+	return rec.bytes
+}
+
+// Body returns the captured copy of the body and whether it was cut at the limit
+func (rec *Recorder) Body() ([]byte, bool) {
+	if rec.body == nil {
+		return nil, false
+	}
+
+	return rec.body.Bytes(), rec.truncated
+}
+
+func (rec *Recorder) WriteHeader(status int) {
+	// informational responses precede the final one
+	if rec.status == 0 && status >= http.StatusOK {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *Recorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(p)
+	rec.bytes += int64(n)
+
+	if rec.body != nil && n > 0 {
+		room := rec.bodyLimit - rec.body.Len()
+		if room < n {
+			rec.truncated = true
+			if room < 0 {
+				room = 0
+			}
+			rec.body.Write(p[:room])
+		} else {
+			rec.body.Write(p[:n])
+		}
+	}
+
+	return n, err
+}
+
+// Flush sends buffered data to the client if the wrapped writer supports it
+func (rec *Recorder) Flush() {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hands the connection over to the caller, the response counts as switching protocols
+func (rec *Recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, ErrNotHijacker
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && rec.status == 0 {
+		rec.status = http.StatusSwitchingProtocols
+	}
+
+	return conn, rw, err
+}
+
+// Push initiates an HTTP/2 server push
+func (rec *Recorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rec.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (rec *Recorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
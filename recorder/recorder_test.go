@@ -0,0 +1,94 @@
+package recorder
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecorderStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(rec *Recorder)
+		want  int
+	}{
+		{"nothing sent", func(rec *Recorder) {}, http.StatusOK},
+		{"explicit", func(rec *Recorder) { rec.WriteHeader(http.StatusCreated) }, http.StatusCreated},
+		{"implicit by write", func(rec *Recorder) { rec.Write([]byte("x")) }, http.StatusOK},
+		{"status after write", func(rec *Recorder) {
+			rec.Write([]byte("x"))
+			rec.WriteHeader(http.StatusInternalServerError)
+		}, http.StatusOK},
+		{"second status", func(rec *Recorder) {
+			rec.WriteHeader(http.StatusNotFound)
+			rec.WriteHeader(http.StatusInternalServerError)
+		}, http.StatusNotFound},
+		{"informational first", func(rec *Recorder) {
+			rec.WriteHeader(http.StatusEarlyHints)
+			rec.WriteHeader(http.StatusContinue)
+			rec.WriteHeader(http.StatusAccepted)
+		}, http.StatusAccepted},
+		{"flush", func(rec *Recorder) {
+			rec.Flush()
+			rec.WriteHeader(http.StatusTeapot)
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := Wrap(httptest.NewRecorder())
+		tt.write(rec)
+		if got := rec.Status(); got != tt.want {
+			t.Errorf("%v: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRecorderBody(t *testing.T) {
+	w := httptest.NewRecorder()
+	rec := Wrap(w)
+	rec.Write([]byte("before "))
+	rec.CaptureBody(5)
+	rec.Write([]byte("abc"))
+	rec.Write([]byte("defg"))
+	rec.Write([]byte("h"))
+
+	if body, truncated := rec.Body(); string(body) != "abcde" || !truncated {
+		t.Errorf("body %q, truncated %v", body, truncated)
+	}
+	if rec.Bytes() != 15 || w.Body.String() != "before abcdefgh" {
+		t.Errorf("sent %v bytes: %q", rec.Bytes(), w.Body)
+	}
+
+	rec = Wrap(httptest.NewRecorder())
+	rec.CaptureBody(3)
+	rec.Write([]byte("abc"))
+	if body, truncated := rec.Body(); string(body) != "abc" || truncated {
+		t.Errorf("body at the limit %q, truncated %v", body, truncated)
+	}
+
+	if body, truncated := Wrap(httptest.NewRecorder()).Body(); body != nil || truncated {
+		t.Errorf("body captured without CaptureBody: %q", body)
+	}
+}
+
+func TestRecorderWrapped(t *testing.T) {
+	w := httptest.NewRecorder()
+	rec := Wrap(w)
+	if Wrap(rec) != rec {
+		t.Error("recorder wrapped twice")
+	}
+	if rec.Unwrap() != w {
+		t.Error("Unwrap")
+	}
+
+	rec.Flush()
+	if !w.Flushed {
+		t.Error("flush did not reach the writer")
+	}
+	if _, _, err := rec.Hijack(); !errors.Is(err, ErrNotHijacker) {
+		t.Errorf("hijack: %v", err)
+	}
+	if err := rec.Push("/style.css", nil); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("push: %v", err)
+	}
+}
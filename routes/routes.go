@@ -0,0 +1,131 @@
+package routes
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Rate limit classes of routes
+const (
+	RateDefault = "default"
+	RateAuth    = "auth"
+	RateExport  = "export"
+)
+
+// Options declare how middlewares treat a route
+type Options struct {
+	// Public routes are served without credentials
+	Public bool
+	// Permissions a caller needs, all of them
+	Permissions []string
+	// RateClass selects the rate limit bucket of the route, RateDefault if empty
+	RateClass string
+	// Timeout bounds the time to respond, no bound if zero
+	Timeout time.Duration
+	// Streaming routes write their response as they go, their timeout only bounds
+	// the request context, which they check between writes
+	Streaming bool
+	// BodyLimit caps the request body in bytes, the registry default if zero, no cap if negative
+	BodyLimit int64
+	// LogBody adds the request body to the access log, sensitive keys redacted
+	LogBody bool
+}
+
+// Option sets a route option
+type Option func(*Options)
+
+// Public serves the route without credentials
+func Public() Option {
+	return func(o *Options) { o.Public = true }
+}
+
+// Permissions requires the caller to have all permissions
+func Permissions(permissions ...string) Option {
+	return func(o *Options) { o.Permissions = append(o.Permissions, permissions...) }
+}
+
+// RateClass puts the route into a rate limit class
+func RateClass(class string) Option {
+	return func(o *Options) { o.RateClass = class }
+}
+
+// Timeout bounds the time to respond of the route
+func Timeout(d time.Duration) Option {
+	return func(o *Options) { o.Timeout = d }
+}
+
+// Streaming marks a route that writes its response as it goes
+func Streaming() Option {
+	return func(o *Options) { o.Streaming = true }
+}
+
+// BodyLimit caps the request body of the route, a negative limit removes the cap
+func BodyLimit(bytes int64) Option {
+	return func(o *Options) { o.BodyLimit = bytes }
+}
+
+// LogBody adds the request body of the route to the access log
+func LogBody() Option {
+	return func(o *Options) { o.LogBody = true }
+}
+
+// Registry holds the options of routes. Requests that match no registered route get
+// the defaults, which are not public
+type Registry struct {
+	Defaults Options
+
+	mu     sync.RWMutex
+	routes map[*mux.Route]*Options
+}
+
+func NewRegistry(defaults Options) *Registry {
+	if defaults.RateClass == "" {
+		defaults.RateClass = RateDefault
+	}
+
+	return &Registry{Defaults: defaults, routes: map[*mux.Route]*Options{}}
+}
+
+// Set declares the options of a route on top of the defaults
+func (reg *Registry) Set(route *mux.Route, opts ...Option) *Options {
+	o := reg.Defaults
+	o.Permissions = nil
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.RateClass == "" {
+		o.RateClass = RateDefault
+	}
+	if o.BodyLimit == 0 {
+		o.BodyLimit = reg.Defaults.BodyLimit
+	}
+
+	reg.mu.Lock()
+	reg.routes[route] = &o
+	reg.mu.Unlock()
+
+	return &o
+}
+
+// Of returns the options of a matched route, the defaults for unregistered ones
+func (reg *Registry) Of(route *mux.Route) *Options {
+	reg.mu.RLock()
+	o, ok := reg.routes[route]
+	reg.mu.RUnlock()
+	if ok {
+		return o
+	}
+
+	d := reg.Defaults
+
+	return &d
+}
+
+// ForRequest returns the options of the route a request matched, mux.CurrentRoute is only
+// set inside handlers and router middlewares
+func (reg *Registry) ForRequest(r *http.Request) *Options {
+	return reg.Of(mux.CurrentRoute(r))
+}
@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+// GetAdminUsers returns users sorted by username
+func (a *Storage) GetAdminUsers(ctx context.Context) (ret []models.AdminUser, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAdminUsers")
+	log.Debugf("GetAdminUsers:: ")
+
+	err = a.DB.Order("username").Find(&ret).Error
+
+	return
+}
+
+// GetAdminUser returns nil if the user does not exist
+func (a *Storage) GetAdminUser(ctx context.Context, id uint) (*models.AdminUser, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAdminUser")
+	log.Debugf("GetAdminUser:: id: %v", id)
+
+	u := new(models.AdminUser)
+	err := a.DB.Where("id = ?", id).First(u).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// GetAdminUserByName returns nil if no user has the username
+func (a *Storage) GetAdminUserByName(ctx context.Context, username string) (*models.AdminUser, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAdminUserByName")
+	log.Debugf("GetAdminUserByName:: username: %v", username)
+
+	u := new(models.AdminUser)
+	err := a.DB.Where("username = ?", username).First(u).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (a *Storage) CreateAdminUser(ctx context.Context, u *models.AdminUser) error {
+	log := logger.FromContext(ctx).WithField("m", "CreateAdminUser")
+	log.Debugf("CreateAdminUser:: username: %v", u.Username)
+
+	return a.DB.Create(u).Error
+}
+
+// UpdateAdminUser replaces the roles, permissions and disabled flag of a user,
+// returns false if the user does not exist
+func (a *Storage) UpdateAdminUser(ctx context.Context, u *models.AdminUser) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "UpdateAdminUser")
+	log.Debugf("UpdateAdminUser:: id: %v", u.ID)
+
+	res := a.DB.Model(&models.AdminUser{}).Where("id = ?", u.ID).Updates(map[string]interface{}{
+		"roles":       u.Roles,
+		"permissions": u.Permissions,
+		"disabled":    u.Disabled,
+	})
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// SetAdminUserPassword replaces the password hash and lifts a lockout
+func (a *Storage) SetAdminUserPassword(ctx context.Context, id uint, hash string) error {
+	log := logger.FromContext(ctx).WithField("m", "SetAdminUserPassword")
+	log.Debugf("SetAdminUserPassword:: id: %v", id)
+
+	return a.DB.Model(&models.AdminUser{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password_hash":       hash,
+		"password_changed_at": time.Now(),
+		"failed_logins":       0,
+		"locked_until":        nil,
+	}).Error
+}
+
+// UnlockAdminUser lifts a lockout, returns false if the user does not exist
+func (a *Storage) UnlockAdminUser(ctx context.Context, id uint) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "UnlockAdminUser")
+	log.Debugf("UnlockAdminUser:: id: %v", id)
+
+	res := a.DB.Model(&models.AdminUser{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"failed_logins": 0,
+		"locked_until":  nil,
+	})
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// RecordLoginFailure counts a failed login, the maxFailures-th consecutive failure locks the user
+// until lockUntil and restarts the count. It reports whether the user got locked
+func (a *Storage) RecordLoginFailure(ctx context.Context, id uint, maxFailures int, lockUntil time.Time) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "RecordLoginFailure")
+	log.Debugf("RecordLoginFailure:: id: %v", id)
+
+	var row struct {
+		Locked bool
+	}
+	err := a.DB.Raw("UPDATE admin_users SET "+
+		"locked_until = CASE WHEN failed_logins + 1 >= ? THEN ? ELSE locked_until END, "+
+		"failed_logins = CASE WHEN failed_logins + 1 >= ? THEN 0 ELSE failed_logins + 1 END "+
+		"WHERE id = ? RETURNING failed_logins = 0 AS locked",
+		maxFailures, lockUntil, maxFailures, id).Scan(&row).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return false, nil
+	}
+
+	return row.Locked, err
+}
+
+// RecordLoginSuccess resets the failed login count and optionally replaces the password hash
+// with a stronger one
+func (a *Storage) RecordLoginSuccess(ctx context.Context, id uint, rehash string) error {
+	log := logger.FromContext(ctx).WithField("m", "RecordLoginSuccess")
+	log.Debugf("RecordLoginSuccess:: id: %v", id)
+
+	fields := map[string]interface{}{
+		"failed_logins": 0,
+		"last_login_at": time.Now(),
+	}
+	if rehash != "" {
+		fields["password_hash"] = rehash
+	}
+
+	return a.DB.Model(&models.AdminUser{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (a *Storage) CreateAdminSession(ctx context.Context, s *models.AdminSession) error {
+	log := logger.FromContext(ctx).WithField("m", "CreateAdminSession")
+	log.Debugf("CreateAdminSession:: userID: %v", s.UserID)
+
+	return a.DB.Create(s).Error
+}
+
+// GetAdminSessionByPrefix returns nil if no session has the access token prefix
+func (a *Storage) GetAdminSessionByPrefix(ctx context.Context, prefix string) (*models.AdminSession, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAdminSessionByPrefix")
+	log.Debugf("GetAdminSessionByPrefix:: prefix: %v", prefix)
+
+	s := new(models.AdminSession)
+	err := a.DB.Where("token_prefix = ?", prefix).First(s).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// GetAdminSessionByRefreshPrefix returns nil if no session has the refresh token prefix
+func (a *Storage) GetAdminSessionByRefreshPrefix(ctx context.Context, prefix string) (*models.AdminSession, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAdminSessionByRefreshPrefix")
+	log.Debugf("GetAdminSessionByRefreshPrefix:: prefix: %v", prefix)
+
+	s := new(models.AdminSession)
+	err := a.DB.Where("refresh_prefix = ?", prefix).First(s).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// RefreshAdminSession replaces both tokens of an unrevoked session if its refresh token is still
+// the one with oldRefreshPrefix, so a refresh token is used at most once. Returns false otherwise
+func (a *Storage) RefreshAdminSession(ctx context.Context, s *models.AdminSession, oldRefreshPrefix string) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "RefreshAdminSession")
+	log.Debugf("RefreshAdminSession:: id: %v", s.ID)
+
+	res := a.DB.Model(&models.AdminSession{}).
+		Where("id = ? AND refresh_prefix = ? AND revoked_at IS NULL", s.ID, oldRefreshPrefix).
+		Updates(map[string]interface{}{
+			"token_prefix":       s.TokenPrefix,
+			"token_hash":         s.TokenHash,
+			"expires_at":         s.ExpiresAt,
+			"refresh_prefix":     s.RefreshPrefix,
+			"refresh_hash":       s.RefreshHash,
+			"refresh_expires_at": s.RefreshExpiresAt,
+		})
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// GetAdminSessions returns the active sessions of a user, newest first
+func (a *Storage) GetAdminSessions(ctx context.Context, userID uint) (ret []models.AdminSession, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAdminSessions")
+	log.Debugf("GetAdminSessions:: userID: %v", userID)
+
+	err = a.DB.Where("user_id = ? AND revoked_at IS NULL AND refresh_expires_at > ?", userID, time.Now()).
+		Order("id desc").Find(&ret).Error
+
+	return
+}
+
+// RevokeAdminSession revokes a session, returns false if it is not active
+func (a *Storage) RevokeAdminSession(ctx context.Context, id uint) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "RevokeAdminSession")
+	log.Debugf("RevokeAdminSession:: id: %v", id)
+
+	res := a.DB.Model(&models.AdminSession{}).Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// RevokeAdminSessions revokes all sessions of a user but the one with id except,
+// and returns how many were revoked
+func (a *Storage) RevokeAdminSessions(ctx context.Context, userID, except uint) (int64, error) {
+	log := logger.FromContext(ctx).WithField("m", "RevokeAdminSessions")
+	log.Debugf("RevokeAdminSessions:: userID: %v, except: %v", userID, except)
+
+	res := a.DB.Model(&models.AdminSession{}).Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, except).
+		Update("revoked_at", time.Now())
+
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+type AlertFilter struct {
+	Status   string
+	Severity string
+	UserID   string
+	AssetID  string
+	RuleID   uint
+	Range    TimeRange
+}
+
+// TransferWindow selects outgoing transfers of a user and an asset for period rules
+type TransferWindow struct {
+	Asset           *models.Asset
+	Chain           *models.Chain
+	UserID          string
+	Range           TimeRange
+	IncludeInternal bool
+}
+
+// where returns conditions on a chain transaction table aliased t
+func (w TransferWindow) where() (string, []interface{}) {
+	q := "t.deleted_at IS NULL AND t.outgoing AND t.user_id = ? AND t.asset_id = ? AND t.timestamp >= ? AND t.timestamp < ?"
+	args := []interface{}{w.UserID, w.Asset.AssetID, w.Range.From, w.Range.To}
+	if !w.IncludeInternal {
+		q += " AND NOT EXISTS (SELECT 1 FROM internal_transactions i WHERE i.deleted_at IS NULL AND i.chain = ? AND i.tx_id = t.tx_id)"
+		args = append(args, w.Chain.Name)
+	}
+
+	return q, args
+}
+
+type transferSum struct {
+	Count  int64
+	Amount models.Amount
+}
+
+// GetAlertRules returns rules sorted by id, enabled rules of an asset if assetID is set
+func (a *Storage) GetAlertRules(ctx context.Context, assetID string) (ret []models.AlertRule, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAlertRules")
+	log.Debugf("GetAlertRules:: assetID: %v", assetID)
+
+	q := a.DB.Order("id")
+	if assetID != "" {
+		q = q.Where("enabled AND asset_id = ?", assetID)
+	}
+	err = q.Find(&ret).Error
+
+	return
+}
+
+// GetAlertRule returns nil if the rule does not exist
+func (a *Storage) GetAlertRule(ctx context.Context, id uint) (*models.AlertRule, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAlertRule")
+	log.Debugf("GetAlertRule:: id: %v", id)
+
+	r := new(models.AlertRule)
+	err := a.DB.Where("id = ?", id).First(r).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (a *Storage) CreateAlertRule(ctx context.Context, r *models.AlertRule) error {
+	log := logger.FromContext(ctx).WithField("m", "CreateAlertRule")
+	log.Debugf("CreateAlertRule:: name: %v", r.Name)
+
+	return a.DB.Create(r).Error
+}
+
+// UpdateAlertRule returns false if the rule does not exist
+func (a *Storage) UpdateAlertRule(ctx context.Context, r *models.AlertRule) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "UpdateAlertRule")
+	log.Debugf("UpdateAlertRule:: id: %v", r.ID)
+
+	res := a.DB.Model(&models.AlertRule{}).Where("id = ?", r.ID).
+		Updates(map[string]interface{}{
+			"name":             r.Name,
+			"kind":             r.Kind,
+			"asset_id":         r.AssetID,
+			"user_id":          r.UserID,
+			"threshold":        r.Threshold,
+			"decimals":         r.Decimals,
+			"count":            r.Count,
+			"window_seconds":   r.WindowSeconds,
+			"include_internal": r.IncludeInternal,
+			"severity":         r.Severity,
+			"enabled":          r.Enabled,
+		})
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteAlertRule soft-deletes a rule, returns false if the rule does not exist
+func (a *Storage) DeleteAlertRule(ctx context.Context, id uint) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "DeleteAlertRule")
+	log.Debugf("DeleteAlertRule:: id: %v", id)
+
+	res := a.DB.Where("id = ?", id).Delete(&models.AlertRule{})
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// SumTransfers counts and sums outgoing transfers in the window
+func (a *Storage) SumTransfers(ctx context.Context, w TransferWindow) (int64, models.Amount, error) {
+	log := logger.FromContext(ctx).WithField("m", "SumTransfers")
+	log.Debugf("SumTransfers:: userID: %v, assetID: %v, range: %+v", w.UserID, w.Asset.AssetID, w.Range)
+
+	where, args := w.where()
+	var s transferSum
+	err := a.DB.Raw("SELECT count(*) AS count, coalesce(sum(t.amount), 0)::numeric(78,0) AS amount FROM "+
+		models.TransactionsTable(w.Chain.Kind)+" t WHERE "+where, args...).Scan(&s).Error
+	if err != nil {
+		return 0, models.Amount{}, err
+	}
+
+	return s.Count, s.Amount.WithDecimals(w.Asset.Decimals), nil
+}
+
+// SumNewAddressTransfers counts and sums outgoing transfers in the window of at most max
+// to addresses the user had not paid before the window
+func (a *Storage) SumNewAddressTransfers(ctx context.Context, w TransferWindow, max models.Amount) (int64, models.Amount, error) {
+	log := logger.FromContext(ctx).WithField("m", "SumNewAddressTransfers")
+	log.Debugf("SumNewAddressTransfers:: userID: %v, assetID: %v, range: %+v", w.UserID, w.Asset.AssetID, w.Range)
+
+	table := models.TransactionsTable(w.Chain.Kind)
+	where, args := w.where()
+	args = append(args, max.Rescale(w.Asset.Decimals), w.Range.From)
+
+	var s transferSum
+	err := a.DB.Raw("SELECT count(*) AS count, coalesce(sum(t.amount), 0)::numeric(78,0) AS amount FROM "+table+
+		" t WHERE "+where+" AND t.amount <= ?"+
+		" AND NOT EXISTS (SELECT 1 FROM "+table+" p WHERE p.deleted_at IS NULL AND p.outgoing"+
+		" AND p.user_id = t.user_id AND p.asset_id = t.asset_id AND p.to_address = t.to_address AND p.timestamp < ?)",
+		args...).Scan(&s).Error
+	if err != nil {
+		return 0, models.Amount{}, err
+	}
+
+	return s.Count, s.Amount.WithDecimals(w.Asset.Decimals), nil
+}
+
+// IsInternalTransaction reports whether the transaction is registered as internal
+func (a *Storage) IsInternalTransaction(ctx context.Context, chain, txID string) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "IsInternalTransaction")
+	log.Debugf("IsInternalTransaction:: chain: %v, txID: %v", chain, txID)
+
+	var n int
+	err := a.DB.Model(&models.InternalTransaction{}).Where("chain = ? AND tx_id = ?", chain, txID).Count(&n).Error
+
+	return n > 0, err
+}
+
+// CreateAlert stores an alert unless the rule already alerted with the same key,
+// it returns false in that case
+func (a *Storage) CreateAlert(ctx context.Context, al *models.Alert) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "CreateAlert")
+	log.Debugf("CreateAlert:: ruleID: %v, key: %v", al.RuleID, al.Key)
+
+	err := a.DB.Set("gorm:insert_option", "ON CONFLICT (rule_id, key) DO NOTHING").Create(al).Error
+	if conflictSkipped(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (a *Storage) alertsQuery(f AlertFilter) *gorm.DB {
+	q := a.DB.Model(&models.Alert{})
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	if f.Severity != "" {
+		q = q.Where("severity = ?", f.Severity)
+	}
+	if f.UserID != "" {
+		q = q.Where("user_id = ?", f.UserID)
+	}
+	if f.AssetID != "" {
+		q = q.Where("asset_id = ?", f.AssetID)
+	}
+	if f.RuleID != 0 {
+		q = q.Where("rule_id = ?", f.RuleID)
+	}
+
+	return f.Range.apply(q, "created_at")
+}
+
+// GetAlerts returns alerts sorted from newest to oldest and the total count for the filter
+func (a *Storage) GetAlerts(ctx context.Context, f AlertFilter, p Page) (ret []models.Alert, total int, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAlerts")
+	log.Debugf("GetAlerts:: f: %+v, p: %+v", f, p)
+
+	if err = a.alertsQuery(f).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err = p.apply(a.alertsQuery(f)).Order("created_at desc, id desc").Find(&ret).Error
+
+	return
+}
+
+// AcknowledgeAlert sets the status of an alert, returns nil if the alert does not exist
+func (a *Storage) AcknowledgeAlert(ctx context.Context, id uint, status, note, by string) (*models.Alert, error) {
+	log := logger.FromContext(ctx).WithField("m", "AcknowledgeAlert")
+	log.Debugf("AcknowledgeAlert:: id: %v, status: %v", id, status)
+
+	now := time.Now()
+	res := a.DB.Model(&models.Alert{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          status,
+		"note":            note,
+		"acknowledged_by": by,
+		"acknowledged_at": &now,
+	})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	ret := new(models.Alert)
+	if err := a.DB.Where("id = ?", id).First(ret).Error; err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// GetUndeliveredAlerts returns the oldest alerts not delivered in fewer than maxAttempts attempts
+func (a *Storage) GetUndeliveredAlerts(ctx context.Context, maxAttempts, limit int) (ret []models.Alert, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetUndeliveredAlerts")
+	log.Debugf("GetUndeliveredAlerts:: maxAttempts: %v, limit: %v", maxAttempts, limit)
+
+	err = a.DB.Where("notified_at IS NULL AND notify_attempts < ?", maxAttempts).
+		Order("id").Limit(limit).Find(&ret).Error
+
+	return
+}
+
+// SetAlertDelivery records a delivery attempt and the notifiers that sent the alert in it,
+// a nil error marks the alert as delivered
+func (a *Storage) SetAlertDelivery(ctx context.Context, id uint, notifiedBy []string, deliveryErr error) error {
+	log := logger.FromContext(ctx).WithField("m", "SetAlertDelivery")
+	log.Debugf("SetAlertDelivery:: id: %v, notifiedBy: %v, err: %v", id, notifiedBy, deliveryErr)
+
+	fields := map[string]interface{}{
+		"notify_attempts": gorm.Expr("notify_attempts + 1"),
+		"notify_error":    "",
+		"notified_by":     gorm.Expr("notified_by || ?::text[]", pq.StringArray(notifiedBy)),
+	}
+	if deliveryErr != nil {
+		fields["notify_error"] = fmt.Sprint(deliveryErr)
+	} else {
+		fields["notified_at"] = time.Now()
+	}
+
+	return a.DB.Model(&models.Alert{}).Where("id = ?", id).Updates(fields).Error
+}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+// apiClientUsage collects last-used times in memory, so authentication does not write to the
+// database on every request. FlushAPIClientUsage stores them
+type apiClientUsage struct {
+	mu       sync.Mutex
+	lastUsed map[uint]time.Time
+}
+
+var (
+	// ErrAPIClientExists is returned for a client named like another unremoved one
+	ErrAPIClientExists = errors.New("api client already exists")
+	// ErrTokenPrefixTaken is returned when a new token's prefix is in use, another token should be generated
+	ErrTokenPrefixTaken = errors.New("token prefix taken")
+)
+
+// apiClientError maps unique violations of API client columns to errors callers handle
+func apiClientError(err error) error {
+	switch constraint, _ := uniqueViolation(err); constraint {
+	case "idx_api_clients_name":
+		return ErrAPIClientExists
+	case "idx_api_clients_token_prefix", "idx_api_clients_previous_token_prefix":
+		return ErrTokenPrefixTaken
+	}
+
+	return err
+}
+
+// GetAPIClientByPrefix returns nil if no client has the token prefix as its current
+// or previous token prefix. A current prefix wins over another client's previous one
+func (a *Storage) GetAPIClientByPrefix(ctx context.Context, prefix string) (*models.APIClient, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAPIClientByPrefix")
+	log.Debugf("GetAPIClientByPrefix:: prefix: %v", prefix)
+
+	c := new(models.APIClient)
+	err := a.DB.Where("token_prefix = ? OR previous_token_prefix = ?", prefix, prefix).
+		Order(gorm.Expr("token_prefix = ? DESC", prefix)).First(c).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GetAPIClient returns nil if the client does not exist
+func (a *Storage) GetAPIClient(ctx context.Context, id uint) (*models.APIClient, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAPIClient")
+	log.Debugf("GetAPIClient:: id: %v", id)
+
+	c := new(models.APIClient)
+	err := a.DB.Where("id = ?", id).First(c).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GetAPIClientByName returns nil if no client has the name
+func (a *Storage) GetAPIClientByName(ctx context.Context, name string) (*models.APIClient, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAPIClientByName")
+	log.Debugf("GetAPIClientByName:: name: %v", name)
+
+	c := new(models.APIClient)
+	err := a.DB.Where("name = ?", name).First(c).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GetAPIClients returns clients sorted by name, revoked clients only if withRevoked is set
+func (a *Storage) GetAPIClients(ctx context.Context, withRevoked bool) (ret []models.APIClient, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAPIClients")
+	log.Debugf("GetAPIClients:: withRevoked: %v", withRevoked)
+
+	q := a.DB.Order("name")
+	if !withRevoked {
+		q = q.Where("revoked_at IS NULL")
+	}
+	err = q.Find(&ret).Error
+
+	return
+}
+
+// CreateAPIClient returns ErrAPIClientExists or ErrTokenPrefixTaken for clashes with other clients
+func (a *Storage) CreateAPIClient(ctx context.Context, c *models.APIClient) error {
+	log := logger.FromContext(ctx).WithField("m", "CreateAPIClient")
+	log.Debugf("CreateAPIClient:: name: %v, kind: %v", c.Name, c.Kind)
+
+	return apiClientError(a.DB.Create(c).Error)
+}
+
+// UpdateAPIClientScope replaces the roles, permissions and allowed ranges of an unrevoked client,
+// returns false if there is no such client
+func (a *Storage) UpdateAPIClientScope(ctx context.Context, c *models.APIClient) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "UpdateAPIClientScope")
+	log.Debugf("UpdateAPIClientScope:: id: %v", c.ID)
+
+	res := a.DB.Model(&models.APIClient{}).Where("id = ? AND revoked_at IS NULL", c.ID).
+		Updates(map[string]interface{}{
+			"roles":       c.Roles,
+			"permissions": c.Permissions,
+			"allowed_ips": c.AllowedIPs,
+		})
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// RotateAPIClient replaces the token of an unrevoked client, the replaced token is accepted until
+// previousExpires. A nil expires keeps the current expiry. Returns false if there is no such client
+// and ErrTokenPrefixTaken if the new prefix is in use
+func (a *Storage) RotateAPIClient(ctx context.Context, id uint, prefix, hash string, previousExpires time.Time, expires *time.Time) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "RotateAPIClient")
+	log.Debugf("RotateAPIClient:: id: %v, previousExpires: %v", id, previousExpires)
+
+	fields := map[string]interface{}{
+		"previous_token_prefix": gorm.Expr("token_prefix"),
+		"previous_token_hash":   gorm.Expr("token_hash"),
+		"previous_expires_at":   previousExpires,
+		"token_prefix":          prefix,
+		"token_hash":            hash,
+	}
+	if expires != nil {
+		fields["expires_at"] = expires
+	}
+	res := a.DB.Model(&models.APIClient{}).Where("id = ? AND revoked_at IS NULL", id).Updates(fields)
+
+	return res.RowsAffected > 0, apiClientError(res.Error)
+}
+
+// RevokeAPIClient revokes the token of a client, returns false if there is no such unrevoked client
+func (a *Storage) RevokeAPIClient(ctx context.Context, name string) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "RevokeAPIClient")
+	log.Debugf("RevokeAPIClient:: name: %v", name)
+
+	res := a.DB.Model(&models.APIClient{}).Where("name = ? AND revoked_at IS NULL", name).
+		Update("revoked_at", time.Now())
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// RevokeAPIClientByID revokes the current and previous tokens of a client,
+// returns false if there is no such unrevoked client
+func (a *Storage) RevokeAPIClientByID(ctx context.Context, id uint) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "RevokeAPIClientByID")
+	log.Debugf("RevokeAPIClientByID:: id: %v", id)
+
+	res := a.DB.Model(&models.APIClient{}).Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// TouchAPIClient records that a client was used at the time, it does not block on the database
+func (a *Storage) TouchAPIClient(id uint, at time.Time) {
+	u := &a.apiClientUsage
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.lastUsed == nil {
+		u.lastUsed = map[uint]time.Time{}
+	}
+	if at.After(u.lastUsed[id]) {
+		u.lastUsed[id] = at
+	}
+}
+
+// FlushAPIClientUsage stores the last-used times recorded by TouchAPIClient.
+// Times that failed to store are kept for the next flush unless newer ones were recorded
+func (a *Storage) FlushAPIClientUsage(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithField("m", "FlushAPIClientUsage")
+
+	u := &a.apiClientUsage
+	u.mu.Lock()
+	pending := u.lastUsed
+	u.lastUsed = nil
+	u.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	log.Debugf("FlushAPIClientUsage:: clients: %v", len(pending))
+
+	var firstErr error
+	for id, at := range pending {
+		err := a.DB.Exec("UPDATE api_clients SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)",
+			at, id, at).Error
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			a.TouchAPIClient(id, at)
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+const (
+	defaultCatalogTTL = time.Minute
+	// reloads discarded because of an invalidation, the last one serves the request
+	// without being cached
+	maxCatalogReloads = 3
+)
+
+// assetCatalog is an in-process copy of the chains and assets tables.
+// It is reloaded after ttl so changes made by other replicas are picked up,
+// and invalidated right away by local writes. Invalidation bumps generation,
+// a reload that overlapped one is discarded since it may have read old rows
+type assetCatalog struct {
+	mu         sync.RWMutex
+	ttl        time.Duration
+	generation uint64
+	loadedAt   time.Time
+	chains     map[string]models.Chain
+	assets     map[string]models.Asset
+}
+
+func (a *Storage) catalog() *assetCatalog {
+	a.catalogOnce.Do(func() {
+		if a.assets == nil {
+			a.assets = &assetCatalog{ttl: defaultCatalogTTL}
+		}
+	})
+
+	return a.assets
+}
+
+// SetCatalogTTL sets how long the asset catalog is cached
+func (a *Storage) SetCatalogTTL(ttl time.Duration) {
+	c := a.catalog()
+	c.mu.Lock()
+	c.ttl = ttl
+	c.mu.Unlock()
+}
+
+// InvalidateCatalog makes the next lookup reload the asset catalog
+func (a *Storage) InvalidateCatalog() {
+	c := a.catalog()
+	c.mu.Lock()
+	c.generation++
+	c.loadedAt = time.Time{}
+	c.mu.Unlock()
+}
+
+func (a *Storage) loadedCatalog(ctx context.Context) (*assetCatalog, error) {
+	c := a.catalog()
+
+	log := logger.FromContext(ctx).WithField("m", "loadedCatalog")
+
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		c.mu.RLock()
+		fresh := !c.loadedAt.IsZero() && time.Since(c.loadedAt) < c.ttl
+		generation := c.generation
+		c.mu.RUnlock()
+		if fresh {
+			return c, nil
+		}
+
+		log.Debugf("loadedCatalog:: reload, generation: %v", generation)
+
+		var chains []models.Chain
+		if err := a.DB.Find(&chains).Error; err != nil {
+			return nil, err
+		}
+		var assets []models.Asset
+		if err := a.DB.Find(&assets).Error; err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		if c.generation != generation && attempt < maxCatalogReloads {
+			c.mu.Unlock()
+			log.Debugf("loadedCatalog:: invalidated during reload, retrying")
+
+			continue
+		}
+		c.chains = make(map[string]models.Chain, len(chains))
+		for _, ch := range chains {
+			c.chains[ch.Name] = ch
+		}
+		c.assets = make(map[string]models.Asset, len(assets))
+		for _, as := range assets {
+			c.assets[as.AssetID] = as
+		}
+		if c.generation == generation {
+			c.loadedAt = time.Now()
+		}
+		c.mu.Unlock()
+
+		return c, nil
+	}
+}
+
+// LookupAsset returns nil if the asset is not in the catalog
+func (a *Storage) LookupAsset(ctx context.Context, assetID string) (*models.Asset, error) {
+	c, err := a.loadedCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	as, ok := c.assets[assetID]
+	if !ok {
+		return nil, nil
+	}
+
+	return &as, nil
+}
+
+// LookupChain returns nil if the chain is not in the catalog
+func (a *Storage) LookupChain(ctx context.Context, name string) (*models.Chain, error) {
+	c, err := a.loadedCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ch, ok := c.chains[name]
+	if !ok {
+		return nil, nil
+	}
+
+	return &ch, nil
+}
+
+// NativeAsset returns the coin of a chain or nil if the catalog has none
+func (a *Storage) NativeAsset(ctx context.Context, chain string) (*models.Asset, error) {
+	c, err := a.loadedCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, as := range c.assets {
+		if as.Chain == chain && as.Native() {
+			return &as, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ChainAssets returns the enabled assets of a chain
+func (a *Storage) ChainAssets(ctx context.Context, chain string) ([]models.Asset, error) {
+	c, err := a.loadedCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var ret []models.Asset
+	for _, as := range c.assets {
+		if as.Chain == chain && as.Enabled {
+			ret = append(ret, as)
+		}
+	}
+
+	return ret, nil
+}
+
+func (a *Storage) GetChains(ctx context.Context) (ret []models.Chain, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetChains")
+	log.Debugf("GetChains:: ")
+
+	err = a.DB.Order("name").Find(&ret).Error
+
+	return
+}
+
+// GetAssets returns all assets, or only enabled assets of enabled chains
+func (a *Storage) GetAssets(ctx context.Context, onlyEnabled bool) (ret []models.Asset, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetAssets")
+	log.Debugf("GetAssets:: onlyEnabled: %v", onlyEnabled)
+
+	q := a.DB.Order("chain, asset_id")
+	if onlyEnabled {
+		q = q.Where("enabled AND chain IN (SELECT name FROM chains WHERE enabled AND deleted_at IS NULL)")
+	}
+	err = q.Find(&ret).Error
+
+	return
+}
+
+func (a *Storage) CreateChain(ctx context.Context, ch *models.Chain) error {
+	log := logger.FromContext(ctx).WithField("m", "CreateChain")
+	log.Debugf("CreateChain:: name: %v", ch.Name)
+
+	defer a.InvalidateCatalog()
+
+	return a.DB.Create(ch).Error
+}
+
+// UpdateChain returns false if the chain does not exist
+func (a *Storage) UpdateChain(ctx context.Context, name string, kind string, enabled bool) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "UpdateChain")
+	log.Debugf("UpdateChain:: name: %v", name)
+
+	defer a.InvalidateCatalog()
+
+	res := a.DB.Model(&models.Chain{}).Where("name = ?", name).
+		Updates(map[string]interface{}{"kind": kind, "enabled": enabled})
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteChain soft-deletes a chain without assets, returns false if the chain does not exist
+func (a *Storage) DeleteChain(ctx context.Context, name string) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "DeleteChain")
+	log.Debugf("DeleteChain:: name: %v", name)
+
+	defer a.InvalidateCatalog()
+
+	res := a.DB.Where("name = ?", name).Delete(&models.Chain{})
+
+	return res.RowsAffected > 0, res.Error
+}
+
+func (a *Storage) CreateAsset(ctx context.Context, as *models.Asset) error {
+	log := logger.FromContext(ctx).WithField("m", "CreateAsset")
+	log.Debugf("CreateAsset:: assetID: %v", as.AssetID)
+
+	defer a.InvalidateCatalog()
+
+	return a.DB.Create(as).Error
+}
+
+// UpdateAsset returns false if the asset does not exist
+func (a *Storage) UpdateAsset(ctx context.Context, as *models.Asset) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "UpdateAsset")
+	log.Debugf("UpdateAsset:: assetID: %v", as.AssetID)
+
+	defer a.InvalidateCatalog()
+
+	res := a.DB.Model(&models.Asset{}).Where("asset_id = ?", as.AssetID).
+		Updates(map[string]interface{}{
+			"symbol":           as.Symbol,
+			"chain":            as.Chain,
+			"contract_address": as.ContractAddress,
+			"decimals":         as.Decimals,
+			"enabled":          as.Enabled,
+		})
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteAsset soft-deletes an asset, returns false if the asset does not exist
+func (a *Storage) DeleteAsset(ctx context.Context, assetID string) (bool, error) {
+	log := logger.FromContext(ctx).WithField("m", "DeleteAsset")
+	log.Debugf("DeleteAsset:: assetID: %v", assetID)
+
+	defer a.InvalidateCatalog()
+
+	res := a.DB.Where("asset_id = ?", assetID).Delete(&models.Asset{})
+
+	return res.RowsAffected > 0, res.Error
+}
+
+// CountChainAssets counts assets of a chain
+func (a *Storage) CountChainAssets(ctx context.Context, chain string) (n int, err error) {
+	log := logger.FromContext(ctx).WithField("m", "CountChainAssets")
+	log.Debugf("CountChainAssets:: chain: %v", chain)
+
+	err = a.DB.Model(&models.Asset{}).Where("chain = ?", chain).Count(&n).Error
+
+	return
+}
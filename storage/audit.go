@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jinzhu/gorm"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+// NewAuditEntry builds an entry with details marshalled to JSON
+func NewAuditEntry(actor, action, target string, dryRun bool, details interface{}) (*models.AuditEntry, error) {
+	b, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuditEntry{
+		Actor:   actor,
+		Action:  action,
+		Target:  target,
+		DryRun:  dryRun,
+		Details: string(b),
+	}, nil
+}
+
+func (a *Storage) AddAuditEntry(ctx context.Context, e *models.AuditEntry) error {
+	log := logger.FromContext(ctx).WithField("m", "AddAuditEntry")
+	log.Debugf("AddAuditEntry:: actor: %v, action: %v, target: %v", e.Actor, e.Action, e.Target)
+
+	return addAuditEntry(a.DB, e)
+}
+
+func addAuditEntry(db *gorm.DB, e *models.AuditEntry) error {
+	return db.Create(e).Error
+}
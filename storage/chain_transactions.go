@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+// OutgoingTransfer is a stored outgoing row of a chain transaction table
+type OutgoingTransfer struct {
+	Chain     string
+	AssetID   string
+	UserID    string
+	TxID      string
+	ToAddress string
+	Amount    models.Amount
+	Timestamp time.Time
+}
+
+// OnOutgoingStored registers fn to be called with outgoing rows after they are committed.
+// Hooks must be registered before transactions are stored
+func (a *Storage) OnOutgoingStored(fn func(ctx context.Context, transfers []OutgoingTransfer)) {
+	a.storedHooks = append(a.storedHooks, fn)
+}
+
+func (a *Storage) outgoingStored(ctx context.Context, transfers []OutgoingTransfer) {
+	if len(transfers) == 0 {
+		return
+	}
+	for _, fn := range a.storedHooks {
+		fn(ctx, transfers)
+	}
+}
+
+// outgoingTransfers returns outgoing rows of all chain tables, chain names rows of tables without a chain column
+func outgoingTransfers(chain string, rows ChainRows) (ret []OutgoingTransfer) {
+	for _, r := range rows.BTC {
+		if r.Outgoing {
+			ret = append(ret, OutgoingTransfer{r.Chain, r.AssetID, r.UserID, r.TxID, r.ToAddress, r.Amount, r.Timestamp})
+		}
+	}
+	for _, r := range rows.ETH {
+		if r.Outgoing {
+			ret = append(ret, OutgoingTransfer{r.Chain, r.AssetID, r.UserID, r.TxID, r.ToAddress, r.Amount, r.Timestamp})
+		}
+	}
+	for _, r := range rows.TRX {
+		if r.Outgoing {
+			ret = append(ret, OutgoingTransfer{chain, r.AssetID, r.UserID, r.TxID, r.ToAddress, r.Amount, r.Timestamp})
+		}
+	}
+
+	return
+}
+
+// WalletsByAddress returns wallets of an asset with one of the addresses, keyed by address
+func (a *Storage) WalletsByAddress(ctx context.Context, assetID string, addresses []string) (map[string]models.Wallet, error) {
+	log := logger.FromContext(ctx).WithField("m", "WalletsByAddress")
+	log.Debugf("WalletsByAddress:: assetID: %v, addresses: %v", assetID, len(addresses))
+
+	ret := map[string]models.Wallet{}
+	if len(addresses) == 0 {
+		return ret, nil
+	}
+
+	var wallets []models.Wallet
+	err := a.DB.Where("asset_id = ? AND address IN (?)", assetID, addresses).Find(&wallets).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range wallets {
+		ret[w.Address] = w
+	}
+
+	return ret, nil
+}
+
+// BTCIncomingOutputs returns stored outputs paying to our wallets in the given transactions
+func (a *Storage) BTCIncomingOutputs(ctx context.Context, chain string, txIDs []string) (ret []models.BTCTransaction, err error) {
+	log := logger.FromContext(ctx).WithField("m", "BTCIncomingOutputs")
+	log.Debugf("BTCIncomingOutputs:: chain: %v, txIDs: %v", chain, len(txIDs))
+
+	if len(txIDs) == 0 {
+		return nil, nil
+	}
+
+	err = a.DB.Where("chain = ? AND tx_id IN (?) AND NOT outgoing", chain, txIDs).Find(&ret).Error
+
+	return
+}
+
+// AddBTCTransactions stores rows skipping already known (chain, tx_id, vout, outgoing, user_id)
+// and returns the number of inserted rows
+func (a *Storage) AddBTCTransactions(ctx context.Context, rows []models.BTCTransaction) (inserted int, err error) {
+	log := logger.FromContext(ctx).WithField("m", "AddBTCTransactions")
+	log.Debugf("AddBTCTransactions:: len: %v", len(rows))
+
+	var added []models.BTCTransaction
+	err = a.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range rows {
+			res := tx.Set("gorm:insert_option", "ON CONFLICT (chain, tx_id, vout, outgoing, user_id) DO NOTHING").Create(&rows[i])
+			if conflictSkipped(res.Error) {
+				continue
+			}
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected > 0 {
+				added = append(added, rows[i])
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	a.outgoingStored(ctx, outgoingTransfers("", ChainRows{BTC: added}))
+	inserted = len(added)
+
+	return inserted, nil
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// TimeRange limits a query by timestamp, zero bounds are not applied
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+func (t TimeRange) apply(q *gorm.DB, column string) *gorm.DB {
+	if !t.From.IsZero() {
+		q = q.Where(column+" >= ?", t.From)
+	}
+	if !t.To.IsZero() {
+		q = q.Where(column+" <= ?", t.To)
+	}
+
+	return q
+}
+
+// Page is a limit/offset window over a sorted result
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+func (p Page) apply(q *gorm.DB) *gorm.DB {
+	if p.Limit > 0 {
+		q = q.Limit(p.Limit)
+	}
+	if p.Offset > 0 {
+		q = q.Offset(p.Offset)
+	}
+
+	return q
+}
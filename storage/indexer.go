@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+// ChainRows are transaction rows extracted from blocks, only the table of the chain kind is used
+type ChainRows struct {
+	BTC []models.BTCTransaction
+	ETH []models.ETHTransaction
+	TRX []models.TRXTransaction
+}
+
+func (r ChainRows) Len() int {
+	return len(r.BTC) + len(r.ETH) + len(r.TRX)
+}
+
+// confirmed rows replace rows seen in the mempool or rolled back by a reorganization
+const confirmUpdate = " DO UPDATE SET block_height = EXCLUDED.block_height, block_hash = EXCLUDED.block_hash," +
+	" timestamp = EXCLUDED.timestamp, deleted_at = NULL, updated_at = now()"
+
+// GetCheckpoint returns nil if the chain has not been indexed
+func (a *Storage) GetCheckpoint(ctx context.Context, chain string) (*models.IndexerCheckpoint, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetCheckpoint")
+	log.Debugf("GetCheckpoint:: chain: %v", chain)
+
+	cp := new(models.IndexerCheckpoint)
+	err := a.DB.Where("chain = ?", chain).First(cp).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cp, nil
+}
+
+func (a *Storage) GetCheckpoints(ctx context.Context) (ret []models.IndexerCheckpoint, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetCheckpoints")
+	log.Debugf("GetCheckpoints:: ")
+
+	err = a.DB.Order("chain").Find(&ret).Error
+
+	return
+}
+
+// GetIndexedBlock returns nil if no block at height is kept
+func (a *Storage) GetIndexedBlock(ctx context.Context, chain string, height int64) (*models.IndexedBlock, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetIndexedBlock")
+	log.Debugf("GetIndexedBlock:: chain: %v, height: %v", chain, height)
+
+	b := new(models.IndexedBlock)
+	err := a.DB.Where("chain = ? AND height = ?", chain, height).First(b).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// StoreBlocks stores the rows of consecutive blocks and moves the checkpoint to the last one
+// in one transaction. Storing the same blocks again is a no-op, it returns the number of stored rows.
+// Outgoing rows are passed to OnOutgoingStored hooks after the commit, including rows stored before
+func (a *Storage) StoreBlocks(ctx context.Context, chain string, blocks []models.IndexedBlock, rows ChainRows) (stored int, err error) {
+	log := logger.FromContext(ctx).WithField("m", "StoreBlocks")
+	log.Debugf("StoreBlocks:: chain: %v, blocks: %v, rows: %v", chain, len(blocks), rows.Len())
+
+	if len(blocks) == 0 {
+		return 0, nil
+	}
+
+	err = a.DB.Transaction(func(tx *gorm.DB) error {
+		create := func(conflict string, row interface{}) error {
+			res := tx.Set("gorm:insert_option", "ON CONFLICT "+conflict+confirmUpdate).Create(row)
+			stored += int(res.RowsAffected)
+			return res.Error
+		}
+		for i := range rows.BTC {
+			if err := create("(chain, tx_id, vout, outgoing, user_id)", &rows.BTC[i]); err != nil {
+				return err
+			}
+		}
+		for i := range rows.ETH {
+			if err := create("(chain, tx_id, log_index, outgoing)", &rows.ETH[i]); err != nil {
+				return err
+			}
+		}
+		for i := range rows.TRX {
+			if err := create("(tx_id, log_index, outgoing)", &rows.TRX[i]); err != nil {
+				return err
+			}
+		}
+
+		for _, b := range blocks {
+			err := tx.Exec("INSERT INTO indexed_blocks (chain, height, hash, parent_hash, timestamp) VALUES (?, ?, ?, ?, ?)"+
+				" ON CONFLICT (chain, height) DO UPDATE SET hash = EXCLUDED.hash, parent_hash = EXCLUDED.parent_hash,"+
+				" timestamp = EXCLUDED.timestamp, created_at = now()",
+				chain, b.Height, b.Hash, b.ParentHash, b.Timestamp).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		last := blocks[len(blocks)-1]
+		return setCheckpoint(tx, chain, last.Height, last.Hash)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	a.outgoingStored(ctx, outgoingTransfers(chain, rows))
+
+	return stored, nil
+}
+
+func setCheckpoint(tx *gorm.DB, chain string, height int64, hash string) error {
+	return tx.Exec("INSERT INTO indexer_checkpoints (chain, height, hash, updated_at) VALUES (?, ?, ?, now())"+
+		" ON CONFLICT (chain) DO UPDATE SET height = EXCLUDED.height, hash = EXCLUDED.hash, updated_at = now()",
+		chain, height, hash).Error
+}
+
+type rollbackSpan struct {
+	RowCount int64
+	MinTS    *time.Time
+	MaxTS    *time.Time
+}
+
+// RollbackBlocks soft-deletes rows of blocks above the fork block, forgets those blocks and moves
+// the checkpoint back to the fork. Turnover rollups covering the removed rows are recomputed.
+// It returns the number of removed rows
+func (a *Storage) RollbackBlocks(ctx context.Context, chain *models.Chain, fork *models.IndexedBlock) (int64, error) {
+	log := logger.FromContext(ctx).WithField("m", "RollbackBlocks")
+	log.Debugf("RollbackBlocks:: chain: %v, fork: %v", chain.Name, fork.Height)
+
+	table := models.TransactionsTable(chain.Kind)
+	var span rollbackSpan
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		orphaned := func() *gorm.DB {
+			q := tx.Table(table).Where("deleted_at IS NULL AND block_height > ?", fork.Height)
+			if chain.Kind != models.ChainKindTRX {
+				q = q.Where("chain = ?", chain.Name)
+			}
+			return q
+		}
+
+		err := orphaned().Select("count(*) AS row_count, min(timestamp) AS min_ts, max(timestamp) AS max_ts").
+			Scan(&span).Error
+		if err != nil {
+			return err
+		}
+		if err := orphaned().UpdateColumn("deleted_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		err = tx.Exec("DELETE FROM indexed_blocks WHERE chain = ? AND height > ?", chain.Name, fork.Height).Error
+		if err != nil {
+			return err
+		}
+
+		return setCheckpoint(tx, chain.Name, fork.Height, fork.Hash)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if span.RowCount == 0 || span.MinTS == nil {
+		return 0, nil
+	}
+
+	return span.RowCount, a.RefreshTurnover(ctx, span.MinTS.Truncate(time.Hour), span.MaxTS.Add(time.Hour))
+}
+
+// PruneIndexedBlocks forgets blocks below height, they are too deep to be reorganized
+func (a *Storage) PruneIndexedBlocks(ctx context.Context, chain string, below int64) error {
+	log := logger.FromContext(ctx).WithField("m", "PruneIndexedBlocks")
+	log.Debugf("PruneIndexedBlocks:: chain: %v, below: %v", chain, below)
+
+	return a.DB.Exec("DELETE FROM indexed_blocks WHERE chain = ? AND height < ?", chain, below).Error
+}
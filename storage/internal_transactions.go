@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+// AddInternalTransactions stores transactions skipping already known (chain, tx_id) pairs
+// and returns the number of inserted rows
+func (a *Storage) AddInternalTransactions(ctx context.Context, txs []models.InternalTransaction) (inserted int, err error) {
+	log := logger.FromContext(ctx).WithField("m", "AddInternalTransactions")
+	log.Debugf("AddInternalTransactions:: len: %v", len(txs))
+
+	err = a.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range txs {
+			res := tx.Set("gorm:insert_option", "ON CONFLICT (chain, tx_id) DO NOTHING").Create(&txs[i])
+			if conflictSkipped(res.Error) {
+				continue
+			}
+			if res.Error != nil {
+				return res.Error
+			}
+			inserted += int(res.RowsAffected)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return inserted, nil
+}
+
+// GetInternalTransaction returns nil if the transaction is not registered
+func (a *Storage) GetInternalTransaction(ctx context.Context, chain, txID string) (*models.InternalTransaction, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetInternalTransaction")
+	log.Debugf("GetInternalTransaction:: chain: %v, txID: %v", chain, txID)
+
+	ret := new(models.InternalTransaction)
+	err := a.DB.Where("chain = ? AND tx_id = ?", chain, txID).First(ret).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// conflictSkipped reports whether an INSERT ... ON CONFLICT DO NOTHING skipped the row,
+// gorm reports the missing RETURNING row as sql.ErrNoRows
+func conflictSkipped(err error) bool {
+	return err == sql.ErrNoRows
+}
+
+// uniqueViolation returns the constraint of a unique violation
+func uniqueViolation(err error) (string, bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return pqErr.Constraint, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+type PriceFilter struct {
+	AssetID string
+	Fiat    string
+	Range   TimeRange
+}
+
+// ImportPrices stores prices in one transaction, a price at an existing timestamp is replaced.
+// It returns the number of stored prices
+func (a *Storage) ImportPrices(ctx context.Context, prices []models.Price) (int, error) {
+	log := logger.FromContext(ctx).WithField("m", "ImportPrices")
+	log.Debugf("ImportPrices:: len: %v", len(prices))
+
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		for _, p := range prices {
+			err := tx.Exec("INSERT INTO prices (asset_id, fiat, timestamp, price) VALUES (?, ?, ?, ?)"+
+				" ON CONFLICT (asset_id, fiat, timestamp) DO UPDATE SET price = EXCLUDED.price, updated_at = now()",
+				p.AssetID, p.Fiat, p.Timestamp, p.Price).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(prices), nil
+}
+
+// GetPrices returns prices sorted by asset, fiat and timestamp and the total count for the filter
+func (a *Storage) GetPrices(ctx context.Context, f PriceFilter, p Page) (ret []models.Price, total int, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetPrices")
+	log.Debugf("GetPrices:: f: %+v, p: %+v", f, p)
+
+	q := a.DB.Model(&models.Price{})
+	if f.AssetID != "" {
+		q = q.Where("asset_id = ?", f.AssetID)
+	}
+	if f.Fiat != "" {
+		q = q.Where("fiat = ?", f.Fiat)
+	}
+	q = f.Range.apply(q, "timestamp")
+
+	if err = q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = p.apply(q.Order("asset_id, fiat, timestamp")).Find(&ret).Error
+
+	return
+}
+
+// GetPriceWindow returns the last price at or before from, nil if there is none,
+// and the prices after from up to and including to in timestamp order
+func (a *Storage) GetPriceWindow(ctx context.Context, assetID, fiat string, tr TimeRange) (*models.Price, []models.Price, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetPriceWindow")
+	log.Debugf("GetPriceWindow:: assetID: %v, fiat: %v, tr: %+v", assetID, fiat, tr)
+
+	prev := new(models.Price)
+	err := a.DB.Where("asset_id = ? AND fiat = ? AND timestamp <= ?", assetID, fiat, tr.From).
+		Order("timestamp DESC").First(prev).Error
+	if gorm.IsRecordNotFoundError(err) {
+		prev = nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	var points []models.Price
+	err = a.DB.Where("asset_id = ? AND fiat = ? AND timestamp > ? AND timestamp <= ?", assetID, fiat, tr.From, tr.To).
+		Order("timestamp").Find(&points).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return prev, points, nil
+}
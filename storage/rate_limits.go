@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"service_template/logger"
+)
+
+// TakeRateLimitToken refills the bucket of key at rate tokens per second up to burst and takes
+// a token if one is left. It returns whether a token was taken and the tokens left.
+// The database clock is used so replicas agree on refills
+func (a *Storage) TakeRateLimitToken(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+	log := logger.FromContext(ctx).WithField("m", "TakeRateLimitToken")
+	log.Debugf("TakeRateLimitToken:: key: %v", key)
+
+	refill := "LEAST(?::double precision, b.tokens + GREATEST(0, EXTRACT(EPOCH FROM (now() - b.updated_at))) * ?::double precision)"
+
+	var row struct {
+		Allowed bool
+		Tokens  float64
+	}
+	err := a.DB.Raw("INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at) VALUES (?, ?::double precision - 1, true, now()) "+
+		"ON CONFLICT (key) DO UPDATE SET "+
+		"tokens = CASE WHEN "+refill+" >= 1 THEN "+refill+" - 1 ELSE "+refill+" END, "+
+		"allowed = "+refill+" >= 1, "+
+		"updated_at = GREATEST(b.updated_at, now()) "+
+		"RETURNING allowed, tokens",
+		key, burst,
+		burst, rate, burst, rate, burst, rate,
+		burst, rate).Scan(&row).Error
+	if err != nil {
+		return false, 0, err
+	}
+
+	return row.Allowed, row.Tokens, nil
+}
+
+// DeleteIdleRateLimitBuckets removes buckets not used since before, they would be full anyway
+func (a *Storage) DeleteIdleRateLimitBuckets(ctx context.Context, before time.Time) (int64, error) {
+	log := logger.FromContext(ctx).WithField("m", "DeleteIdleRateLimitBuckets")
+	log.Debugf("DeleteIdleRateLimitBuckets:: before: %v", before)
+
+	res := a.DB.Exec("DELETE FROM rate_limit_buckets WHERE updated_at < ?", before)
+
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+type FindingFilter struct {
+	Kind   string
+	Status string
+	Chain  string
+	Range  TimeRange
+}
+
+// chainTransactionsUnion selects rows of all chain transaction tables with amounts in base units
+func chainTransactionsUnion() string {
+	var parts []string
+	for _, s := range outgoingSources {
+		parts = append(parts, "SELECT "+s.chain+" AS chain, tx_id, asset_id, user_id, "+
+			s.amount+" AS amount, outgoing, to_address, timestamp FROM "+s.table+" WHERE deleted_at IS NULL")
+	}
+
+	return "(" + strings.Join(parts, " UNION ALL ") + ")"
+}
+
+const findingColumns = "created_at, updated_at, kind, chain, tx_id, asset_id, user_id, status, details"
+
+// Reconcile records findings for registry entries registered in [since, until) and chain
+// transactions in the same window, and resolves open findings that no longer apply.
+// It returns the number of new findings
+func (a *Storage) Reconcile(ctx context.Context, since, until time.Time) (found int64, err error) {
+	log := logger.FromContext(ctx).WithField("m", "Reconcile")
+	log.Debugf("Reconcile:: since: %v, until: %v", since, until)
+
+	chainTxs := chainTransactionsUnion()
+
+	err = a.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Exec("INSERT INTO reconciliation_findings ("+findingColumns+")"+
+			" SELECT now(), now(), ?, i.chain, i.tx_id, i.asset_id, i.user_id, ?,"+
+			" jsonb_build_object('registered_at', i.created_at)"+
+			" FROM internal_transactions i WHERE i.deleted_at IS NULL AND i.created_at >= ? AND i.created_at < ?"+
+			" AND NOT EXISTS (SELECT 1 FROM "+chainTxs+" c WHERE c.chain = i.chain AND c.tx_id = i.tx_id)"+
+			" ON CONFLICT (kind, chain, tx_id) DO NOTHING",
+			models.FindingMissingOnChain, models.FindingOpen, since, until)
+		if res.Error != nil {
+			return res.Error
+		}
+		found += res.RowsAffected
+
+		res = tx.Exec("INSERT INTO reconciliation_findings ("+findingColumns+")"+
+			" SELECT DISTINCT ON (c.chain, c.tx_id) now(), now(), ?, c.chain, c.tx_id, c.asset_id, c.user_id, ?,"+
+			" jsonb_build_object('to_address', c.to_address, 'timestamp', c.timestamp)"+
+			" FROM "+chainTxs+" c WHERE c.outgoing AND c.timestamp >= ? AND c.timestamp < ?"+
+			" AND EXISTS (SELECT 1 FROM wallets w WHERE w.deleted_at IS NULL AND w.asset_id = c.asset_id AND w.address = c.to_address)"+
+			" AND NOT EXISTS (SELECT 1 FROM internal_transactions i WHERE i.deleted_at IS NULL AND i.chain = c.chain AND i.tx_id = c.tx_id)"+
+			" ORDER BY c.chain, c.tx_id"+
+			" ON CONFLICT (kind, chain, tx_id) DO NOTHING",
+			models.FindingMissingInRegistry, models.FindingOpen, since, until)
+		if res.Error != nil {
+			return res.Error
+		}
+		found += res.RowsAffected
+
+		res = tx.Exec("INSERT INTO reconciliation_findings ("+findingColumns+")"+
+			" SELECT now(), now(), ?, i.chain, i.tx_id, i.asset_id, i.user_id, ?,"+
+			" jsonb_build_object('registry_amount', i.amount::text, 'chain_amount', s.amount::text)"+
+			" FROM internal_transactions i JOIN (SELECT chain, tx_id, sum(amount) AS amount FROM "+chainTxs+
+			" c WHERE c.outgoing GROUP BY chain, tx_id) s ON s.chain = i.chain AND s.tx_id = i.tx_id"+
+			" WHERE i.deleted_at IS NULL AND i.amount IS NOT NULL AND i.amount <> s.amount"+
+			" AND i.created_at >= ? AND i.created_at < ?"+
+			" ON CONFLICT (kind, chain, tx_id) DO NOTHING",
+			models.FindingAmountMismatch, models.FindingOpen, since, until)
+		if res.Error != nil {
+			return res.Error
+		}
+		found += res.RowsAffected
+
+		return tx.Exec("UPDATE reconciliation_findings f SET status = ?, note = 'resolved by reconciliation',"+
+			" reviewed_by = 'reconciliation', reviewed_at = now(), updated_at = now()"+
+			" WHERE f.status = ? AND f.deleted_at IS NULL AND ("+
+			"(f.kind = ? AND EXISTS (SELECT 1 FROM "+chainTxs+" c WHERE c.chain = f.chain AND c.tx_id = f.tx_id))"+
+			" OR (f.kind = ? AND EXISTS (SELECT 1 FROM internal_transactions i"+
+			" WHERE i.deleted_at IS NULL AND i.chain = f.chain AND i.tx_id = f.tx_id))"+
+			" OR (f.kind = ? AND EXISTS (SELECT 1 FROM internal_transactions i"+
+			" WHERE i.deleted_at IS NULL AND i.chain = f.chain AND i.tx_id = f.tx_id AND i.amount ="+
+			" (SELECT sum(c.amount) FROM "+chainTxs+" c WHERE c.outgoing AND c.chain = f.chain AND c.tx_id = f.tx_id))))",
+			models.FindingResolved, models.FindingOpen,
+			models.FindingMissingOnChain, models.FindingMissingInRegistry, models.FindingAmountMismatch).Error
+	})
+
+	return found, err
+}
+
+func (a *Storage) findingsQuery(f FindingFilter) *gorm.DB {
+	q := a.DB.Model(&models.ReconciliationFinding{})
+	if f.Kind != "" {
+		q = q.Where("kind = ?", f.Kind)
+	}
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	if f.Chain != "" {
+		q = q.Where("chain = ?", f.Chain)
+	}
+
+	return f.Range.apply(q, "created_at")
+}
+
+// GetFindings returns findings sorted from oldest to newest and the total count for the filter
+func (a *Storage) GetFindings(ctx context.Context, f FindingFilter, p Page) (ret []models.ReconciliationFinding, total int, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetFindings")
+	log.Debugf("GetFindings:: f: %+v, p: %+v", f, p)
+
+	if err = a.findingsQuery(f).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err = p.apply(a.findingsQuery(f)).Order("created_at asc, id asc").Find(&ret).Error
+
+	return
+}
+
+// ReviewFinding sets the status of a finding, returns nil if the finding does not exist
+func (a *Storage) ReviewFinding(ctx context.Context, id uint, status, note, reviewer string) (*models.ReconciliationFinding, error) {
+	log := logger.FromContext(ctx).WithField("m", "ReviewFinding")
+	log.Debugf("ReviewFinding:: id: %v, status: %v", id, status)
+
+	now := time.Now()
+	res := a.DB.Model(&models.ReconciliationFinding{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":      status,
+		"note":        note,
+		"reviewed_by": reviewer,
+		"reviewed_at": &now,
+	})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	ret := new(models.ReconciliationFinding)
+	if err := a.DB.Where("id = ?", id).First(ret).Error; err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// GetFindingsSummary counts findings by kind and status
+func (a *Storage) GetFindingsSummary(ctx context.Context) (ret []models.FindingsSummary, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetFindingsSummary")
+	log.Debugf("GetFindingsSummary:: ")
+
+	err = a.DB.Model(&models.ReconciliationFinding{}).Select("kind, status, count(*) AS count").
+		Group("kind, status").Order("kind, status").Scan(&ret).Error
+
+	return
+}
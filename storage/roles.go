@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+// roleCache is an in-process copy of the role_permissions table, reloaded after ttl
+// and invalidated right away by local writes
+type roleCache struct {
+	mu       sync.RWMutex
+	ttl      time.Duration
+	loadedAt time.Time
+	perms    map[string][]string
+}
+
+// SetRolesTTL sets how long role permissions are cached
+func (a *Storage) SetRolesTTL(ttl time.Duration) {
+	a.roles.mu.Lock()
+	a.roles.ttl = ttl
+	a.roles.mu.Unlock()
+}
+
+func (a *Storage) invalidateRoles() {
+	a.roles.mu.Lock()
+	a.roles.loadedAt = time.Time{}
+	a.roles.mu.Unlock()
+}
+
+func (a *Storage) loadedRoles(ctx context.Context) (map[string][]string, error) {
+	c := &a.roles
+
+	c.mu.RLock()
+	ttl := c.ttl
+	if ttl == 0 {
+		ttl = defaultCatalogTTL
+	}
+	fresh := !c.loadedAt.IsZero() && time.Since(c.loadedAt) < ttl
+	perms := c.perms
+	c.mu.RUnlock()
+	if fresh {
+		return perms, nil
+	}
+
+	log := logger.FromContext(ctx).WithField("m", "loadedRoles")
+	log.Debugf("loadedRoles:: reload")
+
+	var rows []models.RolePermission
+	if err := a.DB.Order("role, permission").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	perms = map[string][]string{}
+	for _, r := range rows {
+		perms[r.Role] = append(perms[r.Role], r.Permission)
+	}
+
+	c.mu.Lock()
+	c.perms, c.loadedAt = perms, time.Now()
+	c.mu.Unlock()
+
+	return perms, nil
+}
+
+// RolePermissions returns the sorted permissions granted to any of the roles
+func (a *Storage) RolePermissions(ctx context.Context, roles []string) ([]string, error) {
+	perms, err := a.loadedRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	set := map[string]bool{}
+	for _, role := range roles {
+		for _, p := range perms[role] {
+			set[p] = true
+		}
+	}
+	ret := make([]string, 0, len(set))
+	for p := range set {
+		ret = append(ret, p)
+	}
+	sort.Strings(ret)
+
+	return ret, nil
+}
+
+// GetRoles returns all roles with their permissions sorted by name
+func (a *Storage) GetRoles(ctx context.Context) ([]models.Role, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetRoles")
+	log.Debugf("GetRoles:: ")
+
+	a.invalidateRoles()
+	perms, err := a.loadedRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]models.Role, 0, len(perms))
+	for name, p := range perms {
+		ret = append(ret, models.Role{Name: name, Permissions: p})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+
+	return ret, nil
+}
+
+// SetRolePermissions replaces the permissions of a role, an empty list removes the role
+func (a *Storage) SetRolePermissions(ctx context.Context, role string, permissions []string) error {
+	log := logger.FromContext(ctx).WithField("m", "SetRolePermissions")
+	log.Debugf("SetRolePermissions:: role: %v, permissions: %v", role, permissions)
+
+	defer a.invalidateRoles()
+
+	return a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM role_permissions WHERE role = ?", role).Error; err != nil {
+			return err
+		}
+		for _, p := range permissions {
+			err := tx.Exec("INSERT INTO role_permissions (role, permission) VALUES (?, ?) ON CONFLICT DO NOTHING", role, p).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// BootstrapRoles fills an empty role table, once any role exists roles are only changed
+// through the API. It returns the number of added permissions
+func (a *Storage) BootstrapRoles(ctx context.Context, roles map[string][]string) (added int64, err error) {
+	log := logger.FromContext(ctx).WithField("m", "BootstrapRoles")
+	log.Debugf("BootstrapRoles:: roles: %v", len(roles))
+
+	defer a.invalidateRoles()
+
+	err = a.DB.Transaction(func(tx *gorm.DB) error {
+		var n int
+		if err := tx.Model(&models.RolePermission{}).Count(&n).Error; err != nil || n > 0 {
+			return err
+		}
+		for role, perms := range roles {
+			for _, p := range perms {
+				res := tx.Exec("INSERT INTO role_permissions (role, permission) VALUES (?, ?) ON CONFLICT DO NOTHING", role, p)
+				if res.Error != nil {
+					return res.Error
+				}
+				added += res.RowsAffected
+			}
+		}
+
+		return nil
+	})
+
+	return added, err
+}
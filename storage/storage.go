@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"sync"
 
 	"github.com/jinzhu/gorm"
 
@@ -46,6 +47,14 @@ func (a DBIDs) toString(ctx context.Context) (ret string) {
 
 type Storage struct {
 	DB *gorm.DB
+
+	catalogOnce sync.Once
+	assets      *assetCatalog
+	roles       roleCache
+
+	apiClientUsage apiClientUsage
+
+	storedHooks []func(ctx context.Context, transfers []OutgoingTransfer)
 }
 
 func (a *Storage) DBLog(ctx context.Context, isSet bool) {
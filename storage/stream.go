@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+// rebind replaces ? placeholders with postgres positional parameters
+func rebind(q string) string {
+	var b strings.Builder
+	n := 0
+	for _, c := range q {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// queryContext runs a query bound to ctx so it is cancelled on the postgres side
+// when the context is done, rows are read from the connection as they are iterated
+func (a *Storage) queryContext(ctx context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+	return a.DB.DB().QueryContext(ctx, rebind(q), args...)
+}
+
+// StreamWallets calls fn for every wallet matching the filter in timestamp order
+func (a *Storage) StreamWallets(ctx context.Context, f WalletFilter, fn func(*models.Wallet) error) error {
+	log := logger.FromContext(ctx).WithField("m", "StreamWallets")
+	log.Debugf("StreamWallets:: f: %+v", f)
+
+	where := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	if f.UserID != "" {
+		where = append(where, "user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if f.AssetID != "" {
+		where = append(where, "asset_id = ?")
+		args = append(args, f.AssetID)
+	}
+	if f.Address != "" {
+		where = append(where, "address = ?")
+		args = append(args, f.Address)
+	}
+	if !f.Range.From.IsZero() {
+		where = append(where, "timestamp >= ?")
+		args = append(args, f.Range.From)
+	}
+	if !f.Range.To.IsZero() {
+		where = append(where, "timestamp <= ?")
+		args = append(args, f.Range.To)
+	}
+
+	rows, err := a.queryContext(ctx, "SELECT id, created_at, user_id, asset_id, address, timestamp FROM wallets WHERE "+
+		strings.Join(where, " AND ")+" ORDER BY timestamp, id", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var w models.Wallet
+	for rows.Next() {
+		if err := rows.Scan(&w.ID, &w.CreatedAt, &w.UserID, &w.AssetID, &w.Address, &w.Timestamp); err != nil {
+			return err
+		}
+		if err := fn(&w); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// StreamOutgoingTransactions calls fn for every outgoing transaction matching the filter in timestamp order
+func (a *Storage) StreamOutgoingTransactions(ctx context.Context, f TransactionFilter, fn func(*models.OutgoingTransaction) error) error {
+	log := logger.FromContext(ctx).WithField("m", "StreamOutgoingTransactions")
+	log.Debugf("StreamOutgoingTransactions:: f: %+v", f)
+
+	union, args := outgoingTransactionsUnion(f)
+	rows, err := a.queryContext(ctx, "SELECT chain, user_id, asset_id, amount, decimals, tx_id, internal, timestamp FROM "+
+		union+" ORDER BY timestamp, src, id", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var r outgoingTransactionRow
+	for rows.Next() {
+		if err := rows.Scan(&r.Chain, &r.UserID, &r.AssetID, &r.Amount, &r.Decimals, &r.TxID, &r.Internal, &r.Timestamp); err != nil {
+			return err
+		}
+
+		tx := models.OutgoingTransaction{
+			Chain:     r.Chain,
+			UserID:    r.UserID,
+			AssetID:   r.AssetID,
+			Amount:    r.Amount.WithDecimals(r.Decimals),
+			TxID:      r.TxID,
+			Internal:  r.Internal,
+			Timestamp: r.Timestamp,
+		}
+		if err := fn(&tx); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
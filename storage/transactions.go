@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+type TransactionFilter struct {
+	UserID   string
+	AssetID  string
+	Chain    string
+	Internal *bool
+	Range    TimeRange
+}
+
+// outgoingSource describes how a chain transaction table maps to the unified view
+type outgoingSource struct {
+	table    string
+	chain    string
+	amount   string
+	decimals string
+}
+
+var outgoingSources = []outgoingSource{
+	{table: models.BTCTransactionsTable, chain: "chain", amount: "amount::numeric", decimals: "8"},
+	{table: models.ETHTransactionsTable, chain: "chain", amount: "amount", decimals: "decimals"},
+	{table: models.TRXTransactionsTable, chain: "'" + models.ChainTRX + "'", amount: "amount", decimals: "decimals"},
+}
+
+func (s outgoingSource) internal() string {
+	return "EXISTS (SELECT 1 FROM internal_transactions i WHERE i.chain = " + s.chain +
+		" AND i.tx_id = " + s.table + ".tx_id AND i.deleted_at IS NULL)"
+}
+
+func (s outgoingSource) query(src int, f TransactionFilter) (string, []interface{}) {
+	where := []string{"deleted_at IS NULL", "outgoing"}
+	var args []interface{}
+
+	if f.UserID != "" {
+		where = append(where, "user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if f.AssetID != "" {
+		where = append(where, "asset_id = ?")
+		args = append(args, f.AssetID)
+	}
+	if f.Chain != "" {
+		where = append(where, s.chain+" = ?")
+		args = append(args, f.Chain)
+	}
+	if !f.Range.From.IsZero() {
+		where = append(where, "timestamp >= ?")
+		args = append(args, f.Range.From)
+	}
+	if !f.Range.To.IsZero() {
+		where = append(where, "timestamp <= ?")
+		args = append(args, f.Range.To)
+	}
+	if f.Internal != nil {
+		where = append(where, s.internal()+" = ?")
+		args = append(args, *f.Internal)
+	}
+
+	q := "SELECT " + s.chain + " AS chain, user_id, asset_id, " +
+		s.amount + "::text AS amount, " + s.decimals + " AS decimals, tx_id, " +
+		s.internal() + " AS internal, timestamp, id, " + strconv.Itoa(src) + " AS src FROM " + s.table +
+		" WHERE " + strings.Join(where, " AND ")
+
+	return q, args
+}
+
+func outgoingTransactionsUnion(f TransactionFilter) (string, []interface{}) {
+	var parts []string
+	var args []interface{}
+
+	for i, s := range outgoingSources {
+		q, a := s.query(i, f)
+		parts = append(parts, q)
+		args = append(args, a...)
+	}
+
+	return "(" + strings.Join(parts, " UNION ALL ") + ") t", args
+}
+
+type outgoingTransactionRow struct {
+	Chain     string
+	UserID    string
+	AssetID   string
+	Amount    models.Amount
+	Decimals  int
+	TxID      string
+	Internal  bool
+	Timestamp time.Time
+}
+
+// GetOutgoingTransactions returns outgoing transactions of all chains sorted by timestamp
+// from oldest to newest and the total count for the filter
+func (a *Storage) GetOutgoingTransactions(ctx context.Context, f TransactionFilter, p Page) (ret []models.OutgoingTransaction, total int, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetOutgoingTransactions")
+	log.Debugf("GetOutgoingTransactions:: f: %+v, p: %+v", f, p)
+
+	union, args := outgoingTransactionsUnion(f)
+
+	if err = a.DB.Raw("SELECT count(*) FROM "+union, args...).Row().Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	var rows []outgoingTransactionRow
+	err = p.apply(a.DB.Raw("SELECT * FROM "+union+" ORDER BY timestamp, src, id", args...)).Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ret = make([]models.OutgoingTransaction, 0, len(rows))
+	for _, r := range rows {
+		ret = append(ret, models.OutgoingTransaction{
+			Chain:     r.Chain,
+			UserID:    r.UserID,
+			AssetID:   r.AssetID,
+			Amount:    r.Amount.WithDecimals(r.Decimals),
+			TxID:      r.TxID,
+			Internal:  r.Internal,
+			Timestamp: r.Timestamp,
+		})
+	}
+
+	return ret, total, nil
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+const TurnoverRollup = "turnover_hourly"
+
+// Turnover granularities
+const (
+	GranularityHour  = "hour"
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// Turnover group-by dimensions in addition to the asset
+const (
+	DimensionChain = "chain"
+	DimensionUser  = "user_id"
+)
+
+type TurnoverQuery struct {
+	Granularity string
+	Location    *time.Location
+	GroupBy     []string
+	AssetID     string
+	UserID      string
+	Chain       string
+	Range       TimeRange
+	// Fiat adds amounts valued at the nearest price at or before the start of each hour
+	Fiat string
+}
+
+// RefreshTurnover recomputes hourly turnover buckets in [from, to)
+func (a *Storage) RefreshTurnover(ctx context.Context, from, to time.Time) error {
+	log := logger.FromContext(ctx).WithField("m", "RefreshTurnover")
+	log.Debugf("RefreshTurnover:: from: %v, to: %v", from, to)
+
+	from = from.UTC().Truncate(time.Hour)
+	to = to.UTC().Truncate(time.Hour)
+	if !to.After(from) {
+		return nil
+	}
+
+	// timestamps are stored with microsecond precision, so this makes the range end exclusive
+	union, args := outgoingTransactionsUnion(TransactionFilter{
+		Range: TimeRange{From: from, To: to.Add(-time.Microsecond)},
+	})
+
+	return a.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("DELETE FROM "+models.TurnoverHourlyTable+" WHERE bucket >= ? AND bucket < ?", from, to).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec("INSERT INTO "+models.TurnoverHourlyTable+
+			" (bucket, chain, asset_id, user_id, internal, decimals, tx_count, amount, updated_at)"+
+			" SELECT date_trunc('hour', timestamp), chain, asset_id, user_id, internal,"+
+			" max(decimals), count(DISTINCT tx_id), sum(amount::numeric), now() FROM "+union+
+			" GROUP BY 1, 2, 3, 4, 5", args...).Error
+	})
+}
+
+// GetRollupState returns zero time if the rollup has never been refreshed
+func (a *Storage) GetRollupState(ctx context.Context, name string) (time.Time, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetRollupState")
+	log.Debugf("GetRollupState:: name: %v", name)
+
+	state := new(models.RollupState)
+	err := a.DB.Where("name = ?", name).First(state).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return state.Until, nil
+}
+
+func (a *Storage) SetRollupState(ctx context.Context, name string, until time.Time) error {
+	log := logger.FromContext(ctx).WithField("m", "SetRollupState")
+	log.Debugf("SetRollupState:: name: %v, until: %v", name, until)
+
+	return a.DB.Exec("INSERT INTO rollup_states (name, until, updated_at) VALUES (?, ?, now())"+
+		" ON CONFLICT (name) DO UPDATE SET until = EXCLUDED.until, updated_at = now()", name, until).Error
+}
+
+type turnoverRow struct {
+	Period         time.Time
+	AssetID        string
+	Chain          string
+	UserID         string
+	Decimals       int
+	TxCount        int64
+	Amount         models.Amount
+	InternalCount  int64
+	InternalAmount models.Amount
+	ExternalCount  int64
+	// fiat sums are in base units times price
+	FiatAmount         *string
+	FiatInternalAmount *string
+	Unpriced           int64
+}
+
+// GetTurnover aggregates hourly rollups into periods of the query granularity in its location.
+// Granularity, location and dimensions must be validated by the caller. Fiat sums value a whole
+// hourly bucket at the last price at or before the bucket start
+func (a *Storage) GetTurnover(ctx context.Context, q TurnoverQuery) ([]models.Turnover, error) {
+	log := logger.FromContext(ctx).WithField("m", "GetTurnover")
+	log.Debugf("GetTurnover:: q: %+v", q)
+
+	period := "date_trunc('" + q.Granularity + "', bucket AT TIME ZONE '" + q.Location.String() + "')" +
+		" AT TIME ZONE '" + q.Location.String() + "'"
+	dims := append([]string{"asset_id"}, q.GroupBy...)
+
+	from := models.TurnoverHourlyTable
+	fiatColumns := ""
+	where := []string{"true"}
+	var args []interface{}
+	if q.Fiat != "" {
+		from += " LEFT JOIN LATERAL (SELECT price FROM prices p WHERE p.asset_id = " + models.TurnoverHourlyTable + ".asset_id" +
+			" AND p.fiat = ? AND p.timestamp <= bucket ORDER BY p.timestamp DESC LIMIT 1) fp ON true"
+		args = append(args, q.Fiat)
+		fiatColumns = ", sum(amount * fp.price)::text AS fiat_amount," +
+			" coalesce(sum(amount * fp.price) FILTER (WHERE internal), 0)::text AS fiat_internal_amount," +
+			" count(*) FILTER (WHERE fp.price IS NULL) AS unpriced"
+	}
+	if q.AssetID != "" {
+		where = append(where, "asset_id = ?")
+		args = append(args, q.AssetID)
+	}
+	if q.UserID != "" {
+		where = append(where, "user_id = ?")
+		args = append(args, q.UserID)
+	}
+	if q.Chain != "" {
+		where = append(where, "chain = ?")
+		args = append(args, q.Chain)
+	}
+	if !q.Range.From.IsZero() {
+		where = append(where, "bucket >= ?")
+		args = append(args, q.Range.From)
+	}
+	if !q.Range.To.IsZero() {
+		where = append(where, "bucket <= ?")
+		args = append(args, q.Range.To)
+	}
+
+	var rows []turnoverRow
+	err := a.DB.Raw("SELECT "+period+" AS period, "+strings.Join(dims, ", ")+", max(decimals) AS decimals,"+
+		" sum(tx_count) AS tx_count, sum(amount)::text AS amount,"+
+		" coalesce(sum(tx_count) FILTER (WHERE internal), 0) AS internal_count,"+
+		" coalesce(sum(amount) FILTER (WHERE internal), 0)::text AS internal_amount,"+
+		" coalesce(sum(tx_count) FILTER (WHERE NOT internal), 0) AS external_count"+fiatColumns+
+		" FROM "+from+" WHERE "+strings.Join(where, " AND ")+
+		" GROUP BY 1, "+strings.Join(dims, ", ")+" ORDER BY 1, "+strings.Join(dims, ", "), args...).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]models.Turnover, 0, len(rows))
+	for _, r := range rows {
+		amount := r.Amount.WithDecimals(r.Decimals)
+		internal := r.InternalAmount.WithDecimals(r.Decimals)
+		ret = append(ret, models.Turnover{
+			Period:         r.Period,
+			AssetID:        r.AssetID,
+			Chain:          r.Chain,
+			UserID:         r.UserID,
+			TxCount:        r.TxCount,
+			Amount:         amount,
+			InternalCount:  r.InternalCount,
+			InternalAmount: internal,
+			ExternalCount:  r.ExternalCount,
+			ExternalAmount: amount.Sub(internal),
+		})
+		if q.Fiat != "" {
+			setFiatTurnover(&ret[len(ret)-1], q.Fiat, r)
+		}
+	}
+
+	return ret, nil
+}
+
+// setFiatTurnover sets fiat amounts of a period whose hours all have a price
+func setFiatTurnover(t *models.Turnover, fiat string, r turnoverRow) {
+	t.Fiat = fiat
+	if r.Unpriced > 0 || r.FiatAmount == nil || r.FiatInternalAmount == nil {
+		return
+	}
+
+	total, ok1 := new(big.Rat).SetString(*r.FiatAmount)
+	internal, ok2 := new(big.Rat).SetString(*r.FiatInternalAmount)
+	if !ok1 || !ok2 {
+		return
+	}
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(r.Decimals)), nil))
+	total.Quo(total, scale)
+	internal.Quo(internal, scale)
+
+	amount := models.AmountFromRat(total, models.FiatDecimals)
+	internalAmount := models.AmountFromRat(internal, models.FiatDecimals)
+	externalAmount := amount.Sub(internalAmount)
+	t.FiatAmount, t.FiatInternalAmount, t.FiatExternalAmount = &amount, &internalAmount, &externalAmount
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+// WalletRemoval lists a wallet and the transaction rows derived from it
+type WalletRemoval struct {
+	Wallet       models.Wallet `json:"wallet"`
+	Table        string        `json:"table"`
+	Transactions []string      `json:"transactions"`
+	rowIDs       []uint
+	timestamps   struct{ from, to time.Time }
+}
+
+type removalRow struct {
+	ID        uint
+	TxID      string
+	Timestamp time.Time
+}
+
+func walletTransactionsQuery(db *gorm.DB, table string, chain *models.Chain, w *models.Wallet) *gorm.DB {
+	q := db.Table(table).
+		Where("deleted_at IS NULL AND user_id = ? AND asset_id = ?", w.UserID, w.AssetID).
+		Where("from_address = ? OR to_address = ?", w.Address, w.Address)
+	if chain.Kind != models.ChainKindTRX {
+		q = q.Where("chain = ?", chain.Name)
+	}
+
+	return q
+}
+
+// PlanWalletRemoval returns nil if the wallet does not exist
+func (a *Storage) PlanWalletRemoval(ctx context.Context, walletID uint) (*WalletRemoval, error) {
+	log := logger.FromContext(ctx).WithField("m", "PlanWalletRemoval")
+	log.Debugf("PlanWalletRemoval:: walletID: %v", walletID)
+
+	return a.planWalletRemoval(ctx, a.DB, walletID)
+}
+
+// planWalletRemoval lists the wallet and its transaction rows with db, inside a transaction
+// the rows are locked so they cannot change before they are removed
+func (a *Storage) planWalletRemoval(ctx context.Context, db *gorm.DB, walletID uint) (*WalletRemoval, error) {
+	plan := new(WalletRemoval)
+	err := db.Where("id = ?", walletID).First(&plan.Wallet).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	asset, err := a.LookupAsset(ctx, plan.Wallet.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	if asset == nil {
+		return nil, fmt.Errorf("asset %v of wallet %v is not in the catalog", plan.Wallet.AssetID, walletID)
+	}
+	chain, err := a.LookupChain(ctx, asset.Chain)
+	if err != nil {
+		return nil, err
+	}
+	if chain == nil {
+		return nil, fmt.Errorf("chain %v of asset %v is not in the catalog", asset.Chain, asset.AssetID)
+	}
+
+	plan.Table = models.TransactionsTable(chain.Kind)
+	var rows []removalRow
+	err = walletTransactionsQuery(db, plan.Table, chain, &plan.Wallet).
+		Select("id, tx_id, timestamp").Order("timestamp, id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Transactions = make([]string, 0, len(rows))
+	for i, r := range rows {
+		plan.Transactions = append(plan.Transactions, r.TxID)
+		plan.rowIDs = append(plan.rowIDs, r.ID)
+		if i == 0 {
+			plan.timestamps.from = r.Timestamp
+		}
+		plan.timestamps.to = r.Timestamp
+	}
+
+	return plan, nil
+}
+
+// RemoveWallet plans the removal and soft-deletes the wallet and its transaction rows in one
+// transaction, with the wallet and rows locked, so the plan and the audit entry made from it by
+// audit match what was removed. Turnover rollups covering the removed rows are recomputed after.
+// It returns nil if the wallet does not exist
+func (a *Storage) RemoveWallet(ctx context.Context, walletID uint, audit func(*WalletRemoval) (*models.AuditEntry, error)) (*WalletRemoval, error) {
+	log := logger.FromContext(ctx).WithField("m", "RemoveWallet")
+	log.Debugf("RemoveWallet:: walletID: %v", walletID)
+
+	var plan *WalletRemoval
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		plan, err = a.planWalletRemoval(ctx, tx.Set("gorm:query_option", "FOR UPDATE"), walletID)
+		if err != nil || plan == nil {
+			return err
+		}
+
+		entry, err := audit(plan)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&plan.Wallet).Error; err != nil {
+			return err
+		}
+		if len(plan.rowIDs) > 0 {
+			err := tx.Table(plan.Table).Where("id IN (?)", plan.rowIDs).
+				UpdateColumn("deleted_at", time.Now()).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return addAuditEntry(tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil || len(plan.rowIDs) == 0 {
+		return plan, nil
+	}
+
+	return plan, a.RefreshTurnover(ctx, plan.timestamps.from, plan.timestamps.to.Add(time.Hour))
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+
+	"service_template/logger"
+	"service_template/models"
+)
+
+type WalletFilter struct {
+	UserID  string
+	AssetID string
+	Address string
+	Range   TimeRange
+}
+
+func (a *Storage) walletsQuery(f WalletFilter) *gorm.DB {
+	q := a.DB.Model(&models.Wallet{})
+	if f.UserID != "" {
+		q = q.Where("user_id = ?", f.UserID)
+	}
+	if f.AssetID != "" {
+		q = q.Where("asset_id = ?", f.AssetID)
+	}
+	if f.Address != "" {
+		q = q.Where("address = ?", f.Address)
+	}
+
+	return f.Range.apply(q, "timestamp")
+}
+
+// GetWallets returns wallets sorted by timestamp from oldest to newest and the total count for the filter
+func (a *Storage) GetWallets(ctx context.Context, f WalletFilter, p Page) (ret []models.Wallet, total int, err error) {
+	log := logger.FromContext(ctx).WithField("m", "GetWallets")
+	log.Debugf("GetWallets:: f: %+v, p: %+v", f, p)
+
+	if err = a.walletsQuery(f).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err = p.apply(a.walletsQuery(f)).Order("timestamp asc, id asc").Find(&ret).Error
+
+	return
+}
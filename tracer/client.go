@@ -21,6 +21,19 @@ type TraceHTTPClient struct {
 
 var _ HTTPClient = (*TraceHTTPClient)(nil)
 
+// NewTraceHTTPClient wraps client with client spans of tracer,
+// nil arguments select http.DefaultClient and the global tracer
+func NewTraceHTTPClient(client HTTPClient, tracer opentracing.Tracer) *TraceHTTPClient {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if tracer == nil {
+		tracer = opentracing.GlobalTracer()
+	}
+
+	return &TraceHTTPClient{client: client, tracer: tracer}
+}
+
 func (c *TraceHTTPClient) Do(req *http.Request) (*http.Response, error) {
 	ctx := req.Context()
 	methodName, ok := twirp.MethodName(ctx)
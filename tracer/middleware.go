@@ -1,8 +1,6 @@
 package tracer
 
 import (
-	"bytes"
-	"context"
 	"net/http"
 
 	opentracing "github.com/opentracing/opentracing-go"
@@ -10,51 +8,52 @@ import (
 	"github.com/uber/jaeger-client-go"
 
 	"service_template/logger"
+	"service_template/recorder"
 )
 
-type LogResponseWriter struct {
-	http.ResponseWriter
-	buf *bytes.Buffer
-}
-
-func (mrw *LogResponseWriter) Write(p []byte) (int, error) {
-	return mrw.buf.Write(p)
-}
+// Middleware serves each request in a span that continues the trace of the caller, if its
+// headers carry one. The span ends with the response and records its status
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := logger.FromContext(ctx)
 
-func tracer(w http.ResponseWriter, r *http.Request) context.Context {
-	ctx := r.Context()
-	log := logger.FromContext(ctx)
+		wireContext, err := opentracing.GlobalTracer().Extract(
+			opentracing.HTTPHeaders,
+			opentracing.HTTPHeadersCarrier(r.Header))
 
-	wireContext, err := opentracing.GlobalTracer().Extract(
-		opentracing.HTTPHeaders,
-		opentracing.HTTPHeadersCarrier(r.Header))
+		if err != nil && err != opentracing.ErrSpanContextNotFound {
+			log.Debugf("failed to extract span from headers: %v", err)
+		}
 
-	if err != nil {
-		log.Debugf("failed to extract span from headers")
-	}
+		options := []opentracing.StartSpanOption{
+			ext.RPCServerOption(wireContext),
+		}
 
-	var serverSpan opentracing.Span
+		if wireContext != nil {
+			options = append(options, opentracing.ChildOf(wireContext))
+		}
 
-	options := []opentracing.StartSpanOption{
-		ext.RPCServerOption(wireContext),
-	}
+		serverSpan := opentracing.StartSpan("HTTP request", options...)
+		defer serverSpan.Finish()
 
-	if wireContext != nil {
-		options = append(options, opentracing.ChildOf(wireContext))
-	}
+		ext.HTTPMethod.Set(serverSpan, r.Method)
+		ext.HTTPUrl.Set(serverSpan, r.URL.Path)
 
-	serverSpan = opentracing.StartSpan("HTTP request", options...)
-	defer serverSpan.Finish()
+		if sc, ok := serverSpan.Context().(jaeger.SpanContext); ok {
+			w.Header().Set("X-Trace-ID", sc.TraceID().String())
+		}
 
-	if sc, ok := serverSpan.Context().(jaeger.SpanContext); ok {
-		headers := w.Header()
-		traceID := sc.TraceID().String()
-		headers.Set("X-Trace-ID", traceID)
-	}
+		ctx = opentracing.ContextWithSpan(ctx, serverSpan)
+		log = logger.WithTraceId(log, serverSpan)
+		ctx = logger.ToContext(ctx, log)
 
-	ctx = opentracing.ContextWithSpan(ctx, serverSpan)
-	log = logger.WithTraceId(log, serverSpan)
-	ctx = logger.ToContext(ctx, log)
+		rec := recorder.Wrap(w)
+		next.ServeHTTP(rec, r.WithContext(ctx))
 
-	return ctx
+		ext.HTTPStatusCode.Set(serverSpan, uint16(rec.Status()))
+		if rec.Status() >= http.StatusInternalServerError {
+			ext.Error.Set(serverSpan, true)
+		}
+	})
 }
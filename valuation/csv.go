@@ -0,0 +1,104 @@
+package valuation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"service_template/models"
+)
+
+var (
+	fiatRe  = regexp.MustCompile(`^[A-Z]{3}$`)
+	priceRe = regexp.MustCompile(`^[0-9]{1,20}(\.[0-9]{1,18})?$`)
+)
+
+// CSVColumns are the required columns of a price import, in any order
+var CSVColumns = []string{"asset_id", "fiat", "timestamp", "price"}
+
+// NormalizeFiat returns the upper case ISO 4217 code or false
+func NormalizeFiat(s string) (string, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+
+	return s, fiatRe.MatchString(s)
+}
+
+// ParseCSV reads prices from CSV with a header row naming CSVColumns. Timestamps are
+// unix seconds or RFC3339, prices are positive decimals of fiat per whole unit
+// with at most 18 fractional digits
+func ParseCSV(r io.Reader) ([]models.Price, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, c := range CSVColumns {
+		if _, ok := index[c]; !ok {
+			return nil, fmt.Errorf("missing column %v", c)
+		}
+	}
+
+	var prices []models.Price
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		p, err := parseRecord(rec, index)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		prices = append(prices, p)
+	}
+
+	return prices, nil
+}
+
+func parseRecord(rec []string, index map[string]int) (models.Price, error) {
+	field := func(name string) string { return strings.TrimSpace(rec[index[name]]) }
+
+	p := models.Price{AssetID: field("asset_id")}
+	if p.AssetID == "" {
+		return p, fmt.Errorf("asset_id is empty")
+	}
+
+	var ok bool
+	if p.Fiat, ok = NormalizeFiat(field("fiat")); !ok {
+		return p, fmt.Errorf("invalid fiat %q", field("fiat"))
+	}
+
+	ts := field("timestamp")
+	if sec, err := strconv.ParseInt(ts, 10, 64); err == nil {
+		p.Timestamp = time.Unix(sec, 0).UTC()
+	} else if p.Timestamp, err = time.Parse(time.RFC3339, ts); err != nil {
+		return p, fmt.Errorf("invalid timestamp %q", ts)
+	}
+
+	price := field("price")
+	rate, ok := new(big.Rat).SetString(price)
+	if !priceRe.MatchString(price) || !ok || rate.Sign() <= 0 {
+		return p, fmt.Errorf("invalid price %q", price)
+	}
+	p.Price = price
+
+	return p, nil
+}
@@ -0,0 +1,48 @@
+package valuation
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCSV(t *testing.T) {
+	prices, err := ParseCSV(strings.NewReader("Price, asset_id,timestamp,fiat\n" +
+		"40000.5,BTC,1704067200,usd\n" +
+		"2000,ETH,2024-01-01T01:00:00+01:00, EUR\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != 2 {
+		t.Fatalf("%v prices", len(prices))
+	}
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if p := prices[0]; p.AssetID != "BTC" || p.Fiat != "USD" || !p.Timestamp.Equal(t0) || p.Price != "40000.5" {
+		t.Errorf("first price %+v", p)
+	}
+	if p := prices[1]; p.AssetID != "ETH" || p.Fiat != "EUR" || !p.Timestamp.Equal(t0) || p.Price != "2000" {
+		t.Errorf("second price %+v", p)
+	}
+
+	header := "asset_id,fiat,timestamp,price\n"
+	invalid := []struct {
+		name, csv, err string
+	}{
+		{"empty", "", "empty file"},
+		{"missing column", "asset_id,fiat,price\nBTC,USD,1", "missing column timestamp"},
+		{"empty asset", header + "BTC,USD,0,1\n,USD,0,1\n", "line 3: asset_id is empty"},
+		{"fiat", header + "BTC,US,0,1\n", "line 2: invalid fiat"},
+		{"timestamp", header + "BTC,USD,2024-01-01,1\n", "line 2: invalid timestamp"},
+		{"zero price", header + "BTC,USD,0,0\n", "line 2: invalid price"},
+		{"negative price", header + "BTC,USD,0,-1\n", "line 2: invalid price"},
+		{"exponent", header + "BTC,USD,0,1e3\n", "line 2: invalid price"},
+		{"too precise", header + "BTC,USD,0,0.0000000000000000001\n", "line 2: invalid price"},
+		{"field count", header + "BTC,USD,0\n", "wrong number of fields"},
+	}
+	for _, tt := range invalid {
+		_, err := ParseCSV(strings.NewReader(tt.csv))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%v: error %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
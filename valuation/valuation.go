@@ -0,0 +1,125 @@
+// Package valuation converts asset amounts to fiat currencies using the local price history
+package valuation
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"service_template/models"
+	"service_template/storage"
+)
+
+// window is the span of prices loaded at once, lookups in time order reuse it
+const window = 7 * 24 * time.Hour
+
+// Value is an amount converted at the nearest earlier price
+type Value struct {
+	Amount  models.Amount
+	Price   string
+	PriceAt time.Time
+}
+
+type series struct {
+	tr     storage.TimeRange
+	prev   *models.Price
+	points []models.Price
+	rates  map[string]*big.Rat
+}
+
+// at returns the last price at or before t, t must be in the loaded range
+func (s *series) at(t time.Time) *models.Price {
+	i := sort.Search(len(s.points), func(i int) bool { return s.points[i].Timestamp.After(t) })
+	if i > 0 {
+		return &s.points[i-1]
+	}
+
+	return s.prev
+}
+
+// Valuer converts amounts of many rows to one fiat currency. Prices are loaded per asset in
+// windows, so a Valuer is meant for one request or export and is not safe for concurrent use
+type Valuer struct {
+	// load returns the last price before a range and the prices in it
+	load   func(ctx context.Context, assetID, fiat string, tr storage.TimeRange) (*models.Price, []models.Price, error)
+	fiat   string
+	series map[string]*series
+}
+
+func New(db *storage.Storage, fiat string) *Valuer {
+	return &Valuer{load: db.GetPriceWindow, fiat: fiat, series: map[string]*series{}}
+}
+
+func (v *Valuer) Fiat() string {
+	return v.fiat
+}
+
+// Value converts amount of an asset at time at, it returns nil if there is no earlier price
+func (v *Valuer) Value(ctx context.Context, assetID string, amount models.Amount, at time.Time) (*Value, error) {
+	s := v.series[assetID]
+	if s == nil || at.Before(s.tr.From) || at.After(s.tr.To) {
+		prev, points, err := v.load(ctx, assetID, v.fiat, storage.TimeRange{From: at, To: at.Add(window)})
+		if err != nil {
+			return nil, err
+		}
+		s = &series{
+			tr:     storage.TimeRange{From: at, To: at.Add(window)},
+			prev:   prev,
+			points: points,
+			rates:  map[string]*big.Rat{},
+		}
+		v.series[assetID] = s
+	}
+
+	p := s.at(at)
+	if p == nil {
+		return nil, nil
+	}
+	rate, ok := s.rates[p.Price]
+	if !ok {
+		if rate, ok = new(big.Rat).SetString(p.Price); !ok {
+			return nil, fmt.Errorf("invalid price %q of %v in %v at %v", p.Price, assetID, v.fiat, p.Timestamp)
+		}
+		s.rates[p.Price] = rate
+	}
+
+	return &Value{
+		Amount:  amount.MulRat(rate, models.FiatDecimals),
+		Price:   rate.FloatString(priceDecimals(p.Price)),
+		PriceAt: p.Timestamp,
+	}, nil
+}
+
+// ValueTransaction sets the fiat fields of an outgoing transaction
+func (v *Valuer) ValueTransaction(ctx context.Context, tx *models.OutgoingTransaction) error {
+	val, err := v.Value(ctx, tx.AssetID, tx.Amount, tx.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	tx.Fiat = v.fiat
+	if val != nil {
+		tx.FiatAmount = &val.Amount
+		tx.FiatPrice = val.Price
+		tx.FiatPriceAt = &val.PriceAt
+	}
+
+	return nil
+}
+
+// priceDecimals returns the significant fractional digits of a numeric price
+func priceDecimals(s string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '.' {
+			n := len(s) - i - 1
+			for n > 0 && s[i+n] == '0' {
+				n--
+			}
+			return n
+		}
+	}
+
+	return 0
+}
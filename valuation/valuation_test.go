@@ -0,0 +1,103 @@
+package valuation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"service_template/models"
+	"service_template/storage"
+)
+
+func TestPriceDecimals(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"100", 0},
+		{"100.", 0},
+		{"100.000000000000000000", 0},
+		{"100.500000000000000000", 1},
+		{"0.000000000000000001", 18},
+		{"12.34", 2},
+	}
+	for _, tt := range tests {
+		if got := priceDecimals(tt.s); got != tt.want {
+			t.Errorf("%v: %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prices := []models.Price{
+		{AssetID: "BTC", Fiat: "USD", Timestamp: t0, Price: "40000.000000000000000000"},
+		{AssetID: "BTC", Fiat: "USD", Timestamp: t0.Add(time.Hour), Price: "40000.125000000000000000"},
+		// the only price of the second window
+		{AssetID: "BTC", Fiat: "USD", Timestamp: t0.Add(window + 2*time.Hour), Price: "50000"},
+	}
+
+	loads := 0
+	v := &Valuer{
+		// GetPriceWindow over prices
+		load: func(ctx context.Context, assetID, fiat string, tr storage.TimeRange) (*models.Price, []models.Price, error) {
+			loads++
+			var prev *models.Price
+			var points []models.Price
+			for i, p := range prices {
+				if p.Timestamp.After(tr.From) {
+					if !p.Timestamp.After(tr.To) {
+						points = append(points, p)
+					}
+				} else {
+					prev = &prices[i]
+				}
+			}
+			return prev, points, nil
+		},
+		fiat:   "USD",
+		series: map[string]*series{},
+	}
+
+	amount, _ := models.ParseAmount("0.5", models.BTCDecimals)
+	tests := []struct {
+		name    string
+		at      time.Time
+		amount  string
+		price   string
+		priceAt time.Time
+		loads   int
+	}{
+		// loads the window starting a minute before t0
+		{"before the first price", t0.Add(-time.Minute), "", "", time.Time{}, 1},
+		{"at a price", t0, "20000", "40000", t0, 1},
+		{"between prices", t0.Add(90 * time.Minute), "20000.06", "40000.125", t0.Add(time.Hour), 1},
+		// the first price of the next window is after the lookup, the window's prev is used
+		{"after the window", t0.Add(window + time.Hour), "20000.06", "40000.125", t0.Add(time.Hour), 2},
+		{"in the next window", t0.Add(window + 3*time.Hour), "25000", "50000", t0.Add(window + 2*time.Hour), 2},
+		{"back in time", t0.Add(30 * time.Minute), "20000", "40000", t0, 3},
+	}
+	for _, tt := range tests {
+		val, err := v.Value(context.Background(), "BTC", amount, tt.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loads != tt.loads {
+			t.Errorf("%v: %v loads, want %v", tt.name, loads, tt.loads)
+		}
+		if tt.price == "" {
+			if val != nil {
+				t.Errorf("%v: valued at %v", tt.name, val.Price)
+			}
+			continue
+		}
+		if val == nil {
+			t.Errorf("%v: not valued", tt.name)
+			continue
+		}
+		if val.Amount.String() != tt.amount || val.Price != tt.price || !val.PriceAt.Equal(tt.priceAt) {
+			t.Errorf("%v: %v at %v from %v, want %v at %v from %v", tt.name,
+				val.Amount, val.Price, val.PriceAt, tt.amount, tt.price, tt.priceAt)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"sync"
+)
+
+// Publisher is a minimal PUB socket bound to a local endpoint. It stands in for a node's
+// notification interface in tests and local development
+type Publisher struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	peers map[*pubPeer]struct{}
+	seq   map[string]uint32
+	wg    sync.WaitGroup
+}
+
+type pubPeer struct {
+	conn   net.Conn
+	mu     sync.Mutex
+	topics [][]byte
+}
+
+func (p *pubPeer) subscribed(topic []byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, t := range p.topics {
+		if bytes.HasPrefix(topic, t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewPublisher binds to an endpoint like tcp://127.0.0.1:0
+func NewPublisher(endpoint string) (*Publisher, error) {
+	addr, err := endpointAddress(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Publisher{ln: ln, peers: map[*pubPeer]struct{}{}, seq: map[string]uint32{}}
+	p.wg.Add(1)
+	go p.accept()
+
+	return p, nil
+}
+
+// Endpoint returns the bound endpoint for subscribers to connect to
+func (p *Publisher) Endpoint() string {
+	return "tcp://" + p.ln.Addr().String()
+}
+
+func (p *Publisher) accept() {
+	defer p.wg.Done()
+
+	for {
+		conn, err := p.ln.Accept()
+		if err != nil {
+			return
+		}
+		p.wg.Add(1)
+		go p.serve(conn)
+	}
+}
+
+func (p *Publisher) serve(conn net.Conn) {
+	defer p.wg.Done()
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	if err := handshake(conn, r, socketTypePUB, socketTypeSUB); err != nil {
+		return
+	}
+
+	peer := &pubPeer{conn: conn}
+	p.mu.Lock()
+	p.peers[peer] = struct{}{}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.peers, peer)
+		p.mu.Unlock()
+	}()
+
+	for {
+		flags, body, err := readFrame(r)
+		if err != nil {
+			return
+		}
+
+		var subscribe bool
+		var topic []byte
+		switch {
+		case flags&flagCommand != 0:
+			name, data, err := parseCommand(body)
+			if err != nil {
+				return
+			}
+			if name != "SUBSCRIBE" && name != "CANCEL" {
+				continue
+			}
+			subscribe, topic = name == "SUBSCRIBE", data
+		case len(body) > 0:
+			subscribe, topic = body[0] == 1, body[1:]
+		default:
+			continue
+		}
+
+		peer.mu.Lock()
+		if subscribe {
+			peer.topics = append(peer.topics, topic)
+		} else {
+			for i, t := range peer.topics {
+				if bytes.Equal(t, topic) {
+					peer.topics = append(peer.topics[:i], peer.topics[i+1:]...)
+					break
+				}
+			}
+		}
+		peer.mu.Unlock()
+	}
+}
+
+// Subscribers returns the number of connected subscribers subscribed to topic
+func (p *Publisher) Subscribers(topic string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := 0
+	for peer := range p.peers {
+		if peer.subscribed([]byte(topic)) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Send publishes a multipart message to subscribers of its first frame
+func (p *Publisher) Send(frames ...[]byte) error {
+	if len(frames) == 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	peers := make([]*pubPeer, 0, len(p.peers))
+	for peer := range p.peers {
+		peers = append(peers, peer)
+	}
+	p.mu.Unlock()
+
+	for _, peer := range peers {
+		if !peer.subscribed(frames[0]) {
+			continue
+		}
+
+		peer.mu.Lock()
+		for i, f := range frames {
+			var flags byte
+			if i < len(frames)-1 {
+				flags = flagMore
+			}
+			if err := writeFrame(peer.conn, flags, f); err != nil {
+				peer.conn.Close()
+				break
+			}
+		}
+		peer.mu.Unlock()
+	}
+
+	return nil
+}
+
+// SendNotification publishes a bitcoind-style notification: topic, body and a little-endian
+// sequence number counted per topic
+func (p *Publisher) SendNotification(topic string, body []byte) error {
+	p.mu.Lock()
+	seq := p.seq[topic]
+	p.seq[topic] = seq + 1
+	p.mu.Unlock()
+
+	var s [4]byte
+	binary.LittleEndian.PutUint32(s[:], seq)
+
+	return p.Send([]byte(topic), body, s[:])
+}
+
+// Close stops accepting subscribers and disconnects connected ones
+func (p *Publisher) Close() error {
+	err := p.ln.Close()
+
+	p.mu.Lock()
+	for peer := range p.peers {
+		peer.conn.Close()
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+
+	return err
+}
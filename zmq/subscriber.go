@@ -0,0 +1,112 @@
+package zmq
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// Subscriber is a SUB socket connected to a single PUB endpoint
+type Subscriber struct {
+	conn net.Conn
+	r    *bufio.Reader
+	mu   sync.Mutex
+}
+
+// DialSubscriber connects to a PUB endpoint like tcp://127.0.0.1:28332 and subscribes to topics
+func DialSubscriber(ctx context.Context, endpoint string, topics ...string) (*Subscriber, error) {
+	addr, err := endpointAddress(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Subscriber{conn: conn, r: bufio.NewReader(conn)}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := handshake(conn, s.r, socketTypeSUB, socketTypePUB); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	for _, t := range topics {
+		if err := s.Subscribe(t); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Subscribe starts receiving messages whose first frame starts with topic
+func (s *Subscriber) Subscribe(topic string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return writeFrame(s.conn, 0, append([]byte{1}, topic...))
+}
+
+// Recv returns the frames of the next message, commands from the peer are handled internally
+func (s *Subscriber) Recv() ([][]byte, error) {
+	var msg [][]byte
+	for {
+		flags, body, err := readFrame(s.r)
+		if err != nil {
+			return nil, err
+		}
+
+		if flags&flagCommand != 0 {
+			if err := s.handleCommand(body); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		msg = append(msg, body)
+		if flags&flagMore == 0 {
+			return msg, nil
+		}
+	}
+}
+
+func (s *Subscriber) handleCommand(body []byte) error {
+	name, data, err := parseCommand(body)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "PING":
+		// PING carries a 2 byte TTL followed by the context echoed back in PONG
+		if len(data) < 2 {
+			return ErrProtocol
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return writeFrame(s.conn, flagCommand, append(command("PONG", nil), data[2:]...))
+	case "ERROR":
+		return ErrProtocol
+	}
+
+	return nil
+}
+
+// SetReadDeadline bounds the next Recv
+func (s *Subscriber) SetReadDeadline(t time.Time) error {
+	return s.conn.SetReadDeadline(t)
+}
+
+func (s *Subscriber) Close() error {
+	return s.conn.Close()
+}
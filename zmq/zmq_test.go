@@ -0,0 +1,132 @@
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ZMTP 3.0 greeting: signature, version 3.0, NULL mechanism, as-server 0, filler
+func TestGreeting(t *testing.T) {
+	want := "ff00000000000000017f0300" + hex.EncodeToString([]byte("NULL")) + strings.Repeat("00", 48)
+	if got := hex.EncodeToString(greeting()); got != want {
+		t.Errorf("greeting %v, want %v", got, want)
+	}
+	if err := readGreeting(bytes.NewReader(greeting())); err != nil {
+		t.Error(err)
+	}
+
+	plain := greeting()
+	copy(plain[12:32], "PLAIN")
+	old := greeting()
+	old[10] = 2
+	for _, g := range [][]byte{plain, old, make([]byte, greetingSize)} {
+		if err := readGreeting(bytes.NewReader(g)); err == nil {
+			t.Errorf("accepted greeting %x", g)
+		}
+	}
+}
+
+func TestFrames(t *testing.T) {
+	long := bytes.Repeat([]byte{0xab}, 300)
+	tests := []struct {
+		flags  byte
+		body   []byte
+		header string
+	}{
+		{0, nil, "0000"},
+		{flagMore, []byte("rawtx"), "0105"},
+		{flagCommand, command("PING", nil), "0405"},
+		{0, long, "02000000000000012c"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := writeFrame(&b, tt.flags, tt.body); err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(b.Bytes()[:len(tt.header)/2]); got != tt.header {
+			t.Errorf("header %v, want %v", got, tt.header)
+		}
+
+		flags, body, err := readFrame(bufio.NewReader(&b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if flags&^flagLong != tt.flags || !bytes.Equal(body, tt.body) {
+			t.Errorf("read flags %x body %x, want %x %x", flags, body, tt.flags, tt.body)
+		}
+	}
+
+	if _, _, err := readFrame(bufio.NewReader(bytes.NewReader([]byte{flagLong, 0xff, 0, 0, 0, 0, 0, 0, 0}))); err == nil {
+		t.Error("read an oversized frame")
+	}
+}
+
+func TestCommand(t *testing.T) {
+	body := command("READY", map[string]string{"Socket-Type": "SUB"})
+	if want := "055245414459" + "0b536f636b65742d54797065" + "00000003" + "535542"; hex.EncodeToString(body) != want {
+		t.Errorf("command %x, want %v", body, want)
+	}
+
+	name, data, err := parseCommand(body)
+	if err != nil || name != "READY" {
+		t.Fatalf("parsed %q, %v", name, err)
+	}
+	// property names are case insensitive
+	props, err := parseProperties(data)
+	if err != nil || props["socket-type"] != "SUB" {
+		t.Errorf("properties %v, %v", props, err)
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	pub, err := NewPublisher("tcp://127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sub, err := DialSubscriber(ctx, pub.Endpoint(), "rawtx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	// subscriptions are asynchronous, wait for the publisher to see it
+	for pub.Subscribers("rawtx") == 0 {
+		if ctx.Err() != nil {
+			t.Fatal("subscription not seen by the publisher")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	long := bytes.Repeat([]byte{1}, 1000)
+	if err := pub.SendNotification("hashblock", []byte("not subscribed")); err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range [][]byte{[]byte("tx0"), long} {
+		if err := pub.SendNotification("rawtx", body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sub.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for seq, body := range [][]byte{[]byte("tx0"), long} {
+		msg, err := sub.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msg) != 3 || string(msg[0]) != "rawtx" || !bytes.Equal(msg[1], body) {
+			t.Fatalf("message %q", msg)
+		}
+		if !bytes.Equal(msg[2], []byte{byte(seq), 0, 0, 0}) {
+			t.Errorf("sequence %x, want %d", msg[2], seq)
+		}
+	}
+}
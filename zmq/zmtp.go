@@ -0,0 +1,208 @@
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ZMTP 3.0 with the NULL security mechanism, enough to talk to libzmq PUB sockets
+// such as bitcoind -zmqpub* notifications
+
+const (
+	greetingSize = 64
+
+	flagMore    = 0x01
+	flagLong    = 0x02
+	flagCommand = 0x04
+
+	maxFrameSize = 64 << 20
+)
+
+const (
+	socketTypeSUB = "SUB"
+	socketTypePUB = "PUB"
+)
+
+var ErrProtocol = errors.New("zmtp protocol error")
+
+func greeting() []byte {
+	g := make([]byte, greetingSize)
+	g[0] = 0xff
+	g[8] = 0x01
+	g[9] = 0x7f
+	g[10] = 3 // version major
+	g[11] = 0 // version minor, 3.0 peers subscribe with messages
+	copy(g[12:32], "NULL")
+
+	return g
+}
+
+func readGreeting(r io.Reader) error {
+	g := make([]byte, greetingSize)
+	if _, err := io.ReadFull(r, g); err != nil {
+		return err
+	}
+	if g[0] != 0xff || g[9]&0x01 != 0x01 {
+		return errors.Wrap(ErrProtocol, "invalid signature")
+	}
+	if g[10] < 3 {
+		return errors.Wrapf(ErrProtocol, "unsupported version %d.%d", g[10], g[11])
+	}
+	if mechanism := string(bytes.TrimRight(g[12:32], "\x00")); mechanism != "NULL" {
+		return errors.Wrapf(ErrProtocol, "unsupported mechanism %q", mechanism)
+	}
+
+	return nil
+}
+
+func writeFrame(w io.Writer, flags byte, body []byte) error {
+	var hdr []byte
+	if len(body) > 255 {
+		hdr = make([]byte, 9)
+		hdr[0] = flags | flagLong
+		binary.BigEndian.PutUint64(hdr[1:], uint64(len(body)))
+	} else {
+		hdr = []byte{flags, byte(len(body))}
+	}
+
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+
+	return err
+}
+
+func readFrame(r *bufio.Reader) (flags byte, body []byte, err error) {
+	if flags, err = r.ReadByte(); err != nil {
+		return 0, nil, err
+	}
+
+	var size uint64
+	if flags&flagLong != 0 {
+		var b [8]byte
+		if _, err = io.ReadFull(r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(b[:])
+	} else {
+		s, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		size = uint64(s)
+	}
+	if size > maxFrameSize {
+		return 0, nil, errors.Wrapf(ErrProtocol, "frame of %d bytes is too large", size)
+	}
+
+	body = make([]byte, size)
+	_, err = io.ReadFull(r, body)
+
+	return flags, body, err
+}
+
+func command(name string, props map[string]string) []byte {
+	var b bytes.Buffer
+	b.WriteByte(byte(len(name)))
+	b.WriteString(name)
+	for k, v := range props {
+		b.WriteByte(byte(len(k)))
+		b.WriteString(k)
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(v)))
+		b.Write(l[:])
+		b.WriteString(v)
+	}
+
+	return b.Bytes()
+}
+
+func parseCommand(body []byte) (name string, data []byte, err error) {
+	if len(body) < 1 || int(body[0])+1 > len(body) {
+		return "", nil, errors.Wrap(ErrProtocol, "malformed command")
+	}
+
+	return string(body[1 : 1+body[0]]), body[1+body[0]:], nil
+}
+
+func parseProperties(data []byte) (map[string]string, error) {
+	props := map[string]string{}
+	for len(data) > 0 {
+		n := int(data[0])
+		if 1+n+4 > len(data) {
+			return nil, errors.Wrap(ErrProtocol, "malformed property")
+		}
+		name := string(data[1 : 1+n])
+		l := int(binary.BigEndian.Uint32(data[1+n:]))
+		data = data[1+n+4:]
+		if l > len(data) {
+			return nil, errors.Wrap(ErrProtocol, "malformed property value")
+		}
+		props[strings.ToLower(name)] = string(data[:l])
+		data = data[l:]
+	}
+
+	return props, nil
+}
+
+// handshake exchanges greetings and READY commands and checks the peer socket type
+func handshake(conn net.Conn, r *bufio.Reader, socketType, peerType string) error {
+	if _, err := conn.Write(greeting()); err != nil {
+		return err
+	}
+	if err := readGreeting(r); err != nil {
+		return err
+	}
+
+	ready := command("READY", map[string]string{"Socket-Type": socketType})
+	if err := writeFrame(conn, flagCommand, ready); err != nil {
+		return err
+	}
+
+	flags, body, err := readFrame(r)
+	if err != nil {
+		return err
+	}
+	if flags&flagCommand == 0 {
+		return errors.Wrap(ErrProtocol, "expected READY command")
+	}
+
+	name, data, err := parseCommand(body)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "READY":
+	case "ERROR":
+		return fmt.Errorf("peer error: %s", data)
+	default:
+		return errors.Wrapf(ErrProtocol, "expected READY command, got %v", name)
+	}
+
+	props, err := parseProperties(data)
+	if err != nil {
+		return err
+	}
+	if t := props["socket-type"]; t != peerType && t != "X"+peerType {
+		return errors.Wrapf(ErrProtocol, "unexpected peer socket type %q", t)
+	}
+
+	return nil
+}
+
+// endpointAddress converts a tcp://host:port endpoint to a dialable address
+func endpointAddress(endpoint string) (string, error) {
+	if !strings.HasPrefix(endpoint, "tcp://") {
+		return "", fmt.Errorf("unsupported endpoint %q, only tcp:// is supported", endpoint)
+	}
+
+	return strings.TrimPrefix(endpoint, "tcp://"), nil
+}